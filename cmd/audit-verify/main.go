@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/patyukin/mdb/internal/audit"
+	"log"
+	"os"
+)
+
+// audit-verify проверяет цепочку хэшей журнала аудита, включая ротированные файлы
+func main() {
+	path := flag.String("path", "", "Audit log path")
+	anchorSeq := flag.Uint64("anchor-seq", 0, "Seq of the first record kept after old files were removed")
+	anchorHash := flag.String("anchor-hash", "", "prev_hash of the first record kept after old files were removed")
+	flag.Parse()
+
+	if *path == "" || (*anchorSeq == 0) != (*anchorHash == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	files, err := audit.Files(*path)
+	if err != nil {
+		log.Fatalf("Failed to list audit log files: %v", err)
+	}
+
+	if len(files) == 0 {
+		log.Fatalf("Audit log %s not found", *path)
+	}
+
+	anchor := audit.GenesisAnchor
+	if *anchorSeq != 0 {
+		anchor = audit.Anchor{Seq: *anchorSeq, PrevHash: *anchorHash}
+	}
+
+	summary, err := audit.VerifyFrom(anchor, files...)
+	if err != nil {
+		fmt.Printf("FAILED after %d valid records: %v\n", summary.Records, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("OK: %d records in %d files, seq %d..%d, last hash %s\n",
+		summary.Records, len(files), summary.FirstSeq, summary.LastSeq, summary.LastHash)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/patyukin/mdb/internal/network"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+func main() {
+	address := flag.String("address", "127.0.0.1:3223", "Server address")
+	socket := flag.String("socket", "", "Unix socket path, used instead of -address")
+	idleTimeout := flag.Duration("idle_timeout", time.Minute, "Idle timeout for connection")
+	user := flag.String("user", "", "Authenticate as the user, the password is taken from MDB_PASSWORD")
+	useTLS := flag.Bool("tls", false, "Connect over TLS")
+	caFile := flag.String("tls_ca", "", "CA bundle to verify the server certificate, implies -tls")
+	certFile := flag.String("tls_cert", "", "Client certificate for mutual TLS, implies -tls")
+	keyFile := flag.String("tls_key", "", "Client certificate key for mutual TLS")
+	serverName := flag.String("tls_server_name", "", "Server name to verify instead of the address host")
+	flag.Parse()
+
+	var client *network.TCPClient
+	var err error
+	switch {
+	case *socket != "":
+		client, err = network.NewUnixClient(*socket, *idleTimeout)
+	case *useTLS || *caFile != "" || *certFile != "":
+		tlsConfig, tlsErr := network.ClientTLSConfig(*caFile, *certFile, *keyFile, *serverName)
+		if tlsErr != nil {
+			log.Fatalf("Failed to load TLS config: %v", tlsErr)
+		}
+
+		client, err = network.NewTLSClient(*address, *idleTimeout, tlsConfig)
+	default:
+		client, err = network.NewTCPClient(*address, *idleTimeout)
+	}
+
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+
+	defer func() {
+		if err = client.Close(); err != nil {
+			log.Printf("failed client.Close, err: %v", err)
+		}
+	}()
+
+	if *user != "" {
+		response, err := client.Send([]byte("AUTH " + *user + " " + os.Getenv("MDB_PASSWORD")))
+		if err != nil {
+			log.Fatalf("Failed to authenticate: %v", err)
+		}
+
+		if !strings.HasPrefix(string(response), "OK") {
+			log.Fatalf("Failed to authenticate: %s", response)
+		}
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("> ")
+		if !scanner.Scan() {
+			break
+		}
+
+		request := strings.TrimSpace(scanner.Text())
+		if request == "" {
+			continue
+		}
+
+		var response []byte
+		response, err = client.Send([]byte(request))
+		if err != nil {
+			if errors.Is(err, network.ErrInvalidRequest) {
+				log.Printf("failed client.Send, err: %v", err)
+				continue
+			}
+
+			log.Fatalf("Connection lost: %v", err)
+		}
+
+		fmt.Println(string(response))
+
+		if subscribes(request) && strings.HasPrefix(string(response), "OK") {
+			listen(client)
+		}
+	}
+}
+
+// subscribes проверяет, подписывает ли запрос соединение на сообщения
+func subscribes(request string) bool {
+	command, _, _ := strings.Cut(request, " ")
+	command = strings.ToUpper(command)
+
+	return command == "SUBSCRIBE" || command == "PSUBSCRIBE"
+}
+
+// listen печатает сообщения pub/sub до разрыва соединения или прерывания программы
+func listen(client *network.TCPClient) {
+	fmt.Println("Reading messages... (press Ctrl-C to quit)")
+	for {
+		message, err := client.Receive(0)
+		if err != nil {
+			log.Fatalf("Connection lost: %v", err)
+		}
+
+		fmt.Println(string(message))
+	}
+}
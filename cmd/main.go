@@ -2,42 +2,516 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"github.com/patyukin/mdb/internal/audit"
+	"github.com/patyukin/mdb/internal/auth"
+	"github.com/patyukin/mdb/internal/cdc"
 	"github.com/patyukin/mdb/internal/config"
 	"github.com/patyukin/mdb/internal/database"
 	"github.com/patyukin/mdb/internal/database/compute"
 	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/slowlog"
 	"github.com/patyukin/mdb/internal/database/storage"
 	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/patyukin/mdb/internal/keyspace"
+	"github.com/patyukin/mdb/internal/metrics"
+	"github.com/patyukin/mdb/internal/network"
+	"github.com/patyukin/mdb/internal/pubsub"
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/patyukin/mdb/internal/trace"
 	"github.com/patyukin/mdb/pkg/logger"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"runtime"
 	"strings"
+	"syscall"
+	"time"
+)
+
+// Коды завершения процесса
+const (
+	exitOK     = 0 // штатная остановка
+	exitError  = 1 // ошибка запуска или работы сервера
+	exitForced = 2 // выполняемые запросы прерваны по истечении grace period
 )
 
 func main() {
+	os.Exit(run())
+}
+
+func run() int {
 	configPath := flag.String("config_path", "", "Config path")
+	printConfig := flag.Bool("print-config", false, "Print the effective config with secrets redacted and exit")
 	flag.Parse()
 
 	cfg, err := config.LoadConfig(*configPath)
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		log.Printf("Failed to load config: %v", err)
+		return exitError
+	}
+
+	if *printConfig {
+		// хэши паролей не выводятся
+		data, err := cfg.Redacted().Marshal()
+		if err != nil {
+			log.Printf("Failed to print config: %v", err)
+			return exitError
+		}
+
+		_, _ = os.Stdout.Write(data)
+		return exitOK
 	}
 
-	l, err := logger.InitLogger(cfg)
+	l, logLevel, err := logger.InitLoggerWithLevel(cfg)
 	if err != nil {
-		log.Fatalf("Failed to init logger: %v", err)
+		log.Printf("Failed to init logger: %v", err)
+		return exitError
+	}
+
+	defer func() { _ = l.Sync() }()
+
+	configManager := config.NewManager(config.FilePath(*configPath), cfg)
+
+	// SHUTDOWN и сигналы передают сюда, нужно ли сохранить данные перед остановкой
+	shutdownRequests := make(chan bool, 1)
+	requestShutdown := func(save bool) {
+		select {
+		case shutdownRequests <- save:
+		default:
+		}
+	}
+
+	// база создается после сервера, который нужен хранилищу, но до приема соединений
+	var dbase *database.Database
+	broker := pubsub.NewBroker(cfg.PubSub.BufferSize)
+	// классы проверены при загрузке конфигурации
+	keyspaceEvents, _ := keyspace.ParseClasses(cfg.PubSub.KeyspaceEvents)
+	notifier := keyspace.NewNotifier(broker, keyspaceEvents)
+
+	var server *network.TCPServer
+	var tlsProvider *network.TLSProvider
+	if cfg.Network.Address != "" || cfg.Network.UnixSocket.Path != "" {
+		serverOptions := []network.TCPServerOption{
+			network.WithMaxConnections(cfg.Network.MaxConnections),
+			network.WithMaxMessageSize(int(cfg.Network.MaxMessageSize)),
+			network.WithIdleTimeout(cfg.Network.IdleTimeout),
+			network.WithMaxInFlight(cfg.Network.MaxInFlight),
+			network.WithBinaryProtocol(func(ctx context.Context, cmd *parser.Command) (string, error) {
+				return dbase.HandleCommand(ctx, cmd)
+			}, database.ErrorCode),
+			network.WithPubSub(broker),
+		}
+
+		if cfg.Network.UnixSocket.Path != "" {
+			// права проверены при загрузке конфигурации
+			mode, _ := config.ParseFileMode(cfg.Network.UnixSocket.Mode)
+			serverOptions = append(serverOptions, network.WithUnixSocket(cfg.Network.UnixSocket.Path, mode))
+		}
+
+		if cfg.Network.TLS.CertFile != "" {
+			tlsProvider, err = newTLSProvider(cfg)
+			if err != nil {
+				l.Error("failed newTLSProvider", zap.Error(err))
+				return exitError
+			}
+
+			serverOptions = append(serverOptions, network.WithTLS(tlsProvider))
+		}
+
+		server = network.NewTCPServer(cfg.Network.Address, l.Named("network"), serverOptions...)
+	}
+
+	authStore, err := newAuthStore(cfg)
+	if err != nil {
+		l.Error("failed newAuthStore", zap.Error(err))
+		return exitError
+	}
+
+	slowLog := slowlog.New(cfg.SlowLog.Threshold, cfg.SlowLog.MaxLen)
+
+	var files persistence
+	defer func() {
+		if err := files.close(true); err != nil {
+			l.Error("failed files.close", zap.Error(err))
+		}
+	}()
+
+	storageOptions := []storage.Option{
+		storage.WithSlowLog(slowLog),
+		storage.WithInfoSection("clients", func() []storage.InfoField {
+			var connected int64
+			if server != nil {
+				connected = server.ActiveConnections()
+			}
+
+			return []storage.InfoField{{Key: "connected_clients", Value: fmt.Sprint(connected)}}
+		}),
+		storage.WithInfoSection("config", func() []storage.InfoField {
+			return configInfo(configManager.Current())
+		}),
+		storage.WithConfig(configManager),
+		storage.WithShutdown(requestShutdown),
+		storage.WithAuth(authStore),
+		storage.WithPubSub(broker),
+		storage.WithNotifier(notifier),
+		storage.WithReadOnly(cfg.Database.ReadOnly),
+		storage.WithInfoSection("pubsub", func() []storage.InfoField {
+			return []storage.InfoField{
+				{Key: "pubsub_channels", Value: fmt.Sprint(broker.Channels())},
+				{Key: "pubsub_patterns", Value: fmt.Sprint(broker.Patterns())},
+				{Key: "pubsub_slow_consumers", Value: fmt.Sprint(broker.SlowConsumers())},
+			}
+		}),
+	}
+
+	if cfg.CDC.Path != "" {
+		files.cdc, err = cdc.Open(cfg.CDC.Path, int64(cfg.CDC.SegmentSize), cfg.CDC.MaxSegments)
+		if err != nil {
+			l.Error("failed cdc.Open", zap.Error(err))
+			return exitError
+		}
+
+		changes := files.cdc
+		storageOptions = append(storageOptions,
+			storage.WithCDC(changes),
+			storage.WithInfoSection("cdc", func() []storage.InfoField {
+				first, last := changes.Offsets()
+				return []storage.InfoField{
+					{Key: "cdc_first_offset", Value: fmt.Sprint(first)},
+					{Key: "cdc_last_offset", Value: fmt.Sprint(last)},
+					{Key: "cdc_segments", Value: fmt.Sprint(changes.Segments())},
+				}
+			}),
+		)
 	}
 
-	engn := engine.New()
-	strg := storage.New(engn, l)
+	engn := engine.New(engine.WithLogger(l.Named("engine")))
+	engn.SetMaxMemory(int64(cfg.Database.MaxMemory))
+	strg := storage.New(engn, l.Named("storage"), storageOptions...)
 	prsr := parser.New()
-	cmpt := compute.New(prsr, l)
+	cmpt := compute.New(prsr, l.Named("compute"))
+
+	registry := metrics.NewRegistry()
+	interceptors := []database.Interceptor{database.SlowLogInterceptor(slowLog)}
+	if cfg.Trace.Path != "" {
+		files.trace, err = logger.OpenRotatingFile(cfg.Trace.Path, int64(cfg.Trace.MaxSize), 0, cfg.Trace.MaxBackups)
+		if err != nil {
+			l.Error("failed logger.OpenRotatingFile", zap.Error(err))
+			return exitError
+		}
+
+		interceptors = append(interceptors, database.TraceInterceptor(trace.NewJSONExporter(files.trace), l.Named("trace")))
+	}
+
+	interceptors = append(interceptors, database.LoggingInterceptor(l.Named("query")))
+	if cfg.Audit.Path != "" {
+		files.audit, err = audit.Open(cfg.Audit.Path, int64(cfg.Audit.MaxSize), audit.WithLogger(l.Named("audit")))
+		if err != nil {
+			l.Error("failed audit.Open", zap.Error(err))
+			return exitError
+		}
+
+		interceptors = append(interceptors, database.AuditInterceptor(files.audit, l.Named("audit")))
+	}
+
+	// отказ в доступе попадает в метрики, лог и журнал аудита
+	interceptors = append(interceptors,
+		database.MetricsInterceptor(database.NewMetrics(registry)),
+		database.AuthInterceptor(authStore),
+	)
+
+	options := []database.Option{
+		database.WithQueryTimeout(cfg.Database.QueryTimeout),
+		database.WithInterceptors(interceptors...),
+	}
+
+	dbase = database.New(cmpt, strg, l.Named("database"), options...)
+
+	registry.Register(
+		metrics.NewGaugeFunc("mdb_keys", "Number of keys in the engine.", func() float64 {
+			return float64(engn.Len())
+		}),
+		metrics.NewGaugeFunc("mdb_memory_bytes", "Approximate heap memory in use by the process.", func() float64 {
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			return float64(stats.HeapInuse)
+		}),
+	)
 
-	dbase := database.New(cmpt, strg, l)
+	configManager.OnChange(func(cfg *config.Config) {
+		applyConfig(cfg, logLevel, dbase, strg, engn, server, slowLog, broker, notifier)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go reloadOnSIGHUP(ctx, configManager, tlsProvider, l.Named("config"))
+
+	if cfg.Metrics.Address != "" {
+		go serveMetrics(ctx, cfg.Metrics.Address, registry, l)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	served := make(chan error, 1)
+	if server == nil {
+		go func() {
+			runREPL(ctx, dbase, l)
+			requestShutdown(true)
+		}()
+	} else {
+		registry.Register(metrics.NewGaugeFunc("mdb_connections", "Number of active client connections.", func() float64 {
+			return float64(server.ActiveConnections())
+		}))
+
+		l.Info("Database started. Waiting for connections...")
+		go func() {
+			served <- server.HandleQueries(ctx, func(ctx context.Context, request []byte) []byte {
+				result, err := dbase.HandleQuery(ctx, string(request))
+				return []byte(database.FormatResponse(result, err))
+			})
+		}()
+	}
+
+	save := true
+	select {
+	case sig := <-signals:
+		l.Info("Received signal, shutting down", zap.String("signal", sig.String()))
+	case save = <-shutdownRequests:
+		l.Info("Shutdown requested", zap.Bool("save", save))
+	case err = <-served:
+		if err != nil {
+			l.Error("failed server.HandleQueries", zap.Error(err))
+			return exitError
+		}
+	}
+
+	code := exitOK
+	if server != nil {
+		code = drain(server, configManager.Current().Shutdown.GracePeriod, signals, l)
+		if err = <-served; err != nil {
+			l.Error("failed server.HandleQueries", zap.Error(err))
+		}
+	}
+
+	if err = files.close(save); err != nil {
+		l.Error("failed files.close", zap.Error(err))
+		code = max(code, exitError)
+	}
 
+	l.Info("Database stopped", zap.Int("exit_code", code))
+
+	return code
+}
+
+// drain дожидается завершения выполняемых запросов не дольше gracePeriod.
+// Повторный сигнал прерывает ожидание
+func drain(server *network.TCPServer, gracePeriod time.Duration, signals <-chan os.Signal, l *zap.Logger) int {
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	go func() {
+		select {
+		case sig := <-signals:
+			l.Warn("Received second signal, aborting in-flight queries", zap.String("signal", sig.String()))
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	l.Info("Draining connections", zap.Duration("grace_period", gracePeriod))
+	if err := server.Shutdown(ctx); err != nil {
+		l.Warn("failed server.Shutdown", zap.Error(err))
+		return exitForced
+	}
+
+	return exitOK
+}
+
+// persistence - файлы, которые нужно сбросить на диск и закрыть при остановке
+type persistence struct {
+	audit *audit.Log
+	trace *logger.RotatingFile
+	cdc   *cdc.Log
+}
+
+// close закрывает файлы. При save данные предварительно сбрасываются на диск;
+// повторный вызов ничего не делает
+func (p *persistence) close(save bool) error {
+	var errs []error
+	if p.audit != nil {
+		if save {
+			errs = append(errs, p.audit.Sync())
+		}
+		errs = append(errs, p.audit.Close())
+		p.audit = nil
+	}
+
+	if p.trace != nil {
+		if save {
+			errs = append(errs, p.trace.Sync())
+		}
+		errs = append(errs, p.trace.Close())
+		p.trace = nil
+	}
+
+	if p.cdc != nil {
+		if save {
+			errs = append(errs, p.cdc.Sync())
+		}
+		errs = append(errs, p.cdc.Close())
+		p.cdc = nil
+	}
+
+	return errors.Join(errs...)
+}
+
+// newTLSProvider загружает сертификаты сервера и CA клиентов из конфигурации
+func newTLSProvider(cfg *config.Config) (*network.TLSProvider, error) {
+	tls := cfg.Network.TLS
+	clientAuth, err := network.ParseClientAuth(tls.ClientAuth, tls.ClientCAFile != "")
+	if err != nil {
+		return nil, fmt.Errorf("failed network.ParseClientAuth: %w", err)
+	}
+
+	provider, err := network.NewTLSProvider(tls.CertFile, tls.KeyFile, tls.ClientCAFile, clientAuth)
+	if err != nil {
+		return nil, fmt.Errorf("failed network.NewTLSProvider: %w", err)
+	}
+
+	return provider, nil
+}
+
+// newAuthStore собирает учетные записи из конфигурации и ACL-файла. Пользователи
+// из конфигурации получают все права. Без учетных записей аутентификация не требуется
+func newAuthStore(cfg *config.Config) (*auth.Store, error) {
+	options := []auth.Option{auth.WithLockout(cfg.Auth.MaxFailures, cfg.Auth.Lockout)}
+	if cfg.Auth.ACLFile != "" {
+		options = append(options, auth.WithACLFile(cfg.Auth.ACLFile))
+	}
+
+	store := auth.NewStore(options...)
+	for name, hash := range cfg.Auth.Users {
+		if err := store.SetUser(auth.User{Name: name, PasswordHash: hash, Permissions: auth.AllPermissions()}); err != nil {
+			return nil, fmt.Errorf("failed store.SetUser: %w", err)
+		}
+	}
+
+	if err := store.Load(); err != nil {
+		return nil, fmt.Errorf("failed store.Load: %w", err)
+	}
+
+	return store, nil
+}
+
+func configInfo(cfg *config.Config) []storage.InfoField {
+	params := cfg.PublicParams()
+	fields := make([]storage.InfoField, 0, len(params))
+	for _, param := range params {
+		fields = append(fields, storage.InfoField{Key: strings.ReplaceAll(param.Name, ".", "_"), Value: param.Value})
+	}
+
+	return fields
+}
+
+// applyConfig применяет динамические параметры новой конфигурации к работающим компонентам
+func applyConfig(
+	cfg *config.Config,
+	logLevel zap.AtomicLevel,
+	dbase *database.Database,
+	strg *storage.Storage,
+	engn *engine.Engine,
+	server *network.TCPServer,
+	slowLog *slowlog.Log,
+	broker *pubsub.Broker,
+	notifier *keyspace.Notifier,
+) {
+	if level, err := zapcore.ParseLevel(cfg.Logger.Level); err == nil {
+		logLevel.SetLevel(level)
+	}
+
+	dbase.SetQueryTimeout(cfg.Database.QueryTimeout)
+	strg.SetReadOnly(cfg.Database.ReadOnly)
+	engn.SetMaxMemory(int64(cfg.Database.MaxMemory))
+	slowLog.SetThreshold(cfg.SlowLog.Threshold)
+	broker.SetBufferSize(cfg.PubSub.BufferSize)
+	if classes, err := keyspace.ParseClasses(cfg.PubSub.KeyspaceEvents); err == nil {
+		notifier.SetClasses(classes)
+	}
+
+	if server != nil {
+		server.SetMaxConnections(cfg.Network.MaxConnections)
+		server.SetMaxMessageSize(int(cfg.Network.MaxMessageSize))
+		server.SetIdleTimeout(cfg.Network.IdleTimeout)
+		server.SetMaxInFlight(cfg.Network.MaxInFlight)
+	}
+}
+
+// reloadOnSIGHUP перечитывает конфигурацию и TLS-сертификаты при получении SIGHUP
+func reloadOnSIGHUP(ctx context.Context, m *config.Manager, tlsProvider *network.TLSProvider, l *zap.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			if tlsProvider != nil {
+				if err := tlsProvider.Reload(); err != nil {
+					l.Error("failed tlsProvider.Reload", zap.Error(err))
+				} else {
+					l.Info("TLS certificates reloaded")
+				}
+			}
+
+			if err := m.Reload(); err != nil {
+				l.Error("failed m.Reload", zap.String("path", m.Path()), zap.Error(err))
+				continue
+			}
+
+			l.Info("Configuration reloaded", zap.String("path", m.Path()))
+		}
+	}
+}
+
+func serveMetrics(ctx context.Context, address string, registry *metrics.Registry, l *zap.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry.Handler())
+
+	srv := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		_ = srv.Shutdown(shutdownCtx)
+	})
+	defer stop()
+
+	l.Info("Serving metrics", zap.String("address", address))
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		l.Error("failed srv.ListenAndServe", zap.Error(err))
+	}
+}
+
+// runREPL читает команды из stdin, если сетевой адрес не задан
+func runREPL(ctx context.Context, dbase *database.Database, l *zap.Logger) {
+	ctx = session.NewContext(ctx, session.New("stdin", ""))
 	scanner := bufio.NewScanner(os.Stdin)
 	l.Info("Database started. Waiting for commands...")
 
@@ -55,16 +529,11 @@ func main() {
 			continue
 		}
 
-		var result string
-		result, err = dbase.HandleQuery(input)
-		if err != nil {
-			l.Error("failed c.ProcessRequest", zap.Error(err))
-		} else if result != "" {
-			l.Info("Request processed successfully", zap.String("result", result))
-		}
+		result, err := dbase.HandleQuery(ctx, input)
+		fmt.Println(database.FormatResponse(result, err))
 	}
 
-	if err = scanner.Err(); err != nil {
+	if err := scanner.Err(); err != nil {
 		l.Error("Error reading from input", zap.Error(err))
 	}
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/patyukin/mdb/internal/auth"
+	"log"
+	"os"
+	"strings"
+)
+
+// passwd читает пароль из первой строки stdin и печатает его хэш для auth.users
+// или строку ACL-файла, если задан пользователь
+func main() {
+	user := flag.String("user", "", "Print an ACL file line for the user")
+	flag.Parse()
+
+	scanner := bufio.NewScanner(os.Stdin)
+	if !scanner.Scan() {
+		log.Fatalf("Failed to read password from stdin: %v", scanner.Err())
+	}
+
+	password := strings.TrimRight(scanner.Text(), "\r")
+	if password == "" {
+		log.Fatalf("Password must not be empty")
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		log.Fatalf("Failed to hash password: %v", err)
+	}
+
+	if *user != "" {
+		fmt.Printf("user %s %s\n", *user, hash)
+		return
+	}
+
+	fmt.Println(hash)
+}
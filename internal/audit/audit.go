@@ -0,0 +1,404 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultMaxSize - размер файла журнала, после которого выполняется ротация
+const DefaultMaxSize = 64 << 20
+
+// GenesisHash - значение prev_hash первой записи журнала
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// Record - запись журнала аудита. Каждая запись содержит хэш предыдущей,
+// поэтому удаление или изменение записи обнаруживается при проверке
+type Record struct {
+	Seq      uint64    `json:"seq"`
+	Time     time.Time `json:"time"`
+	Client   string    `json:"client"`
+	User     string    `json:"user,omitempty"`
+	Command  string    `json:"command"`
+	Args     []string  `json:"args"`
+	Status   string    `json:"status"`
+	PrevHash string    `json:"prev_hash"`
+	Hash     string    `json:"hash"`
+}
+
+// computeHash считает хэш записи без поля Hash
+func (r Record) computeHash() (string, error) {
+	r.Hash = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("failed json.Marshal: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Log пишет записи аудита в файл в формате JSON lines с ротацией по размеру.
+// Ротированные файлы получают суффикс с номером первой записи в них
+type Log struct {
+	path    string
+	maxSize int64
+	logger  *zap.Logger
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	firstSeq uint64
+	lastSeq  uint64
+	lastHash string
+}
+
+// Option настраивает Log
+type Option func(*Log)
+
+// WithLogger задает логгер для предупреждений при открытии журнала
+func WithLogger(logger *zap.Logger) Option {
+	return func(l *Log) {
+		l.logger = logger
+	}
+}
+
+// Open открывает журнал и продолжает цепочку записей, если он уже существует.
+// Неполная последняя строка, оставшаяся после аварийной остановки во время записи,
+// отрезается с предупреждением в лог
+func Open(path string, maxSize int64, options ...Option) (*Log, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+
+	l := &Log{
+		path:     path,
+		maxSize:  maxSize,
+		logger:   zap.NewNop(),
+		lastHash: GenesisHash,
+	}
+
+	for _, option := range options {
+		option(l)
+	}
+
+	torn, err := truncateTornTail(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if torn > 0 {
+		l.logger.Warn("Truncated torn audit log record", zap.String("path", path), zap.Int64("bytes", torn))
+	}
+
+	files, err := Files(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// последняя запись может находиться в ротированном файле, если текущий пуст
+	for i := len(files) - 1; i >= 0; i-- {
+		last, found, err := lastRecord(files[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			l.lastSeq, l.lastHash = last.Seq, last.Hash
+			break
+		}
+	}
+
+	if err = l.open(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *Log) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("failed os.MkdirAll: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed os.OpenFile: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed f.Stat: %w", err)
+	}
+
+	l.file = f
+	l.size = info.Size()
+	l.firstSeq = 0
+
+	return nil
+}
+
+// Write добавляет запись в журнал, заполняя номер, время и хэши
+func (l *Log) Write(r Record) (Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return Record{}, os.ErrClosed
+	}
+
+	r.Seq = l.lastSeq + 1
+	r.Time = time.Now().UTC()
+	r.PrevHash = l.lastHash
+	if r.Args == nil {
+		r.Args = []string{}
+	}
+
+	hash, err := r.computeHash()
+	if err != nil {
+		return Record{}, err
+	}
+	r.Hash = hash
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return Record{}, fmt.Errorf("failed json.Marshal: %w", err)
+	}
+	line = append(line, '\n')
+
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err = l.rotate(); err != nil {
+			return Record{}, err
+		}
+	}
+
+	if _, err = l.file.Write(line); err != nil {
+		return Record{}, fmt.Errorf("failed l.file.Write: %w", err)
+	}
+
+	if l.firstSeq == 0 {
+		l.firstSeq = r.Seq
+	}
+	l.size += int64(len(line))
+	l.lastSeq, l.lastHash = r.Seq, r.Hash
+
+	return r, nil
+}
+
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed l.file.Close: %w", err)
+	}
+
+	firstSeq := l.firstSeq
+	if firstSeq == 0 {
+		// файл был открыт после перезапуска: номер первой записи берем из него
+		first, found, err := firstRecord(l.path)
+		if err != nil {
+			return err
+		}
+
+		if found {
+			firstSeq = first.Seq
+		}
+	}
+
+	if err := os.Rename(l.path, fmt.Sprintf("%s.%d", l.path, firstSeq)); err != nil {
+		return fmt.Errorf("failed os.Rename: %w", err)
+	}
+
+	return l.open()
+}
+
+// Sync сбрасывает записанные данные на диск
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	return l.file.Sync()
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+
+	return err
+}
+
+// Files возвращает файлы журнала в порядке записи: ротированные, затем текущий
+func Files(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, fmt.Errorf("failed filepath.Glob: %w", err)
+	}
+
+	type rotated struct {
+		path string
+		seq  uint64
+	}
+
+	var files []rotated
+	for _, match := range matches {
+		seq, err := strconv.ParseUint(strings.TrimPrefix(match, path+"."), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		files = append(files, rotated{path: match, seq: seq})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].seq < files[j].seq })
+
+	result := make([]string, 0, len(files)+1)
+	for _, f := range files {
+		result = append(result, f.path)
+	}
+
+	if _, err = os.Stat(path); err == nil {
+		result = append(result, path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed os.Stat: %w", err)
+	}
+
+	return result, nil
+}
+
+func firstRecord(path string) (Record, bool, error) {
+	var first Record
+	found := false
+	err := scanRecords(path, func(_ int, line []byte) error {
+		if err := json.Unmarshal(line, &first); err != nil {
+			return err
+		}
+
+		found = true
+		return io.EOF
+	})
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+
+	return first, found, err
+}
+
+func lastRecord(path string) (Record, bool, error) {
+	var last []byte
+	err := scanRecords(path, func(_ int, line []byte) error {
+		last = append(last[:0], line...)
+		return nil
+	})
+	if err != nil || last == nil {
+		return Record{}, false, err
+	}
+
+	var r Record
+	if err = json.Unmarshal(last, &r); err != nil {
+		return Record{}, false, fmt.Errorf("%s: failed json.Unmarshal of last record: %w", path, err)
+	}
+
+	return r, true, nil
+}
+
+// truncateTornTail отрезает байты после последнего перевода строки: запись добавляется
+// одним вызовом Write вместе с переводом строки, поэтому строка без него записана не полностью.
+// Возвращает число отрезанных байт
+func truncateTornTail(path string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("failed os.OpenFile: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed f.Stat: %w", err)
+	}
+
+	size := info.Size()
+	end := size
+	buf := make([]byte, 64<<10)
+	for end > 0 {
+		n := min(int64(len(buf)), end)
+		if _, err = f.ReadAt(buf[:n], end-n); err != nil {
+			return 0, fmt.Errorf("failed f.ReadAt: %w", err)
+		}
+
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			end = end - n + int64(i) + 1
+			break
+		}
+
+		end -= n
+	}
+
+	if end == size {
+		return 0, nil
+	}
+
+	if err = f.Truncate(end); err != nil {
+		return 0, fmt.Errorf("failed f.Truncate: %w", err)
+	}
+
+	return size - end, nil
+}
+
+func scanRecords(path string, fn func(lineNo int, line []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("failed os.Open: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64<<10), 16<<20)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if err = fn(lineNo, line); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
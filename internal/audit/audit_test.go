@@ -0,0 +1,197 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRecords(t *testing.T, l *Log, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		_, err := l.Write(Record{
+			Client:  "127.0.0.1:5000",
+			Command: "SET",
+			Args:    []string{fmt.Sprintf("key%d", i), "value"},
+			Status:  "OK",
+		})
+		require.NoError(t, err)
+	}
+}
+
+func TestLog_WriteAndVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := Open(path, 0)
+	require.NoError(t, err)
+
+	first, err := l.Write(Record{Client: "127.0.0.1:5000", Command: "DEL", Args: []string{"key"}, Status: "ERR_NOT_FOUND"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), first.Seq)
+	assert.Equal(t, GenesisHash, first.PrevHash)
+	assert.Len(t, first.Hash, 64)
+
+	second, err := l.Write(Record{Client: "127.0.0.1:5000", Command: "SLOWLOG", Args: []string{"RESET"}, Status: "OK"})
+	require.NoError(t, err)
+	assert.Equal(t, first.Hash, second.PrevHash)
+	require.NoError(t, l.Close())
+
+	summary, err := Verify(path)
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Records: 2, FirstSeq: 1, LastSeq: 2, LastHash: second.Hash}, summary)
+}
+
+func TestLog_RotationAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := Open(path, 512)
+	require.NoError(t, err)
+	writeRecords(t, l, 10)
+	require.NoError(t, l.Close())
+
+	l, err = Open(path, 512)
+	require.NoError(t, err)
+	writeRecords(t, l, 10)
+	require.NoError(t, l.Close())
+
+	files, err := Files(path)
+	require.NoError(t, err)
+	assert.Greater(t, len(files), 2)
+	assert.Equal(t, path+".1", files[0])
+	assert.Equal(t, path, files[len(files)-1])
+
+	summary, err := Verify(files...)
+	require.NoError(t, err)
+	assert.Equal(t, 20, summary.Records)
+	assert.Equal(t, uint64(20), summary.LastSeq)
+
+	// без первого файла цепочка не начинается с первой записи
+	_, err = Verify(files[1:]...)
+	assert.ErrorIs(t, err, ErrGap)
+
+	// после удаления старых файлов проверка начинается с якоря
+	removed, err := Verify(files[0])
+	require.NoError(t, err)
+	summary, err = VerifyFrom(Anchor{Seq: removed.LastSeq + 1, PrevHash: removed.LastHash}, files[1:]...)
+	require.NoError(t, err)
+	assert.Equal(t, removed.LastSeq+1, summary.FirstSeq)
+	assert.Equal(t, uint64(20), summary.LastSeq)
+
+	_, err = VerifyFrom(Anchor{Seq: removed.LastSeq + 1, PrevHash: GenesisHash}, files[1:]...)
+	assert.ErrorIs(t, err, ErrBroken)
+
+	// ротированный файл должен начинаться с записи, указанной в имени
+	require.NoError(t, os.Rename(files[1], path+".1000"))
+	_, err = Verify(files[0], path+".1000")
+	assert.ErrorIs(t, err, ErrGap)
+}
+
+func TestOpen_TruncatesTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := Open(path, 0)
+	require.NoError(t, err)
+	writeRecords(t, l, 3)
+	require.NoError(t, l.Close())
+
+	valid, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// аварийная остановка во время записи оставляет неполную строку
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"seq":4,"time":"2024-`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	l, err = Open(path, 0)
+	require.NoError(t, err)
+	writeRecords(t, l, 1)
+	require.NoError(t, l.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), string(valid)))
+
+	summary, err := Verify(path)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), summary.LastSeq)
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(lines []string) []string
+		expected error
+	}{
+		{
+			name: "Изменение записи",
+			modify: func(lines []string) []string {
+				lines[2] = strings.Replace(lines[2], `"status":"OK"`, `"status":"ERR_INTERNAL"`, 1)
+				return lines
+			},
+			expected: ErrTampered,
+		},
+		{
+			name: "Удаление записи",
+			modify: func(lines []string) []string {
+				return append(lines[:2], lines[3:]...)
+			},
+			expected: ErrGap,
+		},
+		{
+			name: "Удаление начала журнала",
+			modify: func(lines []string) []string {
+				return lines[2:]
+			},
+			expected: ErrGap,
+		},
+		{
+			name: "Перестановка записей",
+			modify: func(lines []string) []string {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			expected: ErrGap,
+		},
+		{
+			name: "Повреждение JSON",
+			modify: func(lines []string) []string {
+				lines[0] = lines[0][:10]
+				return lines
+			},
+			expected: ErrTampered,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+
+			l, err := Open(path, 0)
+			require.NoError(t, err)
+			writeRecords(t, l, 5)
+			require.NoError(t, l.Close())
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			lines := tt.modify(strings.Split(strings.TrimSpace(string(data)), "\n"))
+			require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+
+			_, err = Verify(path)
+			assert.ErrorIs(t, err, tt.expected)
+
+			var verifyErr *VerifyError
+			if assert.ErrorAs(t, err, &verifyErr) {
+				assert.Equal(t, path, verifyErr.File)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrGap      = errors.New("audit log has a gap")
+	ErrTampered = errors.New("audit log record was modified")
+	ErrBroken   = errors.New("audit log chain is broken")
+)
+
+// VerifyError указывает на запись, не прошедшую проверку
+type VerifyError struct {
+	File string
+	Line int
+	Seq  uint64
+	Err  error
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("%s:%d: record %d: %v", e.File, e.Line, e.Seq, e.Err)
+}
+
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+// Summary - результат успешной проверки
+type Summary struct {
+	Records  int
+	FirstSeq uint64
+	LastSeq  uint64
+	LastHash string
+}
+
+// Anchor - начало проверяемой цепочки: номер первой записи и ее prev_hash.
+// После удаления старых файлов журнала проверка начинается с якоря, сохраненного
+// заранее, например LastSeq+1 и LastHash из Summary проверки удаляемых файлов
+type Anchor struct {
+	Seq      uint64
+	PrevHash string
+}
+
+// GenesisAnchor - начало журнала
+var GenesisAnchor = Anchor{Seq: 1, PrevHash: GenesisHash}
+
+// Verify проверяет цепочку записей в файлах, переданных в порядке записи.
+// Цепочка должна начинаться с первой записи журнала, поэтому удаление начала журнала
+// обнаруживается
+func Verify(files ...string) (Summary, error) {
+	return VerifyFrom(GenesisAnchor, files...)
+}
+
+// VerifyFrom проверяет цепочку записей, которая должна начинаться с anchor
+func VerifyFrom(anchor Anchor, files ...string) (Summary, error) {
+	summary := Summary{LastSeq: anchor.Seq - 1, LastHash: anchor.PrevHash}
+
+	for _, file := range files {
+		// ротированный файл называется по номеру своей первой записи
+		fileSeq, rotated := rotatedSeq(file)
+		first := true
+
+		err := scanRecords(file, func(lineNo int, line []byte) error {
+			var r Record
+			if err := json.Unmarshal(line, &r); err != nil {
+				return &VerifyError{File: file, Line: lineNo, Err: fmt.Errorf("%w: %w", ErrTampered, err)}
+			}
+
+			fail := func(err error) error {
+				return &VerifyError{File: file, Line: lineNo, Seq: r.Seq, Err: err}
+			}
+
+			hash, err := r.computeHash()
+			if err != nil {
+				return fail(err)
+			}
+
+			if hash != r.Hash {
+				return fail(ErrTampered)
+			}
+
+			if first && rotated && r.Seq != fileSeq {
+				return fail(fmt.Errorf("%w: file must start with record %d", ErrGap, fileSeq))
+			}
+			first = false
+
+			if r.Seq != summary.LastSeq+1 {
+				return fail(fmt.Errorf("%w: expected record %d", ErrGap, summary.LastSeq+1))
+			}
+
+			if r.PrevHash != summary.LastHash {
+				return fail(ErrBroken)
+			}
+
+			if summary.Records == 0 {
+				summary.FirstSeq = r.Seq
+			}
+			summary.Records++
+			summary.LastSeq, summary.LastHash = r.Seq, r.Hash
+
+			return nil
+		})
+		if err != nil {
+			return summary, err
+		}
+	}
+
+	if summary.Records == 0 {
+		summary.LastSeq, summary.LastHash = 0, ""
+	}
+
+	return summary, nil
+}
+
+// rotatedSeq возвращает номер первой записи из имени ротированного файла
+func rotatedSeq(file string) (uint64, bool) {
+	name := filepath.Base(file)
+	i := strings.LastIndexByte(name, '.')
+	if i < 0 {
+		return 0, false
+	}
+
+	seq, err := strconv.ParseUint(name[i+1:], 10, 64)
+
+	return seq, err == nil
+}
@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/pkg/glob"
+)
+
+// categoryAll - категория, включающая все команды
+const categoryAll = "all"
+
+var ErrInvalidRule = errors.New("invalid ACL rule")
+
+// categories - категории, допустимые в правилах +@ и -@
+var categories = map[string]bool{
+	categoryAll:               true,
+	parser.CategoryRead:       true,
+	parser.CategoryWrite:      true,
+	parser.CategoryAdmin:      true,
+	parser.CategoryConnection: true,
+	parser.CategoryPubSub:     true,
+}
+
+// commandRule разрешает или запрещает команду либо категорию команд
+type commandRule struct {
+	allow    bool
+	category string
+	command  string
+}
+
+func (r commandRule) matches(spec parser.CommandSpec) bool {
+	if r.category != "" {
+		return r.category == categoryAll || spec.HasCategory(r.category)
+	}
+
+	return r.command == spec.Name
+}
+
+func (r commandRule) String() string {
+	sign := "-"
+	if r.allow {
+		sign = "+"
+	}
+
+	if r.category != "" {
+		return sign + "@" + r.category
+	}
+
+	return sign + r.command
+}
+
+// Permissions - права пользователя: правила для команд, применяемые по порядку,
+// и шаблоны доступных ключей. Пустые права ничего не разрешают
+type Permissions struct {
+	commands []commandRule
+	keys     []string
+}
+
+// AllPermissions разрешает все команды и все ключи
+func AllPermissions() Permissions {
+	return Permissions{
+		commands: []commandRule{{allow: true, category: categoryAll}},
+		keys:     []string{"*"},
+	}
+}
+
+// AllowsCommand проверяет, разрешена ли команда. Решение принимает последнее
+// подходящее правило
+func (p Permissions) AllowsCommand(spec parser.CommandSpec) bool {
+	allowed := false
+	for _, rule := range p.commands {
+		if rule.matches(spec) {
+			allowed = rule.allow
+		}
+	}
+
+	return allowed
+}
+
+// AllowsKey проверяет, соответствует ли ключ одному из шаблонов
+func (p Permissions) AllowsKey(key string) bool {
+	for _, pattern := range p.keys {
+		if glob.Match(pattern, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Rules возвращает права в виде правил, из которых они восстанавливаются
+func (p Permissions) Rules() []string {
+	return append(p.CommandRules(), p.KeyRules()...)
+}
+
+// CommandRules возвращает правила для команд
+func (p Permissions) CommandRules() []string {
+	if len(p.commands) == 0 {
+		return []string{"-@" + categoryAll}
+	}
+
+	rules := make([]string, 0, len(p.commands))
+	for _, rule := range p.commands {
+		rules = append(rules, rule.String())
+	}
+
+	return rules
+}
+
+// KeyRules возвращает шаблоны ключей
+func (p Permissions) KeyRules() []string {
+	if len(p.keys) == 0 {
+		return []string{"resetkeys"}
+	}
+
+	rules := make([]string, 0, len(p.keys))
+	for _, pattern := range p.keys {
+		rules = append(rules, "~"+pattern)
+	}
+
+	return rules
+}
+
+func (p *Permissions) addCommandRule(rule commandRule) {
+	// правило для всех команд перекрывает предыдущие, а повторное правило для той же
+	// команды или категории заменяет прежнее
+	rules := p.commands[:0:0]
+	if rule.category != categoryAll {
+		for _, r := range p.commands {
+			if r.category != rule.category || r.command != rule.command {
+				rules = append(rules, r)
+			}
+		}
+	}
+
+	if rule.category == categoryAll && !rule.allow {
+		p.commands = nil
+		return
+	}
+
+	p.commands = append(rules, rule)
+}
+
+func (p *Permissions) addKeyPattern(pattern string) {
+	if pattern == "*" {
+		p.keys = []string{"*"}
+		return
+	}
+
+	for _, existing := range p.keys {
+		if existing == pattern || existing == "*" {
+			return
+		}
+	}
+
+	p.keys = append(p.keys, pattern)
+}
+
+// ApplyRule меняет учетную запись по одному правилу ACL:
+//
+//	>пароль      задает пароль
+//	#хэш         задает хэш пароля
+//	+@категория  разрешает категорию команд, -@категория запрещает
+//	+КОМАНДА     разрешает команду, -КОМАНДА запрещает
+//	allcommands  то же, что +@all, nocommands - то же, что -@all
+//	~шаблон      разрешает ключи, соответствующие шаблону
+//	allkeys      то же, что ~*, resetkeys запрещает все ключи
+//	reset        снимает все права
+func (u *User) ApplyRule(rule string) error {
+	switch lower := strings.ToLower(rule); {
+	case strings.HasPrefix(rule, ">"):
+		hash, err := HashPassword(rule[1:])
+		if err != nil {
+			return err
+		}
+
+		u.PasswordHash = hash
+	case strings.HasPrefix(rule, "#"):
+		if err := ValidateHash(rule[1:]); err != nil {
+			return err
+		}
+
+		u.PasswordHash = rule[1:]
+	case strings.HasPrefix(rule, "~"):
+		if rule == "~" {
+			return fmt.Errorf("%w: empty key pattern", ErrInvalidRule)
+		}
+
+		u.Permissions.addKeyPattern(rule[1:])
+	case lower == "allkeys":
+		u.Permissions.addKeyPattern("*")
+	case lower == "resetkeys":
+		u.Permissions.keys = nil
+	case lower == "allcommands":
+		u.Permissions.addCommandRule(commandRule{allow: true, category: categoryAll})
+	case lower == "nocommands":
+		u.Permissions.addCommandRule(commandRule{category: categoryAll})
+	case lower == "reset":
+		u.Permissions = Permissions{}
+	case strings.HasPrefix(rule, "+"), strings.HasPrefix(rule, "-"):
+		cmdRule, err := parseCommandRule(rule)
+		if err != nil {
+			return err
+		}
+
+		u.Permissions.addCommandRule(cmdRule)
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidRule, rule)
+	}
+
+	return nil
+}
+
+func parseCommandRule(rule string) (commandRule, error) {
+	r := commandRule{allow: rule[0] == '+'}
+	name := rule[1:]
+
+	if category, ok := strings.CutPrefix(name, "@"); ok {
+		category = strings.ToLower(category)
+		if !categories[category] {
+			return r, fmt.Errorf("%w: unknown category %q", ErrInvalidRule, category)
+		}
+
+		r.category = category
+
+		return r, nil
+	}
+
+	name = strings.ToUpper(name)
+	if _, ok := parser.LookupCommand(name); !ok {
+		return r, fmt.Errorf("%w: unknown command %q", ErrInvalidRule, name)
+	}
+
+	r.command = name
+
+	return r, nil
+}
+
+func (p Permissions) clone() Permissions {
+	return Permissions{
+		commands: append([]commandRule(nil), p.commands...),
+		keys:     append([]string(nil), p.keys...),
+	}
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUser_ApplyRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []string
+		expected []string
+	}{
+		{"Без правил", nil, []string{"-@all", "resetkeys"}},
+		{"Категории и ключи", []string{"+@read", "-@admin", "~billing:*"}, []string{"+@read", "-@admin", "~billing:*"}},
+		{"Повтор заменяет правило", []string{"+@read", "+GET", "-@read"}, []string{"+GET", "-@read", "resetkeys"}},
+		{"allcommands перекрывает предыдущие", []string{"-GET", "allcommands", "-@admin"}, []string{"+@all", "-@admin", "resetkeys"}},
+		{"nocommands сбрасывает", []string{"+@read", "nocommands"}, []string{"-@all", "resetkeys"}},
+		{"allkeys", []string{"~a:*", "allkeys", "~b:*"}, []string{"-@all", "~*"}},
+		{"resetkeys", []string{"~a:*", "resetkeys", "~b:*"}, []string{"-@all", "~b:*"}},
+		{"Имя команды в любом регистре", []string{"+get"}, []string{"+GET", "resetkeys"}},
+		{"reset", []string{"+@all", "~*", "reset"}, []string{"-@all", "resetkeys"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var u User
+			for _, rule := range tt.rules {
+				require.NoError(t, u.ApplyRule(rule))
+			}
+
+			assert.Equal(t, tt.expected, u.Rules())
+		})
+	}
+}
+
+func TestUser_ApplyRule_Invalid(t *testing.T) {
+	for _, rule := range []string{"+@unknown", "+FOO", "~", "read", "#plain"} {
+		var u User
+		assert.Error(t, u.ApplyRule(rule), rule)
+	}
+}
+
+func TestUser_ApplyRule_Password(t *testing.T) {
+	var u User
+	require.NoError(t, u.ApplyRule(">secret"))
+
+	ok, err := VerifyPassword(u.PasswordHash, "secret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	hash := testHash("other")
+	require.NoError(t, u.ApplyRule("#"+hash))
+	assert.Equal(t, hash, u.PasswordHash)
+}
+
+func TestPermissions_AllowsCommand(t *testing.T) {
+	var u User
+	for _, rule := range []string{"+@read", "-INFO", "+SET"} {
+		require.NoError(t, u.ApplyRule(rule))
+	}
+
+	get, _ := parser.LookupCommand(parser.GET)
+	info, _ := parser.LookupCommand(parser.INFO)
+	set, _ := parser.LookupCommand(parser.SET)
+	del, _ := parser.LookupCommand(parser.DELETE)
+
+	assert.True(t, u.Permissions.AllowsCommand(get))
+	assert.False(t, u.Permissions.AllowsCommand(info))
+	assert.True(t, u.Permissions.AllowsCommand(set))
+	assert.False(t, u.Permissions.AllowsCommand(del))
+}
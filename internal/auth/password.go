@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	hashScheme       = "pbkdf2-sha256"
+	hashIterations   = 100_000
+	hashSaltLen      = 16
+	hashKeyLen       = sha256.Size
+	maxHashIteration = 10_000_000
+)
+
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// HashPassword возвращает хэш пароля со случайной солью в формате
+// pbkdf2-sha256$<итерации>$<соль>$<ключ>, соль и ключ закодированы в base64 без дополнения
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, hashSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed rand.Read: %w", err)
+	}
+
+	return formatHash(hashIterations, salt, pbkdf2([]byte(password), salt, hashIterations, hashKeyLen)), nil
+}
+
+func formatHash(iterations int, salt, key []byte) string {
+	return strings.Join([]string{
+		hashScheme,
+		strconv.Itoa(iterations),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	}, "$")
+}
+
+// VerifyPassword сравнивает пароль с хэшем за время, не зависящее от совпадения
+func VerifyPassword(hash, password string) (bool, error) {
+	iterations, salt, key, err := parseHash(hash)
+	if err != nil {
+		return false, err
+	}
+
+	actual := pbkdf2([]byte(password), salt, iterations, len(key))
+
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+// ValidateHash проверяет формат хэша пароля
+func ValidateHash(hash string) error {
+	_, _, _, err := parseHash(hash)
+	return err
+}
+
+func parseHash(hash string) (int, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != hashScheme {
+		return 0, nil, nil, fmt.Errorf("%w: expected %s$<iterations>$<salt>$<key>", ErrInvalidHash, hashScheme)
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 || iterations > maxHashIteration {
+		return 0, nil, nil, fmt.Errorf("%w: invalid iteration count %q", ErrInvalidHash, parts[1])
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil || len(salt) == 0 {
+		return 0, nil, nil, fmt.Errorf("%w: invalid salt", ErrInvalidHash)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return 0, nil, nil, fmt.Errorf("%w: invalid key", ErrInvalidHash)
+	}
+
+	return iterations, salt, key, nil
+}
+
+// pbkdf2 - PBKDF2 (RFC 8018) с HMAC-SHA256
+func pbkdf2(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	blocks := (keyLen + prf.Size() - 1) / prf.Size()
+
+	key := make([]byte, 0, blocks*prf.Size())
+	u := make([]byte, prf.Size())
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, uint32(block)))
+		u = prf.Sum(u[:0])
+
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+
+		key = append(key, t...)
+	}
+
+	return key[:keyLen]
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPBKDF2(t *testing.T) {
+	tests := []struct {
+		iterations int
+		expected   string
+	}{
+		{iterations: 1, expected: "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{iterations: 2, expected: "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+	}
+
+	for _, tt := range tests {
+		key := pbkdf2([]byte("password"), []byte("salt"), tt.iterations, 32)
+		assert.Equal(t, tt.expected, hex.EncodeToString(key))
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("secret")
+	require.NoError(t, err)
+	require.NoError(t, ValidateHash(hash))
+
+	other, err := HashPassword("secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "соль должна быть случайной")
+
+	ok, err := VerifyPassword(hash, "secret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = VerifyPassword(hash, "wrong")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestValidateHash(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{name: "Пустой", hash: ""},
+		{name: "Другая схема", hash: "md5$1$c2FsdA$a2V5"},
+		{name: "Нет ключа", hash: "pbkdf2-sha256$1$c2FsdA"},
+		{name: "Неверное число итераций", hash: "pbkdf2-sha256$0$c2FsdA$a2V5"},
+		{name: "Неверная соль", hash: "pbkdf2-sha256$1$!!$a2V5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, ValidateHash(tt.hash), ErrInvalidHash)
+		})
+	}
+}
@@ -0,0 +1,505 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/session"
+)
+
+const (
+	// DefaultMaxFailures - число неудачных попыток подряд, после которого клиент блокируется
+	// для пользователя
+	DefaultMaxFailures = 5
+	// DefaultLockout - время блокировки клиента после неудачных попыток
+	DefaultLockout = 30 * time.Second
+
+	// unixAddrPrefix - префикс адресов клиентов Unix-сокета
+	unixAddrPrefix = "unix:"
+
+	// noPassword обозначает в ACL-файле пользователя без пароля, который не может войти
+	noPassword = "-"
+)
+
+var (
+	ErrAuthRequired       = errors.New("authentication required")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrLockedOut          = errors.New("too many failed authentication attempts")
+	ErrNoPerm             = errors.New("permission denied")
+	ErrUnknownUser        = errors.New("unknown user")
+	ErrLastUser           = errors.New("cannot delete the last user")
+)
+
+// User - учетная запись с солью и хэшем пароля и правами доступа
+type User struct {
+	Name         string
+	PasswordHash string // пустой - пользователь не может пройти аутентификацию
+	Permissions  Permissions
+}
+
+// Rules возвращает правила, описывающие права пользователя
+func (u User) Rules() []string {
+	return u.Permissions.Rules()
+}
+
+func (u User) clone() User {
+	u.Permissions = u.Permissions.clone()
+	return u
+}
+
+// failures - неудачные попытки аутентификации пользователя с одного клиента
+type failures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// expired сообщает, что блокировка истекла, а последняя неудачная попытка старше
+// окна блокировки: такие попытки больше не учитываются
+func (f *failures) expired(now time.Time, lockout time.Duration) bool {
+	return !now.Before(f.lockedUntil) && now.Sub(f.last) >= lockout
+}
+
+// Store хранит учетные записи, проверяет пароли и права. Если учетных записей нет,
+// аутентификация не требуется
+type Store struct {
+	maxFailures int
+	lockout     time.Duration
+	aclFile     string
+	now         func() time.Time
+
+	mu       sync.RWMutex
+	users    map[string]User
+	failures map[string]*failures
+	prunedAt time.Time
+}
+
+// Option настраивает Store
+type Option func(*Store)
+
+// WithLockout блокирует вход пользователя с клиента на lockout после maxFailures
+// неудачных попыток подряд
+func WithLockout(maxFailures int, lockout time.Duration) Option {
+	return func(s *Store) {
+		if maxFailures > 0 {
+			s.maxFailures = maxFailures
+		}
+
+		if lockout > 0 {
+			s.lockout = lockout
+		}
+	}
+}
+
+// WithACLFile задает ACL-файл, который читает Load и перезаписывает каждое
+// изменение учетных записей
+func WithACLFile(path string) Option {
+	return func(s *Store) {
+		s.aclFile = path
+	}
+}
+
+func NewStore(options ...Option) *Store {
+	s := &Store{
+		maxFailures: DefaultMaxFailures,
+		lockout:     DefaultLockout,
+		now:         time.Now,
+		users:       make(map[string]User),
+		failures:    make(map[string]*failures),
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+// SetUser добавляет или заменяет учетную запись
+func (s *Store) SetUser(u User) error {
+	if u.PasswordHash != "" {
+		if err := ValidateHash(u.PasswordHash); err != nil {
+			return fmt.Errorf("user %s: %w", u.Name, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[u.Name] = u.clone()
+
+	return nil
+}
+
+// User возвращает учетную запись по имени
+func (s *Store) User(name string) (User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[name]
+
+	return u.clone(), ok
+}
+
+// Users возвращает все учетные записи, упорядоченные по имени
+func (s *Store) Users() []User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.sortedUsers()
+}
+
+func (s *Store) sortedUsers() []User {
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u.clone())
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+
+	return users
+}
+
+// ApplyRules создает учетную запись без прав или меняет существующую по правилам ACL
+// и сохраняет ACL-файл. При ошибке учетная запись не меняется
+func (s *Store) ApplyRules(name string, rules []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[name]
+	if !ok {
+		u = User{Name: name}
+	}
+
+	u = u.clone()
+	for _, rule := range rules {
+		if err := u.ApplyRule(rule); err != nil {
+			return err
+		}
+	}
+
+	prev := s.users[name]
+	s.users[name] = u
+	if err := s.save(); err != nil {
+		if ok {
+			s.users[name] = prev
+		} else {
+			delete(s.users, name)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// DeleteUsers удаляет учетные записи и возвращает число удаленных. Последнюю учетную
+// запись удалить нельзя: без учетных записей аутентификация отключается
+func (s *Store) DeleteUsers(names []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := make(map[string]User)
+	for _, name := range names {
+		if u, ok := s.users[name]; ok {
+			deleted[name] = u
+		}
+	}
+
+	if len(deleted) > 0 && len(deleted) == len(s.users) {
+		return 0, ErrLastUser
+	}
+
+	for name := range deleted {
+		delete(s.users, name)
+	}
+
+	if err := s.save(); err != nil {
+		for name, u := range deleted {
+			s.users[name] = u
+		}
+
+		return 0, err
+	}
+
+	return len(deleted), nil
+}
+
+// Enabled сообщает, требуется ли аутентификация
+func (s *Store) Enabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.users) > 0
+}
+
+// Authenticate проверяет пароль пользователя. Неудачные попытки считаются отдельно для
+// каждого пользователя и хоста клиента, а для клиентов Unix-сокета, у которых нет
+// различимых адресов, - для каждого соединения
+func (s *Store) Authenticate(name, password string, client *session.Session) error {
+	key := failureKey(name, client)
+
+	s.mu.RLock()
+	u, ok := s.users[name]
+	f := s.failures[key]
+	lockedUntil := time.Time{}
+	if f != nil {
+		lockedUntil = f.lockedUntil
+	}
+	s.mu.RUnlock()
+
+	if now := s.now(); now.Before(lockedUntil) {
+		return fmt.Errorf("%w, retry in %s", ErrLockedOut, lockedUntil.Sub(now).Round(time.Second))
+	}
+
+	// пароль проверяется и для неизвестного пользователя, чтобы время ответа
+	// не выдавало существование учетной записи
+	ok = ok && u.PasswordHash != ""
+	hash := u.PasswordHash
+	if !ok {
+		hash = dummyHash
+	}
+
+	valid, err := VerifyPassword(hash, password)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.pruneFailures(now)
+
+	if ok && valid {
+		delete(s.failures, key)
+		return nil
+	}
+
+	f = s.failures[key]
+	if f == nil || f.expired(now, s.lockout) {
+		f = &failures{}
+		s.failures[key] = f
+	}
+
+	f.count++
+	f.last = now
+	if f.count >= s.maxFailures {
+		f.count = 0
+		f.lockedUntil = now.Add(s.lockout)
+	}
+
+	return ErrInvalidCredentials
+}
+
+// pruneFailures удаляет устаревшие неудачные попытки не чаще раза в окно блокировки
+func (s *Store) pruneFailures(now time.Time) {
+	if now.Sub(s.prunedAt) < s.lockout {
+		return
+	}
+
+	for key, f := range s.failures {
+		if f.expired(now, s.lockout) {
+			delete(s.failures, key)
+		}
+	}
+
+	s.prunedAt = now
+}
+
+// dummyHash - хэш для проверки пароля неизвестного пользователя
+var dummyHash = formatHash(hashIterations, make([]byte, hashSaltLen), make([]byte, hashKeyLen))
+
+// failureKey возвращает ключ, по которому считаются неудачные попытки
+func failureKey(name string, client *session.Session) string {
+	if strings.HasPrefix(client.RemoteAddr, unixAddrPrefix) {
+		return fmt.Sprintf("%s\x00session:%d", name, client.ID)
+	}
+
+	host := client.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return name + "\x00" + host
+}
+
+// AllowsKey проверяет, доступен ли ключ пользователю. Если учетных записей нет,
+// доступны все ключи
+func (s *Store) AllowsKey(name, key string) bool {
+	if !s.Enabled() {
+		return true
+	}
+
+	u, ok := s.User(name)
+
+	return ok && u.Permissions.AllowsKey(key)
+}
+
+// Authorize запрещает команды, кроме AUTH и PING, до успешной аутентификации,
+// а после нее - команды и ключи, не разрешенные правами пользователя
+func (s *Store) Authorize(ctx context.Context, cmd *parser.Command) error {
+	if !s.Enabled() || cmd.Action == parser.AUTH {
+		return nil
+	}
+
+	var name string
+	if sess, ok := session.FromContext(ctx); ok {
+		name = sess.User()
+	}
+
+	u, ok := s.User(name)
+	if !ok {
+		if cmd.Action == parser.PING {
+			return nil
+		}
+
+		return ErrAuthRequired
+	}
+
+	// узнать свое имя может любой пользователь
+	if cmd.Action == parser.ACL && len(cmd.Args) > 0 && strings.EqualFold(cmd.Args[0], parser.WHOAMI) {
+		return nil
+	}
+
+	spec, ok := parser.LookupCommand(cmd.Action)
+	if !ok {
+		return nil
+	}
+
+	if !u.Permissions.AllowsCommand(spec) {
+		return fmt.Errorf("%w: user %s cannot run the '%s' command", ErrNoPerm, u.Name, cmd.Action)
+	}
+
+	for _, key := range spec.KeyArgs(cmd.Args) {
+		if !u.Permissions.AllowsKey(key) {
+			return fmt.Errorf("%w: user %s cannot access the '%s' key", ErrNoPerm, u.Name, key)
+		}
+	}
+
+	return nil
+}
+
+// Load читает учетные записи из ACL-файла, если он задан и существует
+func (s *Store) Load() error {
+	if s.aclFile == "" {
+		return nil
+	}
+
+	users, err := LoadFile(s.aclFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		if err = s.SetUser(u); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// save перезаписывает ACL-файл текущими учетными записями. Вызывается под s.mu
+func (s *Store) save() error {
+	if s.aclFile == "" {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("# user <name> <password-hash|-> [rules...]\n")
+	for _, u := range s.sortedUsers() {
+		hash := u.PasswordHash
+		if hash == "" {
+			hash = noPassword
+		}
+
+		fmt.Fprintf(&b, "user %s %s %s\n", u.Name, hash, strings.Join(u.Rules(), " "))
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.aclFile), filepath.Base(s.aclFile)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed os.CreateTemp: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err = tmp.WriteString(b.String()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed tmp.WriteString: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed tmp.Close: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), s.aclFile); err != nil {
+		return fmt.Errorf("failed os.Rename: %w", err)
+	}
+
+	return nil
+}
+
+// LoadFile читает учетные записи из ACL-файла. Каждая строка имеет вид
+// "user <имя> <хэш пароля или -> [правила...]", пустые строки и строки, начинающиеся
+// с #, пропускаются. Пользователь без правил получает все права
+func LoadFile(path string) ([]User, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed os.Open: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var users []User
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "user" {
+			return nil, fmt.Errorf("%s:%d: expected \"user <name> <password-hash> [rules...]\"", path, lineNo)
+		}
+
+		u := User{Name: fields[1], Permissions: AllPermissions()}
+		if fields[2] != noPassword {
+			if err = ValidateHash(fields[2]); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+			}
+
+			u.PasswordHash = fields[2]
+		}
+
+		if rules := fields[3:]; len(rules) > 0 {
+			u.Permissions = Permissions{}
+			for _, rule := range rules {
+				if err = u.ApplyRule(rule); err != nil {
+					return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+				}
+			}
+		}
+
+		users = append(users, u)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed scanner.Scan: %w", err)
+	}
+
+	return users, nil
+}
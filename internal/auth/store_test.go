@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testHash возвращает хэш с одной итерацией, чтобы тесты не тратили время на PBKDF2
+func testHash(password string) string {
+	salt := []byte("test-salt")
+	return formatHash(1, salt, pbkdf2([]byte(password), salt, 1, hashKeyLen))
+}
+
+func TestStore_Authenticate(t *testing.T) {
+	s := NewStore()
+	require.NoError(t, s.SetUser(User{Name: "alice", PasswordHash: testHash("secret")}))
+
+	assert.NoError(t, s.Authenticate("alice", "secret", session.New("127.0.0.1:1000", "")))
+	assert.ErrorIs(t, s.Authenticate("alice", "wrong", session.New("127.0.0.1:1000", "")), ErrInvalidCredentials)
+	assert.ErrorIs(t, s.Authenticate("bob", "secret", session.New("127.0.0.1:1000", "")), ErrInvalidCredentials)
+
+	assert.ErrorIs(t, s.SetUser(User{Name: "bob", PasswordHash: "plain"}), ErrInvalidHash)
+}
+
+func TestStore_Lockout(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewStore(WithLockout(2, time.Minute))
+	s.now = func() time.Time { return now }
+	require.NoError(t, s.SetUser(User{Name: "alice", PasswordHash: testHash("secret")}))
+	require.NoError(t, s.SetUser(User{Name: "bob", PasswordHash: testHash("secret")}))
+
+	assert.ErrorIs(t, s.Authenticate("alice", "wrong", session.New("10.0.0.1:1000", "")), ErrInvalidCredentials)
+	assert.ErrorIs(t, s.Authenticate("alice", "wrong", session.New("10.0.0.1:1001", "")), ErrInvalidCredentials)
+
+	// блокируется хост, а не соединение, и даже верный пароль отклоняется
+	err := s.Authenticate("alice", "secret", session.New("10.0.0.1:1002", ""))
+	assert.ErrorIs(t, err, ErrLockedOut)
+	assert.Contains(t, err.Error(), "retry in 1m0s")
+
+	// другие клиенты и другие пользователи того же хоста не затронуты
+	assert.NoError(t, s.Authenticate("alice", "secret", session.New("10.0.0.2:1000", "")))
+	assert.NoError(t, s.Authenticate("bob", "secret", session.New("10.0.0.1:1004", "")))
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, s.Authenticate("alice", "secret", session.New("10.0.0.1:1003", "")))
+}
+
+func TestStore_LockoutUnixSocket(t *testing.T) {
+	s := NewStore(WithLockout(2, time.Minute))
+	require.NoError(t, s.SetUser(User{Name: "alice", PasswordHash: testHash("secret")}))
+
+	// у клиентов Unix-сокета одинаковый адрес, поэтому попытки считаются по соединению
+	attacker := session.New("unix:/tmp/mdb.sock", "")
+	assert.ErrorIs(t, s.Authenticate("alice", "wrong", attacker), ErrInvalidCredentials)
+	assert.ErrorIs(t, s.Authenticate("alice", "wrong", attacker), ErrInvalidCredentials)
+	assert.ErrorIs(t, s.Authenticate("alice", "secret", attacker), ErrLockedOut)
+
+	assert.NoError(t, s.Authenticate("alice", "secret", session.New("unix:/tmp/mdb.sock", "")))
+}
+
+func TestStore_FailuresExpire(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewStore(WithLockout(2, time.Minute))
+	s.now = func() time.Time { return now }
+	require.NoError(t, s.SetUser(User{Name: "alice", PasswordHash: testHash("secret")}))
+
+	for i := 0; i < 10; i++ {
+		_ = s.Authenticate("alice", "wrong", session.New(fmt.Sprintf("10.0.1.%d:1000", i), ""))
+	}
+	assert.Len(t, s.failures, 10)
+
+	// неудачная попытка старше окна блокировки не учитывается
+	now = now.Add(time.Minute)
+	assert.ErrorIs(t, s.Authenticate("alice", "wrong", session.New("10.0.1.0:1000", "")), ErrInvalidCredentials)
+	assert.NoError(t, s.Authenticate("alice", "secret", session.New("10.0.1.0:1000", "")))
+
+	// устаревшие записи удаляются и без успешного входа
+	assert.Empty(t, s.failures)
+}
+
+func TestStore_Authorize(t *testing.T) {
+	sess := session.New("127.0.0.1:1000", "")
+	ctx := session.NewContext(context.Background(), sess)
+	get := &parser.Command{Action: parser.GET, Args: []string{"key"}}
+
+	s := NewStore()
+	assert.NoError(t, s.Authorize(ctx, get), "без пользователей аутентификация не требуется")
+
+	require.NoError(t, s.SetUser(User{Name: "alice", PasswordHash: testHash("secret"), Permissions: AllPermissions()}))
+	assert.ErrorIs(t, s.Authorize(ctx, get), ErrAuthRequired)
+	assert.ErrorIs(t, s.Authorize(context.Background(), get), ErrAuthRequired)
+	assert.NoError(t, s.Authorize(ctx, &parser.Command{Action: parser.PING}))
+	assert.NoError(t, s.Authorize(ctx, &parser.Command{Action: parser.AUTH, Args: []string{"alice", "secret"}}))
+
+	sess.SetUser("alice")
+	assert.NoError(t, s.Authorize(ctx, get))
+}
+
+func TestLoadFile(t *testing.T) {
+	hash := testHash("secret")
+	path := filepath.Join(t.TempDir(), "users.acl")
+	require.NoError(t, os.WriteFile(path, []byte("# users\n\nuser alice "+hash+"\n"), 0o600))
+
+	users, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []User{{Name: "alice", PasswordHash: hash, Permissions: AllPermissions()}}, users)
+
+	require.NoError(t, os.WriteFile(path, []byte("user alice\n"), 0o600))
+	_, err = LoadFile(path)
+	assert.ErrorContains(t, err, "users.acl:1")
+
+	require.NoError(t, os.WriteFile(path, []byte("user alice plain\n"), 0o600))
+	_, err = LoadFile(path)
+	assert.ErrorIs(t, err, ErrInvalidHash)
+}
+
+func TestStore_AuthorizePermissions(t *testing.T) {
+	s := NewStore()
+	require.NoError(t, s.ApplyRules("admin", []string{">secret", "allcommands", "allkeys"}))
+	require.NoError(t, s.ApplyRules("billing", []string{">secret", "+@read", "+SET", "~billing:*"}))
+	require.NoError(t, s.ApplyRules("nobody", []string{">secret"}))
+
+	tests := []struct {
+		name      string
+		user      string
+		cmd       *parser.Command
+		expectErr error
+	}{
+		{"Администратору доступно все", "admin", &parser.Command{Action: parser.CONFIG, Args: []string{"GET", "*"}}, nil},
+		{"Чтение разрешенного ключа", "billing", &parser.Command{Action: parser.GET, Args: []string{"billing:1"}}, nil},
+		{"Запись разрешенной командой", "billing", &parser.Command{Action: parser.SET, Args: []string{"billing:1", "v"}}, nil},
+		{"Чужой ключ", "billing", &parser.Command{Action: parser.GET, Args: []string{"users:1"}}, ErrNoPerm},
+		{"Запрещенная команда", "billing", &parser.Command{Action: parser.DELETE, Args: []string{"billing:1"}}, ErrNoPerm},
+		{"Административная команда", "billing", &parser.Command{Action: parser.SLOWLOG, Args: []string{"LEN"}}, ErrNoPerm},
+		{"Команда без ключей", "billing", &parser.Command{Action: parser.DBSIZE}, nil},
+		{"ACL WHOAMI доступен всем", "nobody", &parser.Command{Action: parser.ACL, Args: []string{"whoami"}}, nil},
+		{"Пользователь без прав", "nobody", &parser.Command{Action: parser.PING}, ErrNoPerm},
+		{"Удаленный пользователь", "ghost", &parser.Command{Action: parser.GET, Args: []string{"k"}}, ErrAuthRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := session.New("127.0.0.1:1000", "")
+			sess.SetUser(tt.user)
+
+			err := s.Authorize(session.NewContext(context.Background(), sess), tt.cmd)
+			if tt.expectErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.expectErr)
+		})
+	}
+}
+
+func TestStore_ApplyRulesPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.acl")
+	s := NewStore(WithACLFile(path))
+	require.NoError(t, s.Load(), "отсутствующий файл не является ошибкой")
+
+	require.NoError(t, s.ApplyRules("alice", []string{">secret", "+@read", "-@admin", "~billing:*"}))
+	require.NoError(t, s.ApplyRules("bob", []string{"+GET"}))
+
+	assert.ErrorIs(t, s.ApplyRules("alice", []string{"+@unknown"}), ErrInvalidRule)
+	u, _ := s.User("alice")
+	assert.Equal(t, []string{"+@read", "-@admin", "~billing:*"}, u.Rules(), "неудачное изменение не применяется")
+
+	loaded := NewStore(WithACLFile(path))
+	require.NoError(t, loaded.Load())
+	assert.Equal(t, s.Users(), loaded.Users())
+	assert.NoError(t, loaded.Authenticate("alice", "secret", session.New("127.0.0.1:1", "")))
+	assert.ErrorIs(t, loaded.Authenticate("bob", "", session.New("127.0.0.1:1", "")), ErrInvalidCredentials)
+
+	n, err := s.DeleteUsers([]string{"bob", "carol"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = s.DeleteUsers([]string{"alice"})
+	assert.ErrorIs(t, err, ErrLastUser)
+
+	loaded = NewStore(WithACLFile(path))
+	require.NoError(t, loaded.Load())
+	assert.Len(t, loaded.Users(), 1)
+}
@@ -0,0 +1,440 @@
+package cdc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Операции, которые попадают в журнал изменений. Операции над коллекциями
+// передают элементы в Args
+const (
+	OpSet = "set"
+	OpDel = "del"
+	// OpHSet записывает в hash пары поле-значение из Args
+	OpHSet = "hset"
+	// OpHDel удаляет из hash поля из Args
+	OpHDel = "hdel"
+	// OpLPush и OpRPush добавляют в начало и в конец списка элементы из Args
+	OpLPush = "lpush"
+	OpRPush = "rpush"
+	// OpLPop и OpRPop извлекают из начала и с конца списка элементы из Args
+	OpLPop = "lpop"
+	OpRPop = "rpop"
+	// OpLTrim оставляет в списке элементы с индексами с Args[0] по Args[1]
+	OpLTrim = "ltrim"
+	// OpSAdd и OpSRem добавляют во множество и удаляют из него элементы из Args
+	OpSAdd = "sadd"
+	OpSRem = "srem"
+	// OpZAdd записывает в упорядоченное множество пары оценка-элемент из Args
+	OpZAdd = "zadd"
+	// OpZRem удаляет из упорядоченного множества элементы из Args
+	OpZRem = "zrem"
+)
+
+const (
+	// DefaultSegmentSize - размер сегмента, после которого начинается новый
+	DefaultSegmentSize = 16 << 20
+	// DefaultMaxSegments - число хранимых сегментов, включая текущий
+	DefaultMaxSegments = 8
+)
+
+// ErrOffsetTrimmed возвращается при чтении со смещения, которое уже удалено из журнала.
+// Потребитель, получивший эту ошибку, пропустил изменения и должен пересинхронизироваться
+var ErrOffsetTrimmed = errors.New("offset trimmed")
+
+// Change - изменение ключа. Смещения монотонно возрастают начиная с 1 и не переиспользуются
+type Change struct {
+	Offset uint64    `json:"offset"`
+	Time   time.Time `json:"time"`
+	Op     string    `json:"op"`
+	Key    string    `json:"key"`
+	Value  string    `json:"value,omitempty"`
+	Args   []string  `json:"args,omitempty"`
+}
+
+// segment - закрытый сегмент журнала
+type segment struct {
+	path  string
+	first uint64
+}
+
+// Log хранит последние изменения в файлах формата JSON lines. Текущий сегмент
+// пишется в path, заполненные получают суффикс со смещением первой записи.
+// Сверх maxSegments самые старые сегменты удаляются
+type Log struct {
+	path        string
+	segmentSize int64
+	maxSegments int
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	segments []segment
+	// currentFirst - смещение первой записи текущего сегмента, 0 для пустого
+	currentFirst uint64
+	last         uint64
+}
+
+// Open открывает журнал и продолжает нумерацию изменений, если он уже существует
+func Open(path string, segmentSize int64, maxSegments int) (*Log, error) {
+	if segmentSize <= 0 {
+		segmentSize = DefaultSegmentSize
+	}
+
+	if maxSegments <= 0 {
+		maxSegments = DefaultMaxSegments
+	}
+
+	l := &Log{
+		path:        path,
+		segmentSize: segmentSize,
+		maxSegments: maxSegments,
+	}
+
+	var err error
+	if l.segments, err = rotatedSegments(path); err != nil {
+		return nil, err
+	}
+
+	first, last, err := boundaries(path)
+	if err != nil {
+		return nil, err
+	}
+	l.currentFirst, l.last = first, last
+
+	// текущий сегмент пуст: последнее смещение берем из заполненных
+	for i := len(l.segments) - 1; i >= 0 && l.last == 0; i-- {
+		if _, l.last, err = boundaries(l.segments[i].path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = l.open(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *Log) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("failed os.MkdirAll: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed os.OpenFile: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed f.Stat: %w", err)
+	}
+
+	l.file = f
+	l.size = info.Size()
+
+	return nil
+}
+
+// Append записывает изменения одной записью в файл и возвращает их с присвоенными
+// смещениями. При ошибке ни одно из изменений не считается записанным
+func (l *Log) Append(changes ...Change) ([]Change, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil, os.ErrClosed
+	}
+
+	now := time.Now().UTC()
+	appended := make([]Change, 0, len(changes))
+	var batch []byte
+	for i, c := range changes {
+		c.Offset, c.Time = l.last+uint64(i)+1, now
+
+		line, err := json.Marshal(c)
+		if err != nil {
+			return nil, fmt.Errorf("failed json.Marshal: %w", err)
+		}
+
+		batch = append(append(batch, line...), '\n')
+		appended = append(appended, c)
+	}
+
+	if len(appended) == 0 {
+		return nil, nil
+	}
+
+	if l.size > 0 && l.size+int64(len(batch)) > l.segmentSize {
+		if err := l.rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := l.file.Write(batch); err != nil {
+		return nil, fmt.Errorf("failed l.file.Write: %w", err)
+	}
+
+	if l.currentFirst == 0 {
+		l.currentFirst = appended[0].Offset
+	}
+	l.size += int64(len(batch))
+	l.last = appended[len(appended)-1].Offset
+
+	return appended, nil
+}
+
+// rotate закрывает текущий сегмент и удаляет сегменты сверх ограничения
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed l.file.Close: %w", err)
+	}
+
+	rotated := segment{path: fmt.Sprintf("%s.%d", l.path, l.currentFirst), first: l.currentFirst}
+	if err := os.Rename(l.path, rotated.path); err != nil {
+		return fmt.Errorf("failed os.Rename: %w", err)
+	}
+
+	l.segments = append(l.segments, rotated)
+	l.currentFirst = 0
+
+	for len(l.segments) > l.maxSegments-1 {
+		if err := os.Remove(l.segments[0].path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed os.Remove: %w", err)
+		}
+
+		l.segments = l.segments[1:]
+	}
+
+	return l.open()
+}
+
+// Offsets возвращает первое хранимое и последнее записанное смещения.
+// В пустом журнале first больше last на единицу
+func (l *Log) Offsets() (first, last uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.firstLocked(), l.last
+}
+
+func (l *Log) firstLocked() uint64 {
+	switch {
+	case len(l.segments) > 0:
+		return l.segments[0].first
+	case l.currentFirst != 0:
+		return l.currentFirst
+	default:
+		return l.last + 1
+	}
+}
+
+// Segments возвращает число сегментов на диске, включая текущий
+func (l *Log) Segments() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.segments) + 1
+}
+
+// Read возвращает до count изменений начиная со смещения from. Смещение после
+// последнего записанного дает пустой результат, по которому потребитель
+// повторяет чтение позже. Удаленное смещение - ошибка ErrOffsetTrimmed
+func (l *Log) Read(from uint64, count int) ([]Change, error) {
+	files, last, err := l.openFrom(from)
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+
+	// файлы открыты под блокировкой и читаются без нее: ротация и удаление
+	// сегментов не мешают уже открытым дескрипторам
+	changes := make([]Change, 0, min(uint64(count), last-from+1))
+	for _, f := range files {
+		err = scanChanges(f, func(c Change) error {
+			if c.Offset < from {
+				return nil
+			}
+
+			changes = append(changes, c)
+			if len(changes) == count || c.Offset >= last {
+				return io.EOF
+			}
+
+			return nil
+		})
+		if errors.Is(err, io.EOF) {
+			return changes, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name(), err)
+		}
+	}
+
+	return changes, nil
+}
+
+// openFrom открывает сегменты, содержащие изменения начиная с from, и возвращает
+// последнее смещение на момент открытия
+func (l *Log) openFrom(from uint64) ([]*os.File, uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if first := l.firstLocked(); from < first {
+		return nil, 0, fmt.Errorf("%w: offset %d, oldest retained %d", ErrOffsetTrimmed, from, first)
+	}
+
+	if from > l.last {
+		return nil, l.last, nil
+	}
+
+	all := l.segments
+	if l.currentFirst != 0 {
+		all = append(all[:len(all):len(all)], segment{path: l.path, first: l.currentFirst})
+	}
+
+	// сегменты до последнего, начинающегося не позже from, содержат только более ранние изменения
+	start := 0
+	for i, s := range all {
+		if s.first <= from {
+			start = i
+		}
+	}
+
+	paths := make([]string, 0, len(all)-start)
+	for _, s := range all[start:] {
+		paths = append(paths, s.path)
+	}
+
+	files := make([]*os.File, 0, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			for _, opened := range files {
+				_ = opened.Close()
+			}
+
+			return nil, 0, fmt.Errorf("failed os.Open: %w", err)
+		}
+
+		files = append(files, f)
+	}
+
+	return files, l.last, nil
+}
+
+// Sync сбрасывает записанные данные на диск
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	return l.file.Sync()
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+
+	return err
+}
+
+// rotatedSegments возвращает заполненные сегменты журнала в порядке записи
+func rotatedSegments(path string) ([]segment, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, fmt.Errorf("failed filepath.Glob: %w", err)
+	}
+
+	var segments []segment
+	for _, match := range matches {
+		first, err := strconv.ParseUint(strings.TrimPrefix(match, path+"."), 10, 64)
+		if err != nil || first == 0 {
+			continue
+		}
+
+		segments = append(segments, segment{path: match, first: first})
+	}
+
+	sort.Slice(segments, func(i, j int) bool { return segments[i].first < segments[j].first })
+
+	return segments, nil
+}
+
+// boundaries возвращает смещения первой и последней записей файла, нули для пустого
+func boundaries(path string) (first, last uint64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, 0, nil
+		}
+
+		return 0, 0, fmt.Errorf("failed os.Open: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	err = scanChanges(f, func(c Change) error {
+		if first == 0 {
+			first = c.Offset
+		}
+		last = c.Offset
+
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return first, last, nil
+}
+
+func scanChanges(r io.Reader, fn func(c Change) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 16<<20)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var c Change
+		if err := json.Unmarshal(line, &c); err != nil {
+			return fmt.Errorf("failed json.Unmarshal: %w", err)
+		}
+
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
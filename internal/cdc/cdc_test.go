@@ -0,0 +1,99 @@
+package cdc
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendChanges(t *testing.T, l *Log, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		_, err := l.Append(Change{Op: OpSet, Key: fmt.Sprintf("key%d", i), Value: "value"})
+		require.NoError(t, err)
+	}
+}
+
+func offsets(changes []Change) []uint64 {
+	result := make([]uint64, 0, len(changes))
+	for _, c := range changes {
+		result = append(result, c.Offset)
+	}
+
+	return result
+}
+
+func TestLog_AppendAndRead(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "cdc.log"), 0, 0)
+	require.NoError(t, err)
+	defer func() { _ = l.Close() }()
+
+	first, last := l.Offsets()
+	assert.Equal(t, uint64(1), first)
+	assert.Equal(t, uint64(0), last)
+
+	appended, err := l.Append(Change{Op: OpSet, Key: "user:1", Value: "alice"})
+	require.NoError(t, err)
+	set := appended[0]
+	assert.Equal(t, uint64(1), set.Offset)
+
+	// изменения одного вызова получают последовательные смещения
+	appended, err = l.Append(Change{Op: OpDel, Key: "user:1"}, Change{Op: OpSet, Key: "user:2", Value: "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2, 3}, offsets(appended))
+	del := appended[0]
+
+	changes, err := l.Read(1, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	assert.Equal(t, Change{Offset: 1, Time: set.Time, Op: OpSet, Key: "user:1", Value: "alice"}, changes[0])
+	assert.Equal(t, Change{Offset: 2, Time: del.Time, Op: OpDel, Key: "user:1"}, changes[1])
+
+	changes, err = l.Read(2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2}, offsets(changes))
+
+	// чтение после последнего смещения - новых изменений пока нет
+	changes, err = l.Read(4, 10)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestLog_RotationAndTrim(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cdc.log")
+
+	l, err := Open(path, 256, 3)
+	require.NoError(t, err)
+	appendChanges(t, l, 30)
+	require.NoError(t, l.Close())
+
+	// после перезапуска нумерация продолжается
+	l, err = Open(path, 256, 3)
+	require.NoError(t, err)
+	defer func() { _ = l.Close() }()
+	appendChanges(t, l, 30)
+
+	assert.Equal(t, 3, l.Segments())
+	first, last := l.Offsets()
+	assert.Equal(t, uint64(60), last)
+	assert.Greater(t, first, uint64(1))
+
+	_, err = l.Read(first-1, 10)
+	assert.ErrorIs(t, err, ErrOffsetTrimmed)
+
+	// чтение через границы сегментов возвращает изменения без пропусков
+	changes, err := l.Read(first, 100)
+	require.NoError(t, err)
+	require.Len(t, changes, int(last-first+1))
+	for i, c := range changes {
+		assert.Equal(t, first+uint64(i), c.Offset)
+	}
+
+	changes, err = l.Read(last-2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{last - 2, last - 1}, offsets(changes))
+}
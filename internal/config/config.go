@@ -1,31 +1,173 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"gopkg.in/yaml.v3"
+	"io"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// ErrInvalidConfig - конфигурация не прошла проверку
+var ErrInvalidConfig = errors.New("config validation failed")
+
 type Config struct {
 	Logger struct {
-		Level string `yaml:"level" validate:"required,oneof=debug info warn error dpanic panic fatal"`
-		Mode  string `yaml:"mode" validate:"required,oneof=devel prod"`
-	}
+		Level    string   `yaml:"level" validate:"required,oneof=debug info warn error dpanic panic fatal"`
+		Mode     string   `yaml:"mode" validate:"required,oneof=devel prod"`
+		Encoding string   `yaml:"encoding" validate:"omitempty,oneof=json console"`
+		Outputs  []string `yaml:"outputs" validate:"dive,required"`
+		Rotation struct {
+			MaxSize    Size          `yaml:"max_size" validate:"gte=0"`
+			MaxAge     time.Duration `yaml:"max_age" validate:"gte=0"`
+			MaxBackups int           `yaml:"max_backups" validate:"gte=0"`
+		} `yaml:"rotation"`
+		Sampling struct {
+			Initial    int `yaml:"initial" validate:"gte=0"`
+			Thereafter int `yaml:"thereafter" validate:"gte=0"`
+		} `yaml:"sampling"`
+		Levels map[string]string `yaml:"levels" validate:"dive,oneof=debug info warn error dpanic panic fatal"`
+	} `yaml:"logger"`
+	Network struct {
+		Address        string        `yaml:"address" validate:"omitempty,hostname_port"`
+		MaxConnections int           `yaml:"max_connections" validate:"gte=0"`
+		MaxMessageSize Size          `yaml:"max_message_size" validate:"gte=0"`
+		IdleTimeout    time.Duration `yaml:"idle_timeout" validate:"gte=0"`
+		MaxInFlight    int           `yaml:"max_in_flight" validate:"gte=0"`
+		UnixSocket     struct {
+			Path string `yaml:"path"`
+			Mode string `yaml:"mode"`
+		} `yaml:"unix_socket"`
+		TLS struct {
+			CertFile     string `yaml:"cert_file"`
+			KeyFile      string `yaml:"key_file"`
+			ClientCAFile string `yaml:"client_ca_file"`
+			ClientAuth   string `yaml:"client_auth" validate:"omitempty,oneof=none optional require"`
+		} `yaml:"tls"`
+	} `yaml:"network"`
+	Metrics struct {
+		Address string `yaml:"address" validate:"omitempty,hostname_port"`
+	} `yaml:"metrics"`
+	Database struct {
+		QueryTimeout time.Duration `yaml:"query_timeout" validate:"gte=0"`
+		// ReadOnly запрещает изменение ключей
+		ReadOnly bool `yaml:"read_only"`
+		// MaxMemory - предел размера ключей и значений, 0 - без ограничения
+		MaxMemory Size `yaml:"max_memory" validate:"gte=0"`
+	} `yaml:"database"`
+	PubSub struct {
+		BufferSize int `yaml:"buffer_size" validate:"gte=0"`
+		// KeyspaceEvents - классы уведомлений об изменении ключей, см. keyspace.ParseClasses
+		KeyspaceEvents string `yaml:"keyspace_events"`
+	} `yaml:"pubsub"`
+	SlowLog struct {
+		Threshold time.Duration `yaml:"threshold" validate:"gte=0"`
+		MaxLen    int           `yaml:"max_len" validate:"gte=0"`
+	} `yaml:"slowlog"`
+	Audit struct {
+		Path    string `yaml:"path"`
+		MaxSize Size   `yaml:"max_size" validate:"gte=0"`
+	} `yaml:"audit"`
+	Shutdown struct {
+		GracePeriod time.Duration `yaml:"grace_period" validate:"gte=0"`
+	} `yaml:"shutdown"`
+	Auth struct {
+		ACLFile     string            `yaml:"acl_file"`
+		Users       map[string]string `yaml:"users,omitempty" validate:"dive,keys,required,excludesall= ,endkeys,required"`
+		MaxFailures int               `yaml:"max_failures" validate:"gte=0"`
+		Lockout     time.Duration     `yaml:"lockout" validate:"gte=0"`
+	} `yaml:"auth"`
+	Trace struct {
+		Path       string `yaml:"path"`
+		MaxSize    Size   `yaml:"max_size" validate:"gte=0"`
+		MaxBackups int    `yaml:"max_backups" validate:"gte=0"`
+	} `yaml:"trace"`
+	CDC struct {
+		Path        string `yaml:"path"`
+		SegmentSize Size   `yaml:"segment_size" validate:"gte=0"`
+		MaxSegments int    `yaml:"max_segments" validate:"gte=0"`
+	} `yaml:"cdc"`
 }
 
+// Default возвращает конфигурацию по умолчанию: сетевой адрес не задан, поэтому команды
+// читаются из stdin; журналы аудита, трассировки и изменений, а также метрики выключены
+func Default() *Config {
+	var config Config
+
+	config.Logger.Level = "info"
+	config.Logger.Mode = "prod"
+	config.Logger.Outputs = []string{"stdout"}
+	config.Logger.Rotation.MaxSize = 100 << 20
+	config.Logger.Rotation.MaxBackups = 7
+
+	config.Network.MaxConnections = 100
+	config.Network.MaxMessageSize = 4 << 10
+	config.Network.IdleTimeout = 5 * time.Minute
+	config.Network.MaxInFlight = 64
+	config.Network.UnixSocket.Mode = "0660"
+
+	config.Database.QueryTimeout = time.Second
+
+	config.PubSub.BufferSize = 1024
+
+	config.SlowLog.Threshold = 10 * time.Millisecond
+	config.SlowLog.MaxLen = 128
+
+	config.Shutdown.GracePeriod = 10 * time.Second
+
+	config.Auth.MaxFailures = 5
+	config.Auth.Lockout = 30 * time.Second
+
+	config.Audit.MaxSize = 64 << 20
+	config.Trace.MaxSize = 64 << 20
+	config.CDC.SegmentSize = 16 << 20
+	config.CDC.MaxSegments = 8
+
+	return &config
+}
+
+// LoadConfig собирает конфигурацию: значения по умолчанию, затем файл (если путь задан
+// аргументом или в YAML_CONFIG_FILE_PATH), затем переменные окружения с префиксом MDB_
 func LoadConfig(yamlConfigFilePath string) (*Config, error) {
-	if yamlConfigFilePath == "" {
-		yamlConfigFilePath = os.Getenv("YAML_CONFIG_FILE_PATH")
-		if yamlConfigFilePath == "" {
-			return nil, fmt.Errorf("yaml config file path is not set")
+	yamlConfigFilePath = FilePath(yamlConfigFilePath)
+
+	config := Default()
+	if yamlConfigFilePath != "" {
+		if err := decodeFile(yamlConfigFilePath, config); err != nil {
+			return nil, err
 		}
 	}
 
-	f, err := os.Open(yamlConfigFilePath)
+	if err := applyEnv(config); err != nil {
+		return nil, fmt.Errorf("unable to apply environment: %w", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// FilePath возвращает путь к файлу конфигурации: переданный или из YAML_CONFIG_FILE_PATH
+func FilePath(yamlConfigFilePath string) string {
+	if yamlConfigFilePath == "" {
+		return os.Getenv("YAML_CONFIG_FILE_PATH")
+	}
+
+	return yamlConfigFilePath
+}
+
+func decodeFile(path string, config *Config) error {
+	f, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("unable to open config file: %w", err)
+		return fmt.Errorf("unable to open config file: %w", err)
 	}
 
 	defer func(f *os.File) {
@@ -34,16 +176,162 @@ func LoadConfig(yamlConfigFilePath string) (*Config, error) {
 		}
 	}(f)
 
-	var config Config
 	decoder := yaml.NewDecoder(f)
-	if err = decoder.Decode(&config); err != nil {
-		return nil, fmt.Errorf("unable to decode config file: %w", err)
+	decoder.KnownFields(true)
+	if err = decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("unable to decode config file: %w", err)
 	}
 
+	return nil
+}
+
+// Validate проверяет значения полей и согласованность полей между собой
+func (c *Config) Validate() error {
 	validate := validator.New()
-	if err = validate.Struct(&config); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+	validate.RegisterTagNameFunc(fieldName)
+	validate.RegisterStructValidation(validateConfig, Config{})
+
+	err := validate.Struct(c)
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		messages := make([]string, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			messages = append(messages, validationMessage(fieldErr))
+		}
+
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(messages, "; "))
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	return nil
+}
+
+// keyspaceEventClasses - символы классов уведомлений об изменении ключей
+const keyspaceEventClasses = "KEg$hlszA"
+
+// minMessageSize - наименьший размер сообщения, в который помещается любая команда без аргументов
+const minMessageSize = 16
+
+func validateConfig(sl validator.StructLevel) {
+	c := sl.Current().Interface().(Config)
+
+	if c.Network.MaxMessageSize > 0 && c.Network.MaxMessageSize < minMessageSize {
+		sl.ReportError(c.Network.MaxMessageSize, "network.max_message_size", "MaxMessageSize", "min", fmt.Sprint(minMessageSize))
+	}
+
+	if c.Metrics.Address != "" && c.Metrics.Address == c.Network.Address {
+		sl.ReportError(c.Metrics.Address, "metrics.address", "Address", "nefield", "network.address")
+	}
+
+	// запрос, прерванный по таймауту раньше порога, никогда не попадет в журнал медленных запросов
+	if c.SlowLog.Threshold > 0 && c.Database.QueryTimeout > 0 && c.SlowLog.Threshold >= c.Database.QueryTimeout {
+		sl.ReportError(c.SlowLog.Threshold, "slowlog.threshold", "Threshold", "ltfield", "database.query_timeout")
+	}
+
+	if c.Logger.Sampling.Thereafter > 0 && c.Logger.Sampling.Initial == 0 {
+		sl.ReportError(c.Logger.Sampling.Thereafter, "logger.sampling.thereafter", "Thereafter", "required_with", "logger.sampling.initial")
+	}
+
+	if strings.Trim(c.PubSub.KeyspaceEvents, keyspaceEventClasses) != "" {
+		sl.ReportError(c.PubSub.KeyspaceEvents, "pubsub.keyspace_events", "KeyspaceEvents", "keyspace_events", "")
+	}
+
+	if _, err := ParseFileMode(c.Network.UnixSocket.Mode); err != nil {
+		sl.ReportError(c.Network.UnixSocket.Mode, "network.unix_socket.mode", "Mode", "file_mode", "")
+	}
+
+	tls := c.Network.TLS
+	if tls.CertFile != "" && tls.KeyFile == "" {
+		sl.ReportError(tls.CertFile, "network.tls.cert_file", "CertFile", "required_with", "network.tls.key_file")
+	}
+
+	if tls.KeyFile != "" && tls.CertFile == "" {
+		sl.ReportError(tls.KeyFile, "network.tls.key_file", "KeyFile", "required_with", "network.tls.cert_file")
+	}
+
+	if tls.ClientCAFile != "" && tls.CertFile == "" {
+		sl.ReportError(tls.ClientCAFile, "network.tls.client_ca_file", "ClientCAFile", "required_with", "network.tls.cert_file")
+	}
+
+	// проверять клиентские сертификаты без CA нечем
+	if (tls.ClientAuth == "optional" || tls.ClientAuth == "require") && tls.ClientCAFile == "" {
+		sl.ReportError(tls.ClientAuth, "network.tls.client_auth", "ClientAuth", "required_with", "network.tls.client_ca_file")
+	}
+
+	if c.Trace.Path != "" && c.Trace.Path == c.Audit.Path {
+		sl.ReportError(c.Trace.Path, "trace.path", "Path", "nefield", "audit.path")
+	}
+
+	if c.CDC.Path != "" && c.CDC.Path == c.Audit.Path {
+		sl.ReportError(c.CDC.Path, "cdc.path", "Path", "nefield", "audit.path")
+	}
+
+	if c.CDC.Path != "" && c.CDC.Path == c.Trace.Path {
+		sl.ReportError(c.CDC.Path, "cdc.path", "Path", "nefield", "trace.path")
+	}
+}
+
+// validationMessage описывает ошибку проверки в терминах yaml-имен полей
+func validationMessage(fieldErr validator.FieldError) string {
+	field := strings.TrimPrefix(fieldErr.Namespace(), "Config.")
+
+	switch fieldErr.Tag() {
+	case "required":
+		return field + " is required"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s], got %q", field, fieldErr.Param(), fmt.Sprint(fieldErr.Value()))
+	case "hostname_port":
+		return fmt.Sprintf("%s must be host:port, got %q", field, fmt.Sprint(fieldErr.Value()))
+	case "gte", "min":
+		return fmt.Sprintf("%s must be at least %s", field, fieldErr.Param())
+	case "ltfield":
+		return fmt.Sprintf("%s must be less than %s", field, fieldErr.Param())
+	case "nefield":
+		return fmt.Sprintf("%s must differ from %s", field, fieldErr.Param())
+	case "excludesall":
+		return fmt.Sprintf("%s must not contain spaces, got %q", field, fmt.Sprint(fieldErr.Value()))
+	case "file_mode":
+		return fmt.Sprintf("%s must be an octal file mode such as 0660, got %q", field, fmt.Sprint(fieldErr.Value()))
+	case "required_with":
+		return fmt.Sprintf("%s requires %s", field, fieldErr.Param())
+	case "keyspace_events":
+		return fmt.Sprintf("%s must consist of [%s] classes, got %q", field, keyspaceEventClasses, fmt.Sprint(fieldErr.Value()))
+	default:
+		return fmt.Sprintf("%s failed %s validation", field, fieldErr.Tag())
+	}
+}
+
+// ParseFileMode разбирает права доступа к файлу в восьмеричной записи. Пустая строка - 0
+func ParseFileMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return 0, nil
+	}
+
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || perm > 0o777 {
+		return 0, fmt.Errorf("invalid file mode %q", mode)
+	}
+
+	return os.FileMode(perm), nil
+}
+
+// Marshal возвращает действующую конфигурацию в формате yaml
+func (c *Config) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return nil, fmt.Errorf("failed encoder.Encode: %w", err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed encoder.Close: %w", err)
 	}
 
-	return &config, nil
+	return buf.Bytes(), nil
 }
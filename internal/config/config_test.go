@@ -4,7 +4,10 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func createTempYAML(t *testing.T, content string) (string, func()) {
@@ -86,14 +89,14 @@ func TestLoadConfig_NoEnvVar(t *testing.T) {
 		return
 	}
 
-	_, err := LoadConfig("")
-	if err == nil {
-		t.Fatalf("Expected error when YAML_CONFIG_FILE_PATH is not set, got nil")
+	config, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("Expected defaults when YAML_CONFIG_FILE_PATH is not set, got %v", err)
 	}
 
-	expectedErrMsg := "yaml config file path is not set"
-	if err.Error() != expectedErrMsg {
-		t.Errorf("Expected error message '%s', got '%s'", expectedErrMsg, err.Error())
+	// без сетевого адреса команды читаются из stdin, как и до появления значений по умолчанию
+	if config.Logger.Level != "info" || config.Network.Address != "" {
+		t.Errorf("Expected default config, got %+v", config)
 	}
 }
 
@@ -151,33 +154,30 @@ logger:
 	}
 }
 
-func TestLoadConfig_InvalidValidation_MissingFields(t *testing.T) {
+func TestLoadConfig_MissingFieldsUseDefaults(t *testing.T) {
 	yamlContent := `
 logger:
-  level: "info"
+  level: "debug"
 `
 
 	filePath, cleanup := createTempYAML(t, yamlContent)
 	defer cleanup()
 
-	if err := os.Setenv("YAML_CONFIG_FILE_PATH", filePath); err != nil {
-		return
+	config, err := LoadConfig(filePath)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
 	}
 
-	defer func() {
-		if err := os.Unsetenv("YAML_CONFIG_FILE_PATH"); err != nil {
-			log.Printf("failed os.Unsetenv, err: %v", err)
-		}
-	}()
+	if config.Logger.Level != "debug" {
+		t.Errorf("Expected logger level 'debug', got '%s'", config.Logger.Level)
+	}
 
-	_, err := LoadConfig("")
-	if err == nil {
-		t.Fatalf("Expected validation error due to missing fields, got nil")
+	if config.Logger.Mode != "prod" {
+		t.Errorf("Expected default logger mode 'prod', got '%s'", config.Logger.Mode)
 	}
 
-	expectedErrPrefix := "config validation failed"
-	if len(err.Error()) < len(expectedErrPrefix) || err.Error()[:len(expectedErrPrefix)] != expectedErrPrefix {
-		t.Errorf("Expected error message to start with '%s', got '%s'", expectedErrPrefix, err.Error())
+	if config.SlowLog.MaxLen != 128 {
+		t.Errorf("Expected default slowlog max_len 128, got %d", config.SlowLog.MaxLen)
 	}
 }
 
@@ -273,3 +273,356 @@ logger:
 		t.Errorf("Expected error message to start with '%s', got '%s'", expectedErrPrefix, err.Error())
 	}
 }
+
+func TestLoadConfig_NetworkAndDatabase(t *testing.T) {
+	yamlContent := `
+logger:
+  level: "info"
+  mode: "prod"
+network:
+  address: "127.0.0.1:3223"
+  max_connections: 100
+  max_message_size: 4096
+  idle_timeout: 5m
+database:
+  query_timeout: 250ms
+`
+
+	filePath, cleanup := createTempYAML(t, yamlContent)
+	defer cleanup()
+
+	config, err := LoadConfig(filePath)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Network.Address != "127.0.0.1:3223" {
+		t.Errorf("Expected network address '127.0.0.1:3223', got '%s'", config.Network.Address)
+	}
+
+	if config.Network.MaxConnections != 100 || config.Network.MaxMessageSize != 4096 {
+		t.Errorf("Unexpected network limits: %+v", config.Network)
+	}
+
+	if config.Network.IdleTimeout != 5*time.Minute {
+		t.Errorf("Expected idle timeout 5m, got %s", config.Network.IdleTimeout)
+	}
+
+	if config.Database.QueryTimeout != 250*time.Millisecond {
+		t.Errorf("Expected query timeout 250ms, got %s", config.Database.QueryTimeout)
+	}
+}
+
+func TestLoadConfig_InvalidValidation_NetworkAddress(t *testing.T) {
+	yamlContent := `
+logger:
+  level: "info"
+  mode: "prod"
+network:
+  address: "localhost"
+`
+
+	filePath, cleanup := createTempYAML(t, yamlContent)
+	defer cleanup()
+
+	_, err := LoadConfig(filePath)
+	if err == nil {
+		t.Fatalf("Expected validation error due to address without port, got nil")
+	}
+
+	expectedErrPrefix := "config validation failed"
+	if len(err.Error()) < len(expectedErrPrefix) || err.Error()[:len(expectedErrPrefix)] != expectedErrPrefix {
+		t.Errorf("Expected error message to start with '%s', got '%s'", expectedErrPrefix, err.Error())
+	}
+}
+
+func TestLoadConfig_LoggerOutputs(t *testing.T) {
+	yamlContent := `
+logger:
+  level: "info"
+  mode: "prod"
+  encoding: "json"
+  outputs: ["stdout", "./data/mdb.log"]
+  rotation:
+    max_size: 1048576
+    max_age: 24h
+    max_backups: 3
+  sampling:
+    initial: 100
+    thereafter: 10
+  levels:
+    storage: "warn"
+`
+
+	filePath, cleanup := createTempYAML(t, yamlContent)
+	defer cleanup()
+
+	config, err := LoadConfig(filePath)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Logger.Encoding != "json" || len(config.Logger.Outputs) != 2 {
+		t.Errorf("Unexpected logger outputs: %+v", config.Logger)
+	}
+
+	if config.Logger.Rotation.MaxAge != 24*time.Hour || config.Logger.Rotation.MaxBackups != 3 {
+		t.Errorf("Unexpected logger rotation: %+v", config.Logger.Rotation)
+	}
+
+	if config.Logger.Sampling.Initial != 100 || config.Logger.Sampling.Thereafter != 10 {
+		t.Errorf("Unexpected logger sampling: %+v", config.Logger.Sampling)
+	}
+
+	if config.Logger.Levels["storage"] != "warn" {
+		t.Errorf("Expected storage level 'warn', got '%s'", config.Logger.Levels["storage"])
+	}
+}
+
+func TestLoadConfig_InvalidValidation_SubsystemLevel(t *testing.T) {
+	yamlContent := `
+logger:
+  level: "info"
+  mode: "prod"
+  levels:
+    storage: "verbose"
+`
+
+	filePath, cleanup := createTempYAML(t, yamlContent)
+	defer cleanup()
+
+	_, err := LoadConfig(filePath)
+	if err == nil {
+		t.Fatalf("Expected validation error due to invalid subsystem level, got nil")
+	}
+
+	expectedErrPrefix := "config validation failed"
+	if len(err.Error()) < len(expectedErrPrefix) || err.Error()[:len(expectedErrPrefix)] != expectedErrPrefix {
+		t.Errorf("Expected error message to start with '%s', got '%s'", expectedErrPrefix, err.Error())
+	}
+}
+
+func TestLoadConfig_EnvOverrides(t *testing.T) {
+	yamlContent := `
+logger:
+  level: "info"
+  mode: "prod"
+network:
+  address: "127.0.0.1:3223"
+  max_message_size: 4KB
+`
+
+	filePath, cleanup := createTempYAML(t, yamlContent)
+	defer cleanup()
+
+	t.Setenv("MDB_NETWORK_ADDRESS", "0.0.0.0:4000")
+	t.Setenv("MDB_NETWORK_MAX_MESSAGE_SIZE", "64MB")
+	t.Setenv("MDB_DATABASE_QUERY_TIMEOUT", "10s")
+	t.Setenv("MDB_LOGGER_OUTPUTS", "stdout, ./data/mdb.log")
+	t.Setenv("MDB_LOGGER_LEVELS", "storage=warn,compute=debug")
+	t.Setenv("MDB_LOGGER_ROTATION_MAX_BACKUPS", "3")
+
+	config, err := LoadConfig(filePath)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Network.Address != "0.0.0.0:4000" {
+		t.Errorf("Expected network address from env, got '%s'", config.Network.Address)
+	}
+
+	if config.Network.MaxMessageSize != 64<<20 {
+		t.Errorf("Expected max message size 64MB, got %d", config.Network.MaxMessageSize)
+	}
+
+	if config.Database.QueryTimeout != 10*time.Second {
+		t.Errorf("Expected query timeout 10s, got %s", config.Database.QueryTimeout)
+	}
+
+	if len(config.Logger.Outputs) != 2 || config.Logger.Outputs[1] != "./data/mdb.log" {
+		t.Errorf("Unexpected logger outputs: %v", config.Logger.Outputs)
+	}
+
+	if config.Logger.Levels["storage"] != "warn" || config.Logger.Levels["compute"] != "debug" {
+		t.Errorf("Unexpected logger levels: %v", config.Logger.Levels)
+	}
+
+	if config.Logger.Rotation.MaxBackups != 3 {
+		t.Errorf("Expected max backups 3, got %d", config.Logger.Rotation.MaxBackups)
+	}
+}
+
+func TestLoadConfig_InvalidEnv(t *testing.T) {
+	t.Setenv("YAML_CONFIG_FILE_PATH", "")
+	t.Setenv("MDB_NETWORK_IDLE_TIMEOUT", "soon")
+
+	_, err := LoadConfig("")
+	if err == nil || !strings.Contains(err.Error(), "MDB_NETWORK_IDLE_TIMEOUT") {
+		t.Fatalf("Expected error naming MDB_NETWORK_IDLE_TIMEOUT, got %v", err)
+	}
+}
+
+func TestValidate_CrossField(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(c *Config)
+		expected string
+	}{
+		{
+			name:     "Порог медленных запросов не меньше таймаута",
+			modify:   func(c *Config) { c.SlowLog.Threshold = 2 * time.Second },
+			expected: "slowlog.threshold must be less than database.query_timeout",
+		},
+		{
+			name:     "Метрики на адресе сервера",
+			modify:   func(c *Config) { c.Network.Address, c.Metrics.Address = "127.0.0.1:3223", "127.0.0.1:3223" },
+			expected: "metrics.address must differ from network.address",
+		},
+		{
+			name:     "Слишком маленький размер сообщения",
+			modify:   func(c *Config) { c.Network.MaxMessageSize = 8 },
+			expected: "network.max_message_size must be at least 16",
+		},
+		{
+			name: "Сэмплирование без начального количества",
+			modify: func(c *Config) {
+				c.Logger.Sampling.Thereafter = 10
+			},
+			expected: "logger.sampling.thereafter requires logger.sampling.initial",
+		},
+		{
+			name: "Общий файл аудита и трассировки",
+			modify: func(c *Config) {
+				c.Audit.Path = "./data/log"
+				c.Trace.Path = "./data/log"
+			},
+			expected: "trace.path must differ from audit.path",
+		},
+		{
+			name: "Журнал изменений в файле трассировки",
+			modify: func(c *Config) {
+				c.Trace.Path = "./data/trace.jsonl"
+				c.CDC.Path = "./data/trace.jsonl"
+			},
+			expected: "cdc.path must differ from trace.path",
+		},
+		{
+			name:     "Неверные права Unix-сокета",
+			modify:   func(c *Config) { c.Network.UnixSocket.Mode = "rw-rw----" },
+			expected: "network.unix_socket.mode must be an octal file mode",
+		},
+		{
+			name:     "Неизвестный класс уведомлений",
+			modify:   func(c *Config) { c.PubSub.KeyspaceEvents = "KEq" },
+			expected: "pubsub.keyspace_events must consist of [KEg$hlszA] classes",
+		},
+		{
+			name:     "Сертификат без ключа",
+			modify:   func(c *Config) { c.Network.TLS.CertFile = "server.crt" },
+			expected: "network.tls.cert_file requires network.tls.key_file",
+		},
+		{
+			name: "Проверка клиентов без CA",
+			modify: func(c *Config) {
+				c.Network.TLS.CertFile = "server.crt"
+				c.Network.TLS.KeyFile = "server.key"
+				c.Network.TLS.ClientAuth = "require"
+			},
+			expected: "network.tls.client_auth requires network.tls.client_ca_file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Default()
+			if err := config.Validate(); err != nil {
+				t.Fatalf("Expected default config to be valid, got %v", err)
+			}
+
+			tt.modify(config)
+
+			err := config.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  Size
+		expectErr bool
+	}{
+		{input: "512", expected: 512},
+		{input: "4KB", expected: 4 << 10},
+		{input: "64MB", expected: 64 << 20},
+		{input: "1gib", expected: 1 << 30},
+		{input: "10 M", expected: 10 << 20},
+		{input: "", expectErr: true},
+		{input: "-1KB", expectErr: true},
+		{input: "10TB", expectErr: true},
+		{input: "99999999999GB", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			size, err := ParseSize(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %d", tt.input, size)
+				}
+				return
+			}
+
+			if err != nil || size != tt.expected {
+				t.Errorf("Expected %d, got %d (err: %v)", tt.expected, size, err)
+			}
+		})
+	}
+}
+
+func TestConfig_MarshalRoundTrip(t *testing.T) {
+	config := Default()
+	config.Logger.Levels = map[string]string{"storage": "warn"}
+
+	data, err := config.Marshal()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !strings.Contains(string(data), "max_message_size: 4KB") {
+		t.Errorf("Expected human-readable sizes, got:\n%s", data)
+	}
+
+	filePath, cleanup := createTempYAML(t, string(data))
+	defer cleanup()
+
+	loaded, err := LoadConfig(filePath)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !reflect.DeepEqual(config, loaded) {
+		t.Errorf("Expected %+v, got %+v", config, loaded)
+	}
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	config := Default()
+	config.Auth.Users = map[string]string{"alice": "pbkdf2-sha256$1$c2FsdA$a2V5"}
+
+	data, err := config.Redacted().Marshal()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if strings.Contains(string(data), "pbkdf2") || !strings.Contains(string(data), "alice: '***'") {
+		t.Errorf("Expected redacted password hashes, got:\n%s", data)
+	}
+
+	if config.Auth.Users["alice"] != "pbkdf2-sha256$1$c2FsdA$a2V5" {
+		t.Errorf("Expected the original config to keep the hash, got %s", config.Auth.Users["alice"])
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix - префикс переменных окружения, переопределяющих конфигурацию.
+// Имя переменной составляется из yaml-имен полей: MDB_NETWORK_ADDRESS, MDB_SLOWLOG_THRESHOLD
+const EnvPrefix = "MDB_"
+
+// applyEnv переопределяет поля конфигурации значениями переменных окружения
+func applyEnv(config *Config) error {
+	return applyEnvStruct(reflect.ValueOf(config).Elem(), strings.TrimSuffix(EnvPrefix, "_"))
+}
+
+func applyEnvStruct(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := fieldName(t.Field(i))
+		if name == "-" {
+			continue
+		}
+
+		env := prefix + "_" + strings.ToUpper(name)
+		field := v.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnvStruct(field, env); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			continue
+		}
+
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", env, err)
+		}
+	}
+
+	return nil
+}
+
+// fieldName возвращает имя поля в yaml
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(f.Name)
+	}
+
+	return name
+}
+
+// setField присваивает полю значение из переменной окружения. Списки задаются через запятую,
+// словари - парами key=value через запятую, остальные значения разбираются как в yaml
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+		return nil
+	case reflect.Slice:
+		items := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		field.Set(reflect.ValueOf(items))
+		return nil
+	case reflect.Map:
+		items := make(map[string]string)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+
+			k, v, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("expected key=value, got %q", item)
+			}
+
+			items[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+
+		field.Set(reflect.ValueOf(items))
+		return nil
+	default:
+		if strings.TrimSpace(value) == "" {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+
+		return yaml.Unmarshal([]byte(value), field.Addr().Interface())
+	}
+}
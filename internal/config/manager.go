@@ -0,0 +1,271 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrUnknownParam = errors.New("unknown config parameter")
+	ErrStaticParam  = errors.New("config parameter cannot be changed at runtime, restart required")
+	ErrNoConfigFile = errors.New("server is running without a config file")
+)
+
+// Manager хранит действующую конфигурацию и применяет изменения без перезапуска.
+// Изменения проверяются целиком и применяются только если затрагивают динамические параметры
+type Manager struct {
+	path string
+
+	mu       sync.Mutex
+	current  *Config
+	handlers []func(*Config)
+	// changed - параметры, измененные через Set после загрузки файла
+	changed map[string]bool
+}
+
+// NewManager создает менеджер для конфигурации, загруженной из path (пустой путь - без файла)
+func NewManager(path string, cfg *Config) *Manager {
+	return &Manager{path: path, current: cfg}
+}
+
+// Path возвращает путь к файлу конфигурации
+func (m *Manager) Path() string {
+	return m.path
+}
+
+// Current возвращает действующую конфигурацию. Возвращаемое значение не изменяется
+func (m *Manager) Current() *Config {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.current
+}
+
+// OnChange регистрирует обработчик, применяющий новую конфигурацию
+func (m *Manager) OnChange(fn func(*Config)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.handlers = append(m.handlers, fn)
+}
+
+// Get возвращает параметры, имена которых соответствуют glob-шаблону
+func (m *Manager) Get(pattern string) ([]Param, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	var params []Param
+	for _, param := range m.Current().PublicParams() {
+		if matched, _ := path.Match(pattern, param.Name); matched {
+			params = append(params, param)
+		}
+	}
+
+	return params, nil
+}
+
+// Set меняет один динамический параметр
+func (m *Manager) Set(name, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	next := m.current.Clone()
+	if err := next.SetParam(name, value); err != nil {
+		return err
+	}
+
+	if err := m.apply(next); err != nil {
+		return err
+	}
+
+	if m.changed == nil {
+		m.changed = make(map[string]bool)
+	}
+	m.changed[name] = true
+
+	return nil
+}
+
+// Reload перечитывает файл конфигурации и переменные окружения
+func (m *Manager) Reload() error {
+	next, err := LoadConfig(m.path)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err = m.apply(next); err != nil {
+		return err
+	}
+
+	// изменения, сделанные через Set, заменены значениями из файла
+	m.changed = nil
+
+	return nil
+}
+
+// apply проверяет новую конфигурацию и передает ее обработчикам. Вызывается под m.mu
+func (m *Manager) apply(next *Config) error {
+	if err := next.Validate(); err != nil {
+		return err
+	}
+
+	current := make(map[string]string)
+	for _, param := range m.current.Params() {
+		current[param.Name] = param.Value
+	}
+
+	var static []string
+	for _, param := range next.Params() {
+		if current[param.Name] != param.Value && !IsDynamic(param.Name) {
+			static = append(static, param.Name)
+		}
+	}
+
+	if len(static) > 0 {
+		return fmt.Errorf("%w: %s", ErrStaticParam, strings.Join(static, ", "))
+	}
+
+	m.current = next
+	for _, fn := range m.handlers {
+		fn(next)
+	}
+
+	return nil
+}
+
+// Rewrite сохраняет в файл, из которого загружена конфигурация, параметры, измененные
+// через Set. Остальное содержимое файла, включая комментарии, сохраняется, а значения
+// по умолчанию и из переменных окружения в файл не попадают
+func (m *Manager) Rewrite() error {
+	if m.path == "" {
+		return ErrNoConfigFile
+	}
+
+	m.mu.Lock()
+	current := m.current
+	changed := make([]string, 0, len(m.changed))
+	for name := range m.changed {
+		changed = append(changed, name)
+	}
+	m.mu.Unlock()
+	sort.Strings(changed)
+
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		return fmt.Errorf("failed os.ReadFile: %w", err)
+	}
+
+	data, err = rewriteParams(data, current, changed)
+	if err != nil {
+		return err
+	}
+
+	// запись во временный файл и переименование не оставляют файл наполовину записанным
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), filepath.Base(m.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed os.CreateTemp: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed tmp.Write: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed tmp.Close: %w", err)
+	}
+
+	if info, err := os.Stat(m.path); err == nil {
+		_ = os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+
+	if err = os.Rename(tmp.Name(), m.path); err != nil {
+		return fmt.Errorf("failed os.Rename: %w", err)
+	}
+
+	return nil
+}
+
+// rewriteParams заменяет в yaml-документе значения параметров names значениями из cfg,
+// добавляя недостающие разделы
+func rewriteParams(data []byte, cfg *Config, names []string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed yaml.Unmarshal: %w", err)
+	}
+
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	fields := make(map[string]reflect.Value)
+	walkParams(reflect.ValueOf(cfg).Elem(), "", func(name string, field reflect.Value) {
+		fields[name] = field
+	})
+
+	for _, name := range names {
+		var value yaml.Node
+		if err := value.Encode(fields[name].Interface()); err != nil {
+			return nil, fmt.Errorf("failed value.Encode: %w", err)
+		}
+
+		setNode(doc.Content[0], strings.Split(name, "."), &value)
+	}
+
+	var b strings.Builder
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed encoder.Encode: %w", err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed encoder.Close: %w", err)
+	}
+
+	return []byte(b.String()), nil
+}
+
+// setNode присваивает значение по пути из yaml-имен, сохраняя комментарий к прежнему значению
+func setNode(mapping *yaml.Node, path []string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != path[0] {
+			continue
+		}
+
+		if len(path) == 1 {
+			value.LineComment = mapping.Content[i+1].LineComment
+			mapping.Content[i+1] = value
+			return
+		}
+
+		if mapping.Content[i+1].Kind != yaml.MappingNode {
+			mapping.Content[i+1] = &yaml.Node{Kind: yaml.MappingNode}
+		}
+
+		setNode(mapping.Content[i+1], path[1:], value)
+		return
+	}
+
+	child := value
+	if len(path) > 1 {
+		child = &yaml.Node{Kind: yaml.MappingNode}
+		setNode(child, path[1:], value)
+	}
+
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: path[0]}, child)
+}
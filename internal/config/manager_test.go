@@ -0,0 +1,201 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestManager(t *testing.T, content string) (*Manager, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	return NewManager(path, cfg), path
+}
+
+func TestManager_Set(t *testing.T) {
+	m, _ := newTestManager(t, "logger:\n  level: info\n  mode: prod\n")
+
+	var applied []*Config
+	m.OnChange(func(cfg *Config) { applied = append(applied, cfg) })
+
+	before := m.Current()
+
+	tests := []struct {
+		name     string
+		param    string
+		value    string
+		expected error
+	}{
+		{name: "Динамический параметр", param: "slowlog.threshold", value: "50ms"},
+		{name: "Уровень логирования", param: "logger.level", value: "debug"},
+		{name: "Размер с единицей измерения", param: "network.max_message_size", value: "64KB"},
+		{name: "Статический параметр", param: "network.address", value: "127.0.0.1:4000", expected: ErrStaticParam},
+		{name: "Неизвестный параметр", param: "network.port", value: "1", expected: ErrUnknownParam},
+		{name: "Недопустимое значение", param: "logger.level", value: "loud", expected: ErrInvalidConfig},
+		{name: "Нарушение связи полей", param: "slowlog.threshold", value: "5s", expected: ErrInvalidConfig},
+		{name: "Значение не разбирается", param: "network.idle_timeout", value: "soon", expected: ErrInvalidConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.Set(tt.param, tt.value)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("Expected error %v, got %v", tt.expected, err)
+			}
+
+			if tt.expected != nil {
+				return
+			}
+
+			params, err := m.Get(tt.param)
+			if err != nil || len(params) != 1 || params[0].Value != tt.value {
+				t.Errorf("Expected %s=%s, got %v (err: %v)", tt.param, tt.value, params, err)
+			}
+		})
+	}
+
+	if len(applied) != 3 {
+		t.Errorf("Expected 3 applied changes, got %d", len(applied))
+	}
+
+	if before.SlowLog.Threshold != 10*time.Millisecond {
+		t.Errorf("Previous config must not change, got threshold %s", before.SlowLog.Threshold)
+	}
+}
+
+func TestManager_Get(t *testing.T) {
+	m, _ := newTestManager(t, "logger:\n  level: info\n  mode: prod\n")
+
+	params, err := m.Get("network.*")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var names []string
+	for _, param := range params {
+		names = append(names, param.Name)
+	}
+
+	expected := "network.address,network.idle_timeout,network.max_connections,network.max_in_flight,network.max_message_size," +
+		"network.tls.cert_file,network.tls.client_auth,network.tls.client_ca_file,network.tls.key_file," +
+		"network.unix_socket.mode,network.unix_socket.path"
+	if strings.Join(names, ",") != expected {
+		t.Errorf("Expected %s, got %v", expected, names)
+	}
+
+	if _, err = m.Get("[network"); err == nil {
+		t.Errorf("Expected error for malformed pattern")
+	}
+}
+
+func TestManager_Reload(t *testing.T) {
+	m, path := newTestManager(t, "logger:\n  level: info\n  mode: prod\n")
+
+	applied := 0
+	m.OnChange(func(*Config) { applied++ })
+
+	if err := os.WriteFile(path, []byte("logger:\n  level: warn\n  mode: prod\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if err := m.Reload(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if m.Current().Logger.Level != "warn" || applied != 1 {
+		t.Errorf("Expected reloaded level 'warn', got '%s' (applied %d)", m.Current().Logger.Level, applied)
+	}
+
+	// изменение статического параметра отклоняет всю перезагрузку
+	content := "logger:\n  level: error\n  mode: devel\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	err := m.Reload()
+	if !errors.Is(err, ErrStaticParam) || !strings.Contains(err.Error(), "logger.mode") {
+		t.Fatalf("Expected static param error naming logger.mode, got %v", err)
+	}
+
+	if m.Current().Logger.Level != "warn" || applied != 1 {
+		t.Errorf("Expected config to stay unchanged, got level '%s' (applied %d)", m.Current().Logger.Level, applied)
+	}
+}
+
+func TestManager_Rewrite(t *testing.T) {
+	t.Setenv("MDB_NETWORK_MAX_CONNECTIONS", "7")
+	m, path := newTestManager(t, "# сервер\nlogger:\n  level: info # уровень\n  mode: prod\n")
+
+	if err := m.Set("database.query_timeout", "3s"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := m.Rewrite(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if loaded.Database.QueryTimeout != 3*time.Second {
+		t.Errorf("Expected rewritten query timeout 3s, got %s", loaded.Database.QueryTimeout)
+	}
+
+	if err = m.Set("logger.level", "warn"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err = m.Rewrite(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// в файл попадают только значения из него и изменения через Set, без значений
+	// по умолчанию и переменных окружения; комментарии сохраняются
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "# сервер\nlogger:\n  level: warn # уровень\n  mode: prod\ndatabase:\n  query_timeout: 3s\n"
+	if string(data) != expected {
+		t.Errorf("Expected rewritten file:\n%s\ngot:\n%s", expected, data)
+	}
+
+	if err = NewManager("", Default()).Rewrite(); !errors.Is(err, ErrNoConfigFile) {
+		t.Errorf("Expected ErrNoConfigFile, got %v", err)
+	}
+}
+
+func TestManager_GetRedactsSecrets(t *testing.T) {
+	m, _ := newTestManager(t, "auth:\n  users:\n    alice: pbkdf2-sha256$1$c2FsdA$a2V5\n")
+
+	params, err := m.Get("auth.users")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(params) != 1 || params[0].Value != "alice=***" {
+		t.Errorf("Expected alice=***, got %v", params)
+	}
+
+	for _, param := range m.Current().Params() {
+		if param.Name == "auth.users" && param.Value != "alice=pbkdf2-sha256$1$c2FsdA$a2V5" {
+			t.Errorf("Expected Params to keep raw values, got %s", param.Value)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Param - параметр конфигурации в виде пути из yaml-имен и строкового значения
+type Param struct {
+	Name  string
+	Value string
+}
+
+// dynamicParams - параметры, которые применяются без перезапуска
+var dynamicParams = map[string]bool{
+	"logger.level":             true,
+	"network.max_connections":  true,
+	"network.max_message_size": true,
+	"network.idle_timeout":     true,
+	"network.max_in_flight":    true,
+	"database.query_timeout":   true,
+	"database.read_only":       true,
+	"database.max_memory":      true,
+	"pubsub.buffer_size":       true,
+	"pubsub.keyspace_events":   true,
+	"slowlog.threshold":        true,
+	"shutdown.grace_period":    true,
+}
+
+// secretParams - параметры, значения которых не показываются клиентам
+var secretParams = map[string]bool{
+	"auth.users": true,
+}
+
+// IsDynamic сообщает, можно ли изменить параметр без перезапуска
+func IsDynamic(name string) bool {
+	return dynamicParams[name]
+}
+
+// Params возвращает все параметры конфигурации, упорядоченные по имени
+func (c *Config) Params() []Param {
+	var params []Param
+	walkParams(reflect.ValueOf(c).Elem(), "", func(name string, field reflect.Value) {
+		params = append(params, Param{Name: name, Value: formatValue(field)})
+	})
+
+	sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+
+	return params
+}
+
+// PublicParams возвращает параметры как Params, но со скрытыми секретами:
+// у auth.users остаются только имена пользователей
+func (c *Config) PublicParams() []Param {
+	params := c.Params()
+	for i, param := range params {
+		if secretParams[param.Name] && param.Value != "" {
+			params[i].Value = redactValues(param.Value)
+		}
+	}
+
+	return params
+}
+
+// Redacted возвращает копию конфигурации со скрытыми, как в PublicParams, секретами
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	for _, param := range c.PublicParams() {
+		if secretParams[param.Name] {
+			// значение получено из той же конфигурации и разбирается без ошибок
+			_ = redacted.SetParam(param.Name, param.Value)
+		}
+	}
+
+	return &redacted
+}
+
+// redactValues заменяет значения в списке key=value на ***
+func redactValues(value string) string {
+	items := strings.Split(value, ",")
+	for i, item := range items {
+		k, _, _ := strings.Cut(item, "=")
+		items[i] = k + "=***"
+	}
+
+	return strings.Join(items, ",")
+}
+
+// SetParam присваивает значение параметру по имени. Значение разбирается так же,
+// как значение переменной окружения
+func (c *Config) SetParam(name, value string) error {
+	var found bool
+	var err error
+	walkParams(reflect.ValueOf(c).Elem(), "", func(fieldName string, field reflect.Value) {
+		if fieldName == name {
+			found = true
+			err = setField(field, value)
+		}
+	})
+
+	if !found {
+		return fmt.Errorf("%w: %s", ErrUnknownParam, name)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: invalid value for %s: %w", ErrInvalidConfig, name, err)
+	}
+
+	return nil
+}
+
+func walkParams(v reflect.Value, prefix string, fn func(name string, field reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := fieldName(t.Field(i))
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			walkParams(field, name, fn)
+			continue
+		}
+
+		fn(name, field)
+	}
+}
+
+// formatValue форматирует значение в том же виде, в котором оно задается
+func formatValue(field reflect.Value) string {
+	switch value := field.Interface().(type) {
+	case time.Duration:
+		return value.String()
+	case fmt.Stringer:
+		return value.String()
+	case []string:
+		return strings.Join(value, ",")
+	case map[string]string:
+		items := make([]string, 0, len(value))
+		for k, v := range value {
+			items = append(items, k+"="+v)
+		}
+		sort.Strings(items)
+
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Clone возвращает независимую копию конфигурации
+func (c *Config) Clone() *Config {
+	clone := *c
+	clone.Logger.Outputs = append([]string(nil), c.Logger.Outputs...)
+
+	clone.Logger.Levels = cloneMap(c.Logger.Levels)
+	clone.Auth.Users = cloneMap(c.Auth.Users)
+
+	return &clone
+}
+
+func cloneMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	clone := make(map[string]string, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+
+	return clone
+}
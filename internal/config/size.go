@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Size - размер в байтах. В конфигурации задается числом или строкой с единицей
+// измерения: 512, 4KB, 64MB, 1GB. Единицы двоичные: 1KB = 1024 байта
+type Size int64
+
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KIB", 1 << 10},
+	{"MIB", 1 << 20},
+	{"GIB", 1 << 30},
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseSize разбирает размер с необязательной единицей измерения
+func ParseSize(s string) (Size, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	if n > 0 && multiplier > (1<<63-1)/n {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+
+	return Size(n * multiplier), nil
+}
+
+func (s Size) String() string {
+	for _, unit := range []struct {
+		suffix     string
+		multiplier int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}} {
+		if s != 0 && int64(s)%unit.multiplier == 0 {
+			return strconv.FormatInt(int64(s)/unit.multiplier, 10) + unit.suffix
+		}
+	}
+
+	return strconv.FormatInt(int64(s), 10)
+}
+
+func (s *Size) UnmarshalYAML(node *yaml.Node) error {
+	size, err := ParseSize(node.Value)
+	if err != nil {
+		return err
+	}
+
+	*s = size
+
+	return nil
+}
+
+func (s Size) MarshalYAML() (any, error) {
+	return s.String(), nil
+}
@@ -1,8 +1,11 @@
 package compute
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/trace"
 	"go.uber.org/zap"
 )
 
@@ -23,10 +26,21 @@ func New(p Parser, l *zap.Logger) *Compute {
 	}
 }
 
-func (c *Compute) ProcessRequest(request string) (*parser.Command, error) {
-	c.logger.Info("Received request", zap.String("request", request))
+func (c *Compute) ProcessRequest(ctx context.Context, request string) (*parser.Command, error) {
+	trace.Logger(ctx, c.logger).Debug("Received request", zap.String("request", request))
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed ctx.Err: %w", err)
+	}
+
+	end := trace.StartSpan(ctx, "parse")
 	command, err := c.parser.Parse(request)
+	end()
 	if err != nil {
+		var syntaxErr *parser.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, syntaxErr
+		}
+
 		return nil, fmt.Errorf("failed c.parser.Parse: %w", err)
 	}
 
@@ -1,6 +1,7 @@
 package compute
 
 import (
+	"context"
 	"errors"
 	"github.com/patyukin/mdb/internal/database/compute/parser"
 	"testing"
@@ -67,7 +68,7 @@ func TestCompute_ProcessRequest(t *testing.T) {
 
 			compute := New(p, l)
 
-			result, err := compute.ProcessRequest(tt.input)
+			result, err := compute.ProcessRequest(context.Background(), tt.input)
 
 			if tt.expectedError != "" {
 				if err == nil {
@@ -0,0 +1,768 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	GET      = "GET"
+	SET      = "SET"
+	DELETE   = "DEL"
+	INFO     = "INFO"
+	DBSIZE   = "DBSIZE"
+	TIME     = "TIME"
+	PING     = "PING"
+	COMMAND  = "COMMAND"
+	SLOWLOG  = "SLOWLOG"
+	CONFIG   = "CONFIG"
+	SHUTDOWN = "SHUTDOWN"
+	AUTH     = "AUTH"
+	ACL      = "ACL"
+	PIPELINE = "PIPELINE"
+
+	SUBSCRIBE    = "SUBSCRIBE"
+	UNSUBSCRIBE  = "UNSUBSCRIBE"
+	PSUBSCRIBE   = "PSUBSCRIBE"
+	PUNSUBSCRIBE = "PUNSUBSCRIBE"
+	PUBLISH      = "PUBLISH"
+
+	CDC = "CDC"
+
+	HSET    = "HSET"
+	HGET    = "HGET"
+	HMGET   = "HMGET"
+	HDEL    = "HDEL"
+	HEXISTS = "HEXISTS"
+	HLEN    = "HLEN"
+	HKEYS   = "HKEYS"
+	HGETALL = "HGETALL"
+	HINCRBY = "HINCRBY"
+
+	LPUSH  = "LPUSH"
+	RPUSH  = "RPUSH"
+	LPOP   = "LPOP"
+	RPOP   = "RPOP"
+	LRANGE = "LRANGE"
+	LLEN   = "LLEN"
+	LINDEX = "LINDEX"
+	LTRIM  = "LTRIM"
+	BLPOP  = "BLPOP"
+	BRPOP  = "BRPOP"
+
+	SADD      = "SADD"
+	SREM      = "SREM"
+	SISMEMBER = "SISMEMBER"
+	SMEMBERS  = "SMEMBERS"
+	SINTER    = "SINTER"
+	SUNION    = "SUNION"
+	SDIFF     = "SDIFF"
+
+	ZADD    = "ZADD"
+	ZREM    = "ZREM"
+	ZSCORE  = "ZSCORE"
+	ZRANK   = "ZRANK"
+	ZRANGE  = "ZRANGE"
+	ZINCRBY = "ZINCRBY"
+
+	TYPE = "TYPE"
+)
+
+// Подкоманды
+const (
+	DOCS       = "DOCS"
+	LEN        = "LEN"
+	RESET      = "RESET"
+	REWRITE    = "REWRITE"
+	SAVE       = "SAVE"
+	NOSAVE     = "NOSAVE"
+	SETUSER    = "SETUSER"
+	GETUSER    = "GETUSER"
+	DELUSER    = "DELUSER"
+	LIST       = "LIST"
+	WHOAMI     = "WHOAMI"
+	FRAMED     = "FRAMED"
+	ORDERED    = "ORDERED"
+	READ       = "READ"
+	BYSCORE    = "BYSCORE"
+	WITHSCORES = "WITHSCORES"
+)
+
+// Категории команд
+const (
+	CategoryRead       = "read"
+	CategoryWrite      = "write"
+	CategoryAdmin      = "admin"
+	CategoryConnection = "connection"
+	CategoryPubSub     = "pubsub"
+)
+
+// CommandSpec описывает команду протокола
+type CommandSpec struct {
+	Name        string
+	Arguments   string // синтаксис аргументов для справки
+	Summary     string
+	Group       string
+	MinArgs     int
+	MaxArgs     int      // -1 - без ограничения
+	Subcommands []string // допустимые значения первого аргумента
+	Categories  []string
+	// позиции ключей среди аргументов, начиная с 1: первый, последний (отрицательный
+	// отсчитывается с конца) и шаг. FirstKey = 0 - команда не обращается к ключам
+	FirstKey int
+	LastKey  int
+	KeyStep  int
+	// Blocking - команда ожидает событие с собственным таймаутом, таймаут запроса к ней не применяется
+	Blocking bool
+}
+
+// KeyArgs возвращает аргументы команды, являющиеся ключами
+func (s CommandSpec) KeyArgs(args []string) []string {
+	if s.FirstKey == 0 {
+		return nil
+	}
+
+	last := s.LastKey
+	if last < 0 {
+		last += len(args) + 1
+	}
+
+	step := max(s.KeyStep, 1)
+
+	var keys []string
+	for i := s.FirstKey; i <= last && i <= len(args); i += step {
+		keys = append(keys, args[i-1])
+	}
+
+	return keys
+}
+
+// HasCategory проверяет, относится ли команда к категории
+func (s CommandSpec) HasCategory(category string) bool {
+	return slices.Contains(s.Categories, category)
+}
+
+var commands = map[string]CommandSpec{
+	GET: {
+		Name:       GET,
+		Arguments:  "key",
+		Summary:    "Returns the value of a key.",
+		Group:      "string",
+		MinArgs:    1,
+		MaxArgs:    1,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	SET: {
+		Name:       SET,
+		Arguments:  "key value",
+		Summary:    "Sets the string value of a key.",
+		Group:      "string",
+		MinArgs:    2,
+		MaxArgs:    2,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	DELETE: {
+		Name:       DELETE,
+		Arguments:  "key",
+		Summary:    "Deletes a key.",
+		Group:      "keyspace",
+		MinArgs:    1,
+		MaxArgs:    1,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	HSET: {
+		Name:       HSET,
+		Arguments:  "key field value [field value ...]",
+		Summary:    "Sets fields of a hash, creating the hash if needed.",
+		Group:      "hash",
+		MinArgs:    3,
+		MaxArgs:    -1,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	HGET: {
+		Name:       HGET,
+		Arguments:  "key field",
+		Summary:    "Returns the value of a hash field.",
+		Group:      "hash",
+		MinArgs:    2,
+		MaxArgs:    2,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	HMGET: {
+		Name:       HMGET,
+		Arguments:  "key field [field ...]",
+		Summary:    "Returns the values of hash fields, (nil) for missing ones.",
+		Group:      "hash",
+		MinArgs:    2,
+		MaxArgs:    -1,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	HDEL: {
+		Name:       HDEL,
+		Arguments:  "key field [field ...]",
+		Summary:    "Deletes fields of a hash; the hash is deleted with its last field.",
+		Group:      "hash",
+		MinArgs:    2,
+		MaxArgs:    -1,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	HEXISTS: {
+		Name:       HEXISTS,
+		Arguments:  "key field",
+		Summary:    "Reports whether a hash field exists.",
+		Group:      "hash",
+		MinArgs:    2,
+		MaxArgs:    2,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	HLEN: {
+		Name:       HLEN,
+		Arguments:  "key",
+		Summary:    "Returns the number of fields in a hash.",
+		Group:      "hash",
+		MinArgs:    1,
+		MaxArgs:    1,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	HKEYS: {
+		Name:       HKEYS,
+		Arguments:  "key",
+		Summary:    "Returns the field names of a hash.",
+		Group:      "hash",
+		MinArgs:    1,
+		MaxArgs:    1,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	HGETALL: {
+		Name:       HGETALL,
+		Arguments:  "key",
+		Summary:    "Returns the fields and values of a hash.",
+		Group:      "hash",
+		MinArgs:    1,
+		MaxArgs:    1,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	HINCRBY: {
+		Name:       HINCRBY,
+		Arguments:  "key field increment",
+		Summary:    "Increments the integer value of a hash field.",
+		Group:      "hash",
+		MinArgs:    3,
+		MaxArgs:    3,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	LPUSH: {
+		Name:       LPUSH,
+		Arguments:  "key element [element ...]",
+		Summary:    "Inserts elements at the head of a list, creating the list if needed.",
+		Group:      "list",
+		MinArgs:    2,
+		MaxArgs:    -1,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	RPUSH: {
+		Name:       RPUSH,
+		Arguments:  "key element [element ...]",
+		Summary:    "Appends elements to the tail of a list, creating the list if needed.",
+		Group:      "list",
+		MinArgs:    2,
+		MaxArgs:    -1,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	LPOP: {
+		Name:       LPOP,
+		Arguments:  "key [count]",
+		Summary:    "Removes and returns elements from the head of a list.",
+		Group:      "list",
+		MinArgs:    1,
+		MaxArgs:    2,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	RPOP: {
+		Name:       RPOP,
+		Arguments:  "key [count]",
+		Summary:    "Removes and returns elements from the tail of a list.",
+		Group:      "list",
+		MinArgs:    1,
+		MaxArgs:    2,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	LRANGE: {
+		Name:       LRANGE,
+		Arguments:  "key start stop",
+		Summary:    "Returns a range of list elements; negative indexes count from the tail.",
+		Group:      "list",
+		MinArgs:    3,
+		MaxArgs:    3,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	LLEN: {
+		Name:       LLEN,
+		Arguments:  "key",
+		Summary:    "Returns the length of a list.",
+		Group:      "list",
+		MinArgs:    1,
+		MaxArgs:    1,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	LINDEX: {
+		Name:       LINDEX,
+		Arguments:  "key index",
+		Summary:    "Returns a list element by its index.",
+		Group:      "list",
+		MinArgs:    2,
+		MaxArgs:    2,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	LTRIM: {
+		Name:       LTRIM,
+		Arguments:  "key start stop",
+		Summary:    "Keeps only the given range of list elements.",
+		Group:      "list",
+		MinArgs:    3,
+		MaxArgs:    3,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	BLPOP: {
+		Name:       BLPOP,
+		Arguments:  "key [key ...] timeout",
+		Summary:    "Removes and returns the head of the first non-empty list, waiting up to timeout seconds (0 - forever).",
+		Group:      "list",
+		MinArgs:    2,
+		MaxArgs:    -1,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    -2,
+		KeyStep:    1,
+		Blocking:   true,
+	},
+	BRPOP: {
+		Name:       BRPOP,
+		Arguments:  "key [key ...] timeout",
+		Summary:    "Removes and returns the tail of the first non-empty list, waiting up to timeout seconds (0 - forever).",
+		Group:      "list",
+		MinArgs:    2,
+		MaxArgs:    -1,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    -2,
+		KeyStep:    1,
+		Blocking:   true,
+	},
+	SADD: {
+		Name:       SADD,
+		Arguments:  "key member [member ...]",
+		Summary:    "Adds members to a set, creating the set if needed.",
+		Group:      "set",
+		MinArgs:    2,
+		MaxArgs:    -1,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	SREM: {
+		Name:       SREM,
+		Arguments:  "key member [member ...]",
+		Summary:    "Removes members from a set; the set is deleted with its last member.",
+		Group:      "set",
+		MinArgs:    2,
+		MaxArgs:    -1,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	SISMEMBER: {
+		Name:       SISMEMBER,
+		Arguments:  "key member",
+		Summary:    "Reports whether a value is a member of a set.",
+		Group:      "set",
+		MinArgs:    2,
+		MaxArgs:    2,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	SMEMBERS: {
+		Name:       SMEMBERS,
+		Arguments:  "key",
+		Summary:    "Returns the members of a set.",
+		Group:      "set",
+		MinArgs:    1,
+		MaxArgs:    1,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	SINTER: {
+		Name:       SINTER,
+		Arguments:  "key [key ...]",
+		Summary:    "Returns the intersection of sets.",
+		Group:      "set",
+		MinArgs:    1,
+		MaxArgs:    -1,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    1,
+	},
+	SUNION: {
+		Name:       SUNION,
+		Arguments:  "key [key ...]",
+		Summary:    "Returns the union of sets.",
+		Group:      "set",
+		MinArgs:    1,
+		MaxArgs:    -1,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    1,
+	},
+	SDIFF: {
+		Name:       SDIFF,
+		Arguments:  "key [key ...]",
+		Summary:    "Returns the members of the first set that are not in the other sets.",
+		Group:      "set",
+		MinArgs:    1,
+		MaxArgs:    -1,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    -1,
+		KeyStep:    1,
+	},
+	ZADD: {
+		Name:       ZADD,
+		Arguments:  "key score member [score member ...]",
+		Summary:    "Adds members to a sorted set or updates their scores.",
+		Group:      "sorted_set",
+		MinArgs:    3,
+		MaxArgs:    -1,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	ZREM: {
+		Name:       ZREM,
+		Arguments:  "key member [member ...]",
+		Summary:    "Removes members from a sorted set; the set is deleted with its last member.",
+		Group:      "sorted_set",
+		MinArgs:    2,
+		MaxArgs:    -1,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	ZSCORE: {
+		Name:       ZSCORE,
+		Arguments:  "key member",
+		Summary:    "Returns the score of a sorted set member.",
+		Group:      "sorted_set",
+		MinArgs:    2,
+		MaxArgs:    2,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	ZRANK: {
+		Name:       ZRANK,
+		Arguments:  "key member",
+		Summary:    "Returns the rank of a member in a sorted set ordered by ascending score.",
+		Group:      "sorted_set",
+		MinArgs:    2,
+		MaxArgs:    2,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	ZRANGE: {
+		Name:       ZRANGE,
+		Arguments:  "key start stop [BYSCORE] [WITHSCORES]",
+		Summary:    "Returns sorted set members by rank or, with BYSCORE, by score range; a ( prefix makes a score bound exclusive.",
+		Group:      "sorted_set",
+		MinArgs:    3,
+		MaxArgs:    5,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	ZINCRBY: {
+		Name:       ZINCRBY,
+		Arguments:  "key increment member",
+		Summary:    "Increments the score of a sorted set member.",
+		Group:      "sorted_set",
+		MinArgs:    3,
+		MaxArgs:    3,
+		Categories: []string{CategoryWrite},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	TYPE: {
+		Name:       TYPE,
+		Arguments:  "key",
+		Summary:    "Returns the type of the value stored at a key.",
+		Group:      "keyspace",
+		MinArgs:    1,
+		MaxArgs:    1,
+		Categories: []string{CategoryRead},
+		FirstKey:   1,
+		LastKey:    1,
+		KeyStep:    1,
+	},
+	INFO: {
+		Name:       INFO,
+		Arguments:  "[section]",
+		Summary:    "Returns information and statistics about the server.",
+		Group:      "server",
+		MinArgs:    0,
+		MaxArgs:    1,
+		Categories: []string{CategoryRead},
+	},
+	DBSIZE: {
+		Name:       DBSIZE,
+		Summary:    "Returns the number of keys in the database.",
+		Group:      "server",
+		Categories: []string{CategoryRead},
+	},
+	TIME: {
+		Name:       TIME,
+		Summary:    "Returns the server time as unix seconds and microseconds.",
+		Group:      "server",
+		Categories: []string{CategoryRead},
+	},
+	PING: {
+		Name:       PING,
+		Arguments:  "[message]",
+		Summary:    "Returns PONG or the given message.",
+		Group:      "connection",
+		MinArgs:    0,
+		MaxArgs:    1,
+		Categories: []string{CategoryConnection},
+	},
+	AUTH: {
+		Name:       AUTH,
+		Arguments:  "username password",
+		Summary:    "Authenticates the connection as the given user.",
+		Group:      "connection",
+		MinArgs:    2,
+		MaxArgs:    2,
+		Categories: []string{CategoryConnection},
+	},
+	ACL: {
+		Name:        ACL,
+		Arguments:   "SETUSER username [rule ...] | GETUSER username | DELUSER username [username ...] | LIST | WHOAMI",
+		Summary:     "Manages users and their permissions.",
+		Group:       "server",
+		MinArgs:     1,
+		MaxArgs:     -1,
+		Subcommands: []string{SETUSER, GETUSER, DELUSER, LIST, WHOAMI},
+		Categories:  []string{CategoryAdmin},
+	},
+	PIPELINE: {
+		Name:        PIPELINE,
+		Arguments:   "FRAMED | ORDERED",
+		Summary:     "Switches the connection between in-order responses and out-of-order responses tagged with request IDs.",
+		Group:       "connection",
+		MinArgs:     1,
+		MaxArgs:     1,
+		Subcommands: []string{FRAMED, ORDERED},
+		Categories:  []string{CategoryConnection},
+	},
+	SUBSCRIBE: {
+		Name:       SUBSCRIBE,
+		Arguments:  "channel [channel ...]",
+		Summary:    "Subscribes the connection to channels and returns the number of its subscriptions.",
+		Group:      "pubsub",
+		MinArgs:    1,
+		MaxArgs:    -1,
+		Categories: []string{CategoryPubSub},
+	},
+	UNSUBSCRIBE: {
+		Name:       UNSUBSCRIBE,
+		Arguments:  "[channel ...]",
+		Summary:    "Unsubscribes the connection from the given or all channels.",
+		Group:      "pubsub",
+		MinArgs:    0,
+		MaxArgs:    -1,
+		Categories: []string{CategoryPubSub},
+	},
+	PSUBSCRIBE: {
+		Name:       PSUBSCRIBE,
+		Arguments:  "pattern [pattern ...]",
+		Summary:    "Subscribes the connection to channels matching glob patterns.",
+		Group:      "pubsub",
+		MinArgs:    1,
+		MaxArgs:    -1,
+		Categories: []string{CategoryPubSub},
+	},
+	PUNSUBSCRIBE: {
+		Name:       PUNSUBSCRIBE,
+		Arguments:  "[pattern ...]",
+		Summary:    "Unsubscribes the connection from the given or all patterns.",
+		Group:      "pubsub",
+		MinArgs:    0,
+		MaxArgs:    -1,
+		Categories: []string{CategoryPubSub},
+	},
+	PUBLISH: {
+		Name:       PUBLISH,
+		Arguments:  "channel message",
+		Summary:    "Posts a message to a channel and returns the number of receivers.",
+		Group:      "pubsub",
+		MinArgs:    2,
+		MaxArgs:    2,
+		Categories: []string{CategoryPubSub},
+	},
+	COMMAND: {
+		Name:        COMMAND,
+		Arguments:   "DOCS [command-name]",
+		Summary:     "Returns documentation for all or the given command.",
+		Group:       "server",
+		MinArgs:     1,
+		MaxArgs:     2,
+		Subcommands: []string{DOCS},
+		Categories:  []string{CategoryRead},
+	},
+	SLOWLOG: {
+		Name:        SLOWLOG,
+		Arguments:   "GET [count] | LEN | RESET",
+		Summary:     "Manages the slow queries log.",
+		Group:       "server",
+		MinArgs:     1,
+		MaxArgs:     2,
+		Subcommands: []string{GET, LEN, RESET},
+		Categories:  []string{CategoryAdmin},
+	},
+	CONFIG: {
+		Name:        CONFIG,
+		Arguments:   "GET pattern | SET parameter value | REWRITE",
+		Summary:     "Reads or changes the server configuration at runtime.",
+		Group:       "server",
+		MinArgs:     1,
+		MaxArgs:     3,
+		Subcommands: []string{GET, SET, REWRITE},
+		Categories:  []string{CategoryAdmin},
+	},
+	CDC: {
+		Name:        CDC,
+		Arguments:   "READ from_offset count",
+		Summary:     "Reads key changes starting at an offset from the change data capture log.",
+		Group:       "server",
+		MinArgs:     3,
+		MaxArgs:     3,
+		Subcommands: []string{READ},
+		Categories:  []string{CategoryAdmin},
+	},
+	SHUTDOWN: {
+		Name:        SHUTDOWN,
+		Arguments:   "[NOSAVE | SAVE]",
+		Summary:     "Finishes in-flight queries, flushes persistence and stops the server.",
+		Group:       "server",
+		MinArgs:     0,
+		MaxArgs:     1,
+		Subcommands: []string{NOSAVE, SAVE},
+		Categories:  []string{CategoryAdmin},
+	},
+}
+
+// LookupCommand возвращает описание команды по имени
+func LookupCommand(name string) (CommandSpec, bool) {
+	spec, ok := commands[name]
+	return spec, ok
+}
+
+// Commands возвращает описания всех команд, упорядоченные по имени
+func Commands() []CommandSpec {
+	specs := make([]CommandSpec, 0, len(commands))
+	for _, spec := range commands {
+		specs = append(specs, spec)
+	}
+
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].Name < specs[j].Name
+	})
+
+	return specs
+}
+
+func (s CommandSpec) validate(args []string) error {
+	if len(args) < s.MinArgs || (s.MaxArgs >= 0 && len(args) > s.MaxArgs) {
+		return &ArityError{Command: s.Name, Min: s.MinArgs, Max: s.MaxArgs}
+	}
+
+	if len(s.Subcommands) > 0 && len(args) > 0 && !slices.Contains(s.Subcommands, strings.ToUpper(args[0])) {
+		return fmt.Errorf("%w: %s %s", ErrUnknownCommand, s.Name, args[0])
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrEmptyCommand    = errors.New("empty command")
+	ErrSyntax          = errors.New("syntax error")
+	ErrUnknownCommand  = errors.New("unknown command")
+	ErrWrongArity      = errors.New("wrong number of arguments")
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrTooManyArguments - токенизатор встретил больше аргументов, чем допускает протокол
+	ErrTooManyArguments = errors.New("too many arguments")
+)
+
+// SyntaxError описывает ошибку разбора запроса с указанием позиции
+type SyntaxError struct {
+	Input    string // исходная строка запроса
+	Offset   int    // смещение ошибочного символа в байтах
+	Rune     rune   // ошибочный символ
+	Expected string // ожидаемый класс токена
+	State    State  // состояние автомата в момент ошибки
+}
+
+func newSyntaxError(fsm *FSM, ch rune, expected string) *SyntaxError {
+	return &SyntaxError{
+		Input:    fsm.input,
+		Offset:   byteOffset(fsm.input, fsm.position),
+		Rune:     ch,
+		Expected: expected,
+		State:    fsm.currentState,
+	}
+}
+
+// byteOffset переводит позицию в рунах в смещение в байтах
+func byteOffset(input string, position int) int {
+	n := 0
+	for i := range input {
+		if n == position {
+			return i
+		}
+		n++
+	}
+
+	return len(input)
+}
+
+// Is позволяет проверять SyntaxError через errors.Is(err, ErrSyntax)
+func (e *SyntaxError) Is(target error) bool {
+	return target == ErrSyntax
+}
+
+func (e *SyntaxError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "syntax error at offset %d in %s: unexpected %q", e.Offset, e.State, e.Rune)
+	if e.Expected != "" {
+		fmt.Fprintf(&b, ", expected %s", e.Expected)
+	}
+
+	line, column := e.line()
+	b.WriteString("\n")
+	b.WriteString(line)
+	b.WriteString("\n")
+	b.WriteString(column)
+	b.WriteString("^")
+
+	return b.String()
+}
+
+// line возвращает строку запроса, содержащую ошибку, и отступ для каретки
+func (e *SyntaxError) line() (string, string) {
+	offset := min(max(e.Offset, 0), len(e.Input))
+
+	start := strings.LastIndexByte(e.Input[:offset], '\n') + 1
+	end := strings.IndexByte(e.Input[offset:], '\n')
+	if end < 0 {
+		end = len(e.Input)
+	} else {
+		end += offset
+	}
+
+	var pad strings.Builder
+	for _, r := range e.Input[start:offset] {
+		// табуляцию сохраняем, чтобы каретка совпала с символом при выводе
+		if r == '\t' {
+			pad.WriteRune('\t')
+		} else {
+			pad.WriteRune(' ')
+		}
+	}
+
+	return strings.TrimRight(e.Input[start:end], "\r"), pad.String()
+}
+
+// ArityError - неверное количество аргументов команды
+type ArityError struct {
+	Command string
+	Min     int
+	Max     int // -1 - без ограничения
+}
+
+// Is позволяет проверять ArityError через errors.Is(err, ErrWrongArity)
+func (e *ArityError) Is(target error) bool {
+	return target == ErrWrongArity
+}
+
+func (e *ArityError) Error() string {
+	switch {
+	case e.Min == e.Max && e.Min == 0:
+		return fmt.Sprintf("command %s takes no arguments", e.Command)
+	case e.Min == e.Max && e.Min == 1:
+		return fmt.Sprintf("command %s requires 1 argument", e.Command)
+	case e.Min == e.Max:
+		return fmt.Sprintf("%d arguments required for %s command", e.Min, e.Command)
+	case e.Max < 0:
+		return fmt.Sprintf("command %s requires at least %d arguments", e.Command, e.Min)
+	default:
+		return fmt.Sprintf("command %s requires from %d to %d arguments", e.Command, e.Min, e.Max)
+	}
+}
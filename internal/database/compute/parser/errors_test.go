@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSyntaxError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		offset   int
+		char     rune
+		state    State
+		expected string
+	}{
+		{
+			name:     "Недопустимый символ в аргументе",
+			input:    "SET key va(lue",
+			offset:   10,
+			char:     '(',
+			state:    StateArguments,
+			expected: "syntax error at offset 10 in arguments: unexpected '(', expected argument character or whitespace\nSET key va(lue\n          ^",
+		},
+		{
+			name:     "Смещение в байтах для многобайтовых символов",
+			input:    "SET ключ зна(чение",
+			offset:   19,
+			char:     '(',
+			state:    StateArguments,
+			expected: "syntax error at offset 19 in arguments: unexpected '(', expected argument character or whitespace\nSET ключ зна(чение\n            ^",
+		},
+		{
+			name:     "Каретка на строке с ошибкой",
+			input:    "GET\r\nab(",
+			offset:   7,
+			char:     '(',
+			state:    StateArguments,
+			expected: "syntax error at offset 7 in arguments: unexpected '(', expected argument character or whitespace\nab(\n  ^",
+		},
+		{
+			name:     "Табуляция сохраняется в отступе",
+			input:    "GET\ta(",
+			offset:   5,
+			char:     '(',
+			state:    StateArguments,
+			expected: "syntax error at offset 5 in arguments: unexpected '(', expected argument character or whitespace\nGET\ta(\n   \t ^",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New().Parse(tt.input)
+
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("ожидалась SyntaxError, получена %v", err)
+			}
+
+			if syntaxErr.Offset != tt.offset {
+				t.Errorf("ожидалось смещение %d, получено %d", tt.offset, syntaxErr.Offset)
+			}
+
+			if syntaxErr.Rune != tt.char {
+				t.Errorf("ожидался символ %q, получен %q", tt.char, syntaxErr.Rune)
+			}
+
+			if syntaxErr.State != tt.state {
+				t.Errorf("ожидалось состояние %v, получено %v", tt.state, syntaxErr.State)
+			}
+
+			if err.Error() != tt.expected {
+				t.Errorf("ожидалось сообщение\n%s\nполучено\n%s", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestParser_Parse_ErrorKinds(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		kind  error
+	}{
+		{"Пустая команда", "   ", ErrEmptyCommand},
+		{"Синтаксическая ошибка", "get key", ErrSyntax},
+		{"Неизвестная команда", "FOO key", ErrUnknownCommand},
+		{"Неверное число аргументов", "SET key", ErrWrongArity},
+		{"Лишний аргумент", "SET a b c", ErrWrongArity},
+		{"Слишком много аргументов", "SET" + strings.Repeat(" a", MaxTokens), ErrTooManyArguments},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New().Parse(tt.input)
+			if !errors.Is(err, tt.kind) {
+				t.Errorf("ожидалась ошибка %v, получена %v", tt.kind, err)
+			}
+		})
+	}
+}
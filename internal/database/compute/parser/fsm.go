@@ -1,10 +1,15 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
 
+// MaxTokens ограничивает число токенов в запросе. Число аргументов конкретной команды
+// проверяется по таблице команд
+const MaxTokens = 1024
+
 // FSM отвечает за токенизацию входной строки
 type FSM struct {
 	input        string
@@ -31,10 +36,10 @@ func (fsm *FSM) Tokenize() ([]string, error) {
 		if fsm.position >= len(runes) {
 			switch fsm.currentState {
 			case StateStart:
-				return nil, fmt.Errorf("empty command")
+				return nil, ErrEmptyCommand
 			case StateCommand:
 				if fsm.currentToken.Len() == 0 {
-					return nil, fmt.Errorf("empty command")
+					return nil, ErrEmptyCommand
 				}
 				fsm.tokens = append(fsm.tokens, fsm.currentToken.String())
 			case StateArguments:
@@ -58,6 +63,11 @@ func (fsm *FSM) Tokenize() ([]string, error) {
 			if transition.Condition(currentRune) {
 				err := transition.Action(fsm, currentRune)
 				if err != nil {
+					var syntaxErr *SyntaxError
+					if errors.As(err, &syntaxErr) {
+						return nil, syntaxErr
+					}
+
 					return nil, fmt.Errorf("failed transition.Action: %w", err)
 				}
 
@@ -68,12 +78,12 @@ func (fsm *FSM) Tokenize() ([]string, error) {
 		}
 
 		if !matched {
-			return nil, fmt.Errorf("no transition from state %v for symbol '%c'", fsm.currentState, currentRune)
+			return nil, newSyntaxError(fsm, currentRune, "")
 		}
 	}
 
 	if len(fsm.tokens) == 0 {
-		return nil, fmt.Errorf("empty command")
+		return nil, ErrEmptyCommand
 	}
 
 	return fsm.tokens, nil
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -42,14 +43,14 @@ func TestFSM_Tokenize(t *testing.T) {
 			input:     "command arg1",
 			want:      nil,
 			expectErr: true,
-			errMsg:    "failed transition.Action: invalid character at start: 'c'",
+			errMsg:    "syntax error at offset 0 in start: unexpected 'c', expected uppercase command name\ncommand arg1\n^",
 		},
 		{
 			name:      "Invalid Character in Command",
 			input:     "COMmAND arg1",
 			want:      nil,
 			expectErr: true,
-			errMsg:    "failed transition.Action: invalid character in command: 'm'",
+			errMsg:    "syntax error at offset 3 in command: unexpected 'm', expected uppercase letter or whitespace\nCOMmAND arg1\n   ^",
 		},
 		{
 			name:      "Invalid Character in Argument",
@@ -59,8 +60,15 @@ func TestFSM_Tokenize(t *testing.T) {
 			errMsg:    "",
 		},
 		{
-			name:      "Too Many Arguments",
+			name:      "Several Arguments",
 			input:     "CMD arg1 arg2 arg3",
+			want:      []string{"CMD", "arg1", "arg2", "arg3"},
+			expectErr: false,
+			errMsg:    "",
+		},
+		{
+			name:      "Too Many Arguments",
+			input:     "CMD" + strings.Repeat(" arg", MaxTokens),
 			want:      nil,
 			expectErr: true,
 			errMsg:    "failed transition.Action: too many arguments",
@@ -108,7 +116,7 @@ func TestFSM_Tokenize(t *testing.T) {
 			input:     "#CMD arg1",
 			want:      nil,
 			expectErr: true,
-			errMsg:    "failed transition.Action: invalid character at start: '#'",
+			errMsg:    "syntax error at offset 0 in start: unexpected '#', expected uppercase command name\n#CMD arg1\n^",
 		},
 	}
 
@@ -32,7 +32,7 @@ func isUppercase(ch rune) bool {
 // isPunctuation проверяет, является ли символ допустимым знаком пунктуации
 func isPunctuation(ch rune) bool {
 	switch ch {
-	case '*', '/', '_', '-', '.', '+', '=', '?', '&', '%', '$', '#', '@', '!':
+	case '*', '/', '_', '-', '.', '+', '=', '?', '&', '%', '$', '#', '@', '!', ':', '~', '>':
 		return true
 	default:
 		return false
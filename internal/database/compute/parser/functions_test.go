@@ -132,6 +132,10 @@ func TestIsPunctuation(t *testing.T) {
 		{"Цифра", '1', false},
 		{"Пробел", ' ', false},
 		{"Символ", '$', true},
+		{"Двоеточие", ':', true},
+		{"Тильда", '~', true},
+		{"Больше", '>', true},
+		{"Меньше", '<', false},
 	}
 
 	for _, tt := range tests {
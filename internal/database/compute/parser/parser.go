@@ -1,15 +1,10 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 )
 
-const (
-	GET    = "GET"
-	SET    = "SET"
-	DELETE = "DEL"
-)
-
 //go:generate go run github.com/vektra/mockery/v2@v2.45.1 --name=FMS --output ../../mocks
 type FMS interface {
 	Tokenize() ([]string, error)
@@ -21,19 +16,12 @@ type Command struct {
 }
 
 func (c *Command) Validate() error {
-	if c.Action != GET && c.Action != SET && c.Action != DELETE {
-		return fmt.Errorf("unknown command: %s", c.Action)
-	}
-
-	if (c.Action == GET || c.Action == DELETE) && len(c.Args) != 1 {
-		return fmt.Errorf("command %s requires 1 argument", c.Action)
+	spec, ok := LookupCommand(c.Action)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownCommand, c.Action)
 	}
 
-	if c.Action == SET && len(c.Args) != 2 {
-		return fmt.Errorf("2 arguments required for SET command")
-	}
-
-	return nil
+	return spec.validate(c.Args)
 }
 
 type Parser struct{}
@@ -46,6 +34,11 @@ func (p *Parser) Parse(input string) (*Command, error) {
 	f := NewFSM(input)
 	currentFSM, err := f.Tokenize()
 	if err != nil {
+		var syntaxErr *SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, syntaxErr
+		}
+
 		return nil, fmt.Errorf("failed f.Parse: %w", err)
 	}
 
@@ -183,3 +183,67 @@ func TestParser_FsmParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_Parse_AdminCommands(t *testing.T) {
+	p := New()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected *Command
+		wantErr  bool
+	}{
+		{"INFO без раздела", "INFO", &Command{Action: INFO, Args: []string{}}, false},
+		{"INFO с разделом", "INFO keyspace", &Command{Action: INFO, Args: []string{"keyspace"}}, false},
+		{"DBSIZE", "DBSIZE", &Command{Action: DBSIZE, Args: []string{}}, false},
+		{"TIME", "TIME", &Command{Action: TIME, Args: []string{}}, false},
+		{"PING с сообщением", "PING hello", &Command{Action: PING, Args: []string{"hello"}}, false},
+		{"COMMAND DOCS", "COMMAND DOCS GET", &Command{Action: COMMAND, Args: []string{"DOCS", "GET"}}, false},
+		{"COMMAND без подкоманды", "COMMAND", nil, true},
+		{"Неизвестная подкоманда COMMAND", "COMMAND COUNT", nil, true},
+		{"TIME с аргументом", "TIME now", nil, true},
+		{"INFO с двумя разделами", "INFO server memory", nil, true},
+		{"ACL SETUSER с правилами", "ACL SETUSER bob >pass +@read ~billing:*", &Command{Action: ACL, Args: []string{"SETUSER", "bob", ">pass", "+@read", "~billing:*"}}, false},
+		{"ACL WHOAMI", "ACL WHOAMI", &Command{Action: ACL, Args: []string{"WHOAMI"}}, false},
+		{"Неизвестная подкоманда ACL", "ACL CAT", nil, true},
+		{"Исключаемая граница ZRANGE", "ZRANGE board (1 +inf BYSCORE", &Command{Action: ZRANGE, Args: []string{"board", "(1", "+inf", "BYSCORE"}}, false},
+		{"Скобка внутри аргумента", "ZRANGE board 1( 2", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Parse(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Parse() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCommandSpec_KeyArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     CommandSpec
+		args     []string
+		expected []string
+	}{
+		{"Без ключей", CommandSpec{}, []string{"a"}, nil},
+		{"Один ключ", CommandSpec{FirstKey: 1, LastKey: 1, KeyStep: 1}, []string{"key", "value"}, []string{"key"}},
+		{"Все аргументы", CommandSpec{FirstKey: 1, LastKey: -1, KeyStep: 1}, []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+		{"Кроме последнего", CommandSpec{FirstKey: 1, LastKey: -2, KeyStep: 1}, []string{"a", "b", "5"}, []string{"a", "b"}},
+		{"С шагом", CommandSpec{FirstKey: 1, LastKey: -1, KeyStep: 2}, []string{"k1", "v1", "k2", "v2"}, []string{"k1", "k2"}},
+		{"Аргументов меньше", CommandSpec{FirstKey: 1, LastKey: 1, KeyStep: 1}, []string{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.spec.KeyArgs(tt.args); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("KeyArgs() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// NilValue обозначает в многострочных текстовых ответах отсутствующее значение
+const NilValue = "(nil)"
+
+// Quote заключает в кавычки по правилам strconv.Quote значения, которые иначе нельзя
+// выделить из текстовой строки ответа или спутать с NilValue: пустые, с пробелами
+// и непечатными символами
+func Quote(s string) string {
+	if s != "" && s != NilValue && strings.IndexFunc(s, func(r rune) bool { return r == ' ' || !unicode.IsGraphic(r) }) < 0 {
+		return s
+	}
+
+	return strconv.Quote(s)
+}
+
+type rawValuesKey struct{}
+
+// WithRawValues отмечает запрос протокола, который передает ответ без разбора на строки,
+// например бинарного: одиночные значения в ответе не заключаются в кавычки
+func WithRawValues(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rawValuesKey{}, true)
+}
+
+// QuoteValue возвращает ответ из одного значения: как есть для запросов WithRawValues,
+// иначе по правилам Quote, чтобы перевод строки в значении не разорвал текстовый ответ
+func QuoteValue(ctx context.Context, s string) string {
+	if raw, _ := ctx.Value(rawValuesKey{}).(bool); raw {
+		return s
+	}
+
+	return Quote(s)
+}
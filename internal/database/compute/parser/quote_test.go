@@ -0,0 +1,27 @@
+package parser
+
+import "testing"
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Простое значение", "value", "value"},
+		{"Юникод", "значение", "значение"},
+		{"Пустая строка", "", `""`},
+		{"Пробел", "a b", `"a b"`},
+		{"Перевод строки", "a\nb", `"a\nb"`},
+		{"Кавычка внутри", `a"b`, `a"b`},
+		{"Отсутствующее значение", NilValue, `"(nil)"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := Quote(tt.input); result != tt.expected {
+				t.Errorf("Quote(%q) = %s; ожидалось %s", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
@@ -15,6 +15,21 @@ const (
 	StateEnd
 )
 
+func (s State) String() string {
+	switch s {
+	case StateStart:
+		return "start"
+	case StateCommand:
+		return "command"
+	case StateArguments:
+		return "arguments"
+	case StateEnd:
+		return "end"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
 // Transition условие перехода между состояниями
 type Transition struct {
 	Condition func(rune) bool
@@ -48,7 +63,7 @@ var TransitionTable = map[State][]Transition{
 			},
 			NextState: StateEnd,
 			Action: func(fsm *FSM, ch rune) error {
-				return fmt.Errorf("invalid character at start: '%c'", ch)
+				return newSyntaxError(fsm, ch, "uppercase command name")
 			},
 		},
 	},
@@ -58,7 +73,7 @@ var TransitionTable = map[State][]Transition{
 			NextState: StateArguments,
 			Action: func(fsm *FSM, ch rune) error {
 				if fsm.currentToken.Len() == 0 {
-					return fmt.Errorf("empty command")
+					return ErrEmptyCommand
 				}
 
 				fsm.tokens = append(fsm.tokens, fsm.currentToken.String())
@@ -82,7 +97,7 @@ var TransitionTable = map[State][]Transition{
 			},
 			NextState: StateEnd,
 			Action: func(fsm *FSM, ch rune) error {
-				return fmt.Errorf("invalid character in command: '%c'", ch)
+				return newSyntaxError(fsm, ch, "uppercase letter or whitespace")
 			},
 		},
 	},
@@ -106,8 +121,27 @@ var TransitionTable = map[State][]Transition{
 			Action: func(fsm *FSM, ch rune) error {
 				fsm.currentToken.WriteRune(ch)
 				fsm.position++
-				if len(fsm.tokens) >= 3 {
-					return fmt.Errorf("too many arguments")
+				if len(fsm.tokens) >= MaxTokens {
+					return ErrTooManyArguments
+				}
+				return nil
+			},
+		},
+		{
+			// ( допустима только в начале аргумента: так задается исключаемая граница ZRANGE BYSCORE
+			Condition: func(r rune) bool {
+				return r == '('
+			},
+			NextState: StateArguments,
+			Action: func(fsm *FSM, ch rune) error {
+				if fsm.currentToken.Len() > 0 {
+					return newSyntaxError(fsm, ch, "argument character or whitespace")
+				}
+
+				fsm.currentToken.WriteRune(ch)
+				fsm.position++
+				if len(fsm.tokens) >= MaxTokens {
+					return ErrTooManyArguments
 				}
 				return nil
 			},
@@ -118,7 +152,7 @@ var TransitionTable = map[State][]Transition{
 			},
 			NextState: StateEnd,
 			Action: func(fsm *FSM, ch rune) error {
-				return fmt.Errorf("invalid character in argument: '%c'", ch)
+				return newSyntaxError(fsm, ch, "argument character or whitespace")
 			},
 		},
 	},
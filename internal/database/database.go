@@ -1,47 +1,176 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/patyukin/mdb/internal/trace"
 	"go.uber.org/zap"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 //go:generate go run github.com/vektra/mockery/v2@v2.45.1 --name=Storage --output ./mocks
 type Storage interface {
-	Execute(*parser.Command) (string, error)
+	Execute(context.Context, *parser.Command) (string, error)
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.45.1 --name=Compute --output ./mocks
 type Compute interface {
-	ProcessRequest(request string) (*parser.Command, error)
+	ProcessRequest(ctx context.Context, request string) (*parser.Command, error)
+}
+
+// Authorizer решает, может ли клиент выполнить команду
+type Authorizer interface {
+	Authorize(ctx context.Context, cmd *parser.Command) error
 }
 
 type Database struct {
-	strg   Storage
-	cmpt   Compute
-	logger *zap.Logger
+	strg         Storage
+	cmpt         Compute
+	logger       *zap.Logger
+	queryTimeout atomic.Int64
+	interceptors []Interceptor
+	handler      Handler
+}
+
+// Option настраивает Database
+type Option func(*Database)
+
+// WithQueryTimeout ограничивает время выполнения одного запроса
+func WithQueryTimeout(timeout time.Duration) Option {
+	return func(d *Database) {
+		d.queryTimeout.Store(int64(timeout))
+	}
 }
 
-func New(cmpt Compute, strg Storage, logger *zap.Logger) *Database {
-	return &Database{
+// SetQueryTimeout меняет таймаут запросов, начатых после вызова
+func (d *Database) SetQueryTimeout(timeout time.Duration) {
+	d.queryTimeout.Store(int64(timeout))
+}
+
+func New(cmpt Compute, strg Storage, logger *zap.Logger, options ...Option) *Database {
+	d := &Database{
 		logger: logger,
 		strg:   strg,
 		cmpt:   cmpt,
 	}
+
+	for _, option := range options {
+		option(d)
+	}
+
+	d.handler = chain(d.interceptors, d.execute)
+
+	return d
 }
 
-func (d *Database) HandleQuery(request string) (string, error) {
-	cmd, err := d.cmpt.ProcessRequest(request)
+func (d *Database) HandleQuery(ctx context.Context, request string) (string, error) {
+	id, request, err := requestID(ctx, request)
 	if err != nil {
-		return "", fmt.Errorf("failed d.cmpt.ProcessRequest: %w", err)
+		// ошибка идентификатора - ошибка разбора: запрос проходит перехватчики, но не выполняется
+		id = trace.NewRequestID()
+	}
+
+	return d.handle(ctx, id, request, &query{err: err})
+}
+
+// HandleCommand выполняет уже разобранную команду, например полученную по бинарному
+// протоколу. Перехватчики получают текстовое представление команды, но его изменение
+// на выполнение не влияет. Значения в ответе передаются без кавычек
+func (d *Database) HandleCommand(ctx context.Context, cmd *parser.Command) (string, error) {
+	id := trace.RequestID(ctx)
+	if id == "" {
+		id = trace.NewRequestID()
+	}
+
+	return d.handle(parser.WithRawValues(ctx), id, commandString(cmd), &query{command: cmd, parsed: true})
+}
+
+func (d *Database) handle(ctx context.Context, id, request string, q *query) (string, error) {
+	q.untimed = ctx
+	if timeout := time.Duration(d.queryTimeout.Load()); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	ctx = trace.NewContext(ctx, trace.New(id))
+	ctx = context.WithValue(ctx, queryKey{}, q)
+
+	// разбор выполняется до перехватчиков, чтобы они видели команду до ее выполнения
+	if q.err == nil {
+		q.command, q.err = d.command(ctx, request, q)
+	}
+
+	return d.handler(ctx, request)
+}
+
+// execute выполняет разобранную команду или возвращает ошибку разбора
+func (d *Database) execute(ctx context.Context, _ string) (string, error) {
+	q := ctx.Value(queryKey{}).(*query)
+	if q.err != nil {
+		return "", q.err
 	}
 
-	result, err := d.strg.Execute(cmd)
+	cmd := q.command
+	if spec, _ := parser.LookupCommand(cmd.Action); spec.Blocking && q.untimed != nil {
+		// блокирующая команда ждет в пределах собственного таймаута, но прерывается
+		// при отмене запроса, например при закрытии соединения
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+		defer cancel()
+		defer context.AfterFunc(q.untimed, cancel)()
+
+		// ожидание не считается простоем соединения
+		if sess, ok := session.FromContext(ctx); ok {
+			defer sess.Block()()
+		}
+	}
+
+	result, err := d.strg.Execute(ctx, cmd)
 	if err != nil {
 		return "", fmt.Errorf("failed c.storage.Execute: %w", err)
 	}
 
-	d.logger.Info("Request processed successfully")
-
 	return result, nil
 }
+
+// command возвращает команду запроса: переданную вызывающей стороной после проверки
+// аргументов или полученную разбором текста
+func (d *Database) command(ctx context.Context, request string, q *query) (*parser.Command, error) {
+	if q.parsed {
+		if err := q.command.Validate(); err != nil {
+			return nil, fmt.Errorf("failed cmd.Validate: %w", err)
+		}
+
+		return q.command, nil
+	}
+
+	cmd, err := d.cmpt.ProcessRequest(ctx, request)
+	if err != nil {
+		var syntaxErr *parser.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, syntaxErr
+		}
+
+		return nil, fmt.Errorf("failed d.cmpt.ProcessRequest: %w", err)
+	}
+
+	return cmd, nil
+}
+
+// commandString возвращает текстовое представление команды для логов и трассировки.
+// Аргументы заключаются в кавычки по правилам parser.Quote
+func commandString(cmd *parser.Command) string {
+	parts := make([]string, 0, len(cmd.Args)+1)
+	parts = append(parts, cmd.Action)
+	for _, arg := range cmd.Args {
+		parts = append(parts, parser.Quote(arg))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -1,15 +1,26 @@
 package database
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"github.com/patyukin/mdb/internal/auth"
 	"github.com/patyukin/mdb/internal/database/compute"
 	"github.com/patyukin/mdb/internal/database/compute/parser"
 	"github.com/patyukin/mdb/internal/database/mocks"
+	"github.com/patyukin/mdb/internal/database/slowlog"
 	"github.com/patyukin/mdb/internal/database/storage"
 	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/patyukin/mdb/internal/trace"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"testing"
+	"time"
 )
 
 func TestHandleQuery_TableDriven(t *testing.T) {
@@ -32,8 +43,8 @@ func TestHandleQuery_TableDriven(t *testing.T) {
 			setupMocks: func() {
 				cmd := &parser.Command{Action: "SET", Args: []string{"test_key", "test_value"}}
 				result := ""
-				mockCompute.On("ProcessRequest", "SET test_key test_value").Return(cmd, nil)
-				mockStorage.On("Execute", cmd).Return(result, nil)
+				mockCompute.On("ProcessRequest", mock.Anything, "SET test_key test_value").Return(cmd, nil)
+				mockStorage.On("Execute", mock.Anything, cmd).Return(result, nil)
 			},
 			expectedResult: "",
 			expectError:    false,
@@ -42,7 +53,7 @@ func TestHandleQuery_TableDriven(t *testing.T) {
 			name:    "ProcessRequest Failure",
 			request: "test_request",
 			setupMocks: func() {
-				mockCompute.On("ProcessRequest", "test_request").Return(nil, errors.New("process error"))
+				mockCompute.On("ProcessRequest", mock.Anything, "test_request").Return(nil, errors.New("process error"))
 			},
 			expectedResult: "",
 			expectError:    true,
@@ -53,8 +64,8 @@ func TestHandleQuery_TableDriven(t *testing.T) {
 			request: "test_request",
 			setupMocks: func() {
 				cmd := &parser.Command{Action: "TestCommand"}
-				mockCompute.On("ProcessRequest", "test_request").Return(cmd, nil)
-				mockStorage.On("Execute", cmd).Return("", errors.New("execute error"))
+				mockCompute.On("ProcessRequest", mock.Anything, "test_request").Return(cmd, nil)
+				mockStorage.On("Execute", mock.Anything, cmd).Return("", errors.New("execute error"))
 			},
 			expectedResult: "",
 			expectError:    true,
@@ -66,7 +77,7 @@ func TestHandleQuery_TableDriven(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setupMocks()
 
-			res, err := db.HandleQuery(tt.request)
+			res, err := db.HandleQuery(context.Background(), tt.request)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -98,6 +109,16 @@ func TestHandleQuery(t *testing.T) {
 
 	db := New(cmpt, strg, logger)
 
+	t.Run("Синтаксическая ошибка передается без обертки", func(t *testing.T) {
+		_, err := db.HandleQuery(context.Background(), "SET key va(lue")
+
+		var syntaxErr *parser.SyntaxError
+		assert.ErrorAs(t, err, &syntaxErr)
+		assert.Equal(t, 10, syntaxErr.Offset)
+		assert.Equal(t, '(', syntaxErr.Rune)
+		assert.Equal(t, parser.StateArguments, syntaxErr.State)
+	})
+
 	tests := []struct {
 		name           string
 		request        string
@@ -127,7 +148,7 @@ func TestHandleQuery(t *testing.T) {
 			setupStorage:   func() {},
 			expectedResult: "",
 			expectError:    true,
-			errorMessage:   "failed d.cmpt.ProcessRequest: failed c.parser.Parse: failed f.Parse: failed transition.Action: invalid character at start: 'u'",
+			errorMessage:   "syntax error at offset 0 in start: unexpected 'u', expected uppercase command name\nunknown_command\n^",
 		},
 	}
 
@@ -135,7 +156,7 @@ func TestHandleQuery(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setupStorage()
 
-			res, err := db.HandleQuery(tt.request)
+			res, err := db.HandleQuery(context.Background(), tt.request)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -148,3 +169,262 @@ func TestHandleQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleQuery_Cancellation(t *testing.T) {
+	logger := zap.NewNop()
+	eng := engine.New()
+	db := New(compute.New(parser.New(), logger), storage.New(eng, logger), logger)
+
+	t.Run("Отмененный запрос не изменяет данные", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := db.HandleQuery(ctx, "SET key value")
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = eng.Get(context.Background(), "key")
+		assert.ErrorIs(t, err, engine.ErrNotFound)
+	})
+
+	t.Run("Запрос, отмененный во время разбора, не изменяет данные", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		cmpt := new(mocks.Compute)
+		cmpt.On("ProcessRequest", mock.Anything, "SET key value").
+			Run(func(mock.Arguments) { cancel() }).
+			Return(&parser.Command{Action: "SET", Args: []string{"key", "value"}}, nil)
+
+		_, err := New(cmpt, storage.New(eng, logger), logger).HandleQuery(ctx, "SET key value")
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = eng.Get(context.Background(), "key")
+		assert.ErrorIs(t, err, engine.ErrNotFound)
+	})
+
+	t.Run("Превышение таймаута запроса", func(t *testing.T) {
+		cmpt := new(mocks.Compute)
+		cmpt.On("ProcessRequest", mock.Anything, "SET key value").
+			Run(func(mock.Arguments) { time.Sleep(20 * time.Millisecond) }).
+			Return(&parser.Command{Action: "SET", Args: []string{"key", "value"}}, nil)
+
+		db := New(cmpt, storage.New(eng, logger), logger, WithQueryTimeout(time.Millisecond))
+
+		_, err := db.HandleQuery(context.Background(), "SET key value")
+		assert.ErrorIs(t, err, storage.ErrTimeout)
+		assert.Equal(t, CodeTimeout, ErrorCode(err))
+
+		_, err = eng.Get(context.Background(), "key")
+		assert.ErrorIs(t, err, engine.ErrNotFound)
+	})
+}
+
+func TestHandleQuery_SlowLog(t *testing.T) {
+	cmd := &parser.Command{Action: "GET", Args: []string{"key"}}
+	slowCmd := &parser.Command{Action: "GET", Args: []string{"slow"}}
+
+	mockCompute := new(mocks.Compute)
+	mockCompute.On("ProcessRequest", mock.Anything, "GET key").Return(cmd, nil)
+	mockCompute.On("ProcessRequest", mock.Anything, "GET slow").Return(slowCmd, nil)
+
+	mockStorage := new(mocks.Storage)
+	mockStorage.On("Execute", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			if args.Get(0).(context.Context).Value(slowKey{}) != nil {
+				time.Sleep(15 * time.Millisecond)
+			}
+		}).
+		Return("value", nil)
+
+	l := slowlog.New(10*time.Millisecond, 10)
+	db := New(mockCompute, mockStorage, zap.NewNop(), WithInterceptors(SlowLogInterceptor(l)))
+
+	_, err := db.HandleQuery(context.Background(), "GET key")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, l.Len())
+
+	ctx := session.NewContext(context.WithValue(context.Background(), slowKey{}, true), session.New("10.0.0.1:5000", ""))
+	_, err = db.HandleQuery(ctx, "GET slow")
+	assert.NoError(t, err)
+
+	entries := l.Get(-1)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, []string{"GET", "slow"}, entries[0].Args)
+		assert.Equal(t, "10.0.0.1:5000", entries[0].ClientAddr)
+		assert.GreaterOrEqual(t, entries[0].Duration, 15*time.Millisecond)
+	}
+}
+
+type slowKey struct{}
+
+func TestHandleQuery_Trace(t *testing.T) {
+	logger := zap.NewNop()
+	core, engineLogs := observer.New(zap.DebugLevel)
+	var buf bytes.Buffer
+	db := New(compute.New(parser.New(), logger), storage.New(engine.New(engine.WithLogger(zap.New(core))), logger), logger,
+		WithInterceptors(TraceInterceptor(trace.NewJSONExporter(&buf), logger)))
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		request    string
+		expectedID string
+		expectErr  error
+		spans      []string
+	}{
+		{
+			name:       "Идентификатор из запроса",
+			ctx:        context.Background(),
+			request:    "@req-1 SET key value",
+			expectedID: "req-1",
+			spans:      []string{"parse", "validate", "engine.set", "execute"},
+		},
+		{
+			name:       "Идентификатор из контекста",
+			ctx:        trace.WithRequestID(context.Background(), "ctx-2"),
+			request:    "GET key",
+			expectedID: "ctx-2",
+			spans:      []string{"parse", "validate", "engine.get", "execute"},
+		},
+		{
+			name:      "Недопустимый идентификатор",
+			ctx:       context.Background(),
+			request:   "@bad/id GET key",
+			expectErr: parser.ErrInvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+
+			_, err := db.HandleQuery(tt.ctx, tt.request)
+			code := CodeOK
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				code = ErrorCode(err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			var exported struct {
+				RequestID string       `json:"request_id"`
+				Request   string       `json:"request"`
+				Code      string       `json:"code"`
+				Spans     []trace.Span `json:"spans"`
+			}
+			assert.NoError(t, json.Unmarshal(buf.Bytes(), &exported))
+			if tt.expectedID != "" {
+				assert.Equal(t, tt.expectedID, exported.RequestID)
+				assert.NotContains(t, exported.Request, "@")
+			}
+			assert.Equal(t, code, exported.Code)
+
+			var names []string
+			for _, span := range exported.Spans {
+				names = append(names, span.Name)
+			}
+			// запрос с недопустимым идентификатором не разбирается и не выполняется
+			assert.Equal(t, tt.spans, names)
+		})
+	}
+
+	// записи движка связаны с запросом
+	var ids []string
+	for _, entry := range engineLogs.All() {
+		ids = append(ids, entry.ContextMap()["request_id"].(string))
+	}
+	assert.Equal(t, []string{"req-1"}, ids)
+}
+
+func TestHandleQuery_Auth(t *testing.T) {
+	store := auth.NewStore()
+	assert.NoError(t, store.ApplyRules("alice", []string{">secret", "+@read", "~user:*"}))
+
+	var exported bytes.Buffer
+	logger := zap.NewNop()
+	db := New(
+		compute.New(parser.New(), logger),
+		storage.New(engine.New(), logger, storage.WithAuth(store)),
+		logger,
+		WithInterceptors(TraceInterceptor(trace.NewJSONExporter(&exported), logger), AuthInterceptor(store)),
+	)
+
+	sess := session.New("127.0.0.1:5000", "")
+	ctx := session.NewContext(context.Background(), sess)
+
+	steps := []struct {
+		request      string
+		expectedCode string
+	}{
+		{request: "GET key", expectedCode: CodeNoAuth},
+		{request: "PING", expectedCode: CodeOK},
+		{request: "AUTH alice wrong", expectedCode: CodeAuth},
+		{request: "AUTH alice secret", expectedCode: CodeOK},
+		{request: "GET user:1", expectedCode: CodeNotFound},
+		{request: "GET billing:1", expectedCode: CodeNoPerm},
+		{request: "SET user:1 value", expectedCode: CodeNoPerm},
+		{request: "ACL WHOAMI", expectedCode: CodeOK},
+	}
+
+	for _, step := range steps {
+		_, err := db.HandleQuery(ctx, step.request)
+		assert.Equal(t, step.expectedCode, ErrorCode(err), step.request)
+	}
+
+	assert.Equal(t, "alice", sess.User())
+	assert.NotContains(t, exported.String(), "secret")
+	assert.NotContains(t, exported.String(), "wrong")
+	assert.Contains(t, exported.String(), `"request":"AUTH alice ***"`)
+}
+
+func TestHandleCommand(t *testing.T) {
+	logger := zap.NewNop()
+	mockCompute := new(mocks.Compute)
+	l := slowlog.New(time.Nanosecond, 10)
+	db := New(mockCompute, storage.New(engine.New(), logger), logger, WithInterceptors(SlowLogInterceptor(l)))
+
+	value := "line1\nline2 with spaces"
+	_, err := db.HandleCommand(context.Background(), &parser.Command{Action: parser.SET, Args: []string{"key", value}})
+	assert.NoError(t, err)
+
+	result, err := db.HandleCommand(context.Background(), &parser.Command{Action: parser.GET, Args: []string{"key"}})
+	assert.NoError(t, err)
+	assert.Equal(t, value, result)
+
+	_, err = db.HandleCommand(context.Background(), &parser.Command{Action: parser.GET})
+	assert.ErrorIs(t, err, parser.ErrWrongArity)
+
+	// команда не разбирается повторно, а в журнал попадают ее аргументы
+	mockCompute.AssertNotCalled(t, "ProcessRequest", mock.Anything, mock.Anything)
+	entries := l.Get(-1)
+	if assert.NotEmpty(t, entries) {
+		assert.Equal(t, []string{"SET", "key", value}, entries[len(entries)-1].Args)
+	}
+}
+
+func TestHandleCommand_BlockingIgnoresQueryTimeout(t *testing.T) {
+	logger := zap.NewNop()
+	db := New(new(mocks.Compute), storage.New(engine.New(), logger), logger, WithQueryTimeout(5*time.Millisecond))
+
+	// ожидание дольше таймаута запроса завершается по собственному таймауту команды
+	result, err := db.HandleCommand(context.Background(), &parser.Command{Action: parser.BLPOP, Args: []string{"queue", "0.05"}})
+	require.NoError(t, err)
+	assert.Equal(t, "(nil)", result)
+
+	// отмена запроса по-прежнему прерывает ожидание
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err = db.HandleCommand(ctx, &parser.Command{Action: parser.BLPOP, Args: []string{"queue", "0"}})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// пока команда ждет, сессия отмечена как ожидающая и не закрывается по простою
+	sess := session.New("client", "server")
+	ctx, cancel = context.WithCancel(session.NewContext(context.Background(), sess))
+	go func() {
+		assert.Eventually(t, sess.Blocked, time.Second, time.Millisecond)
+		cancel()
+	}()
+	_, err = db.HandleCommand(ctx, &parser.Command{Action: parser.BLPOP, Args: []string{"queue", "0"}})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, sess.Blocked())
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/patyukin/mdb/internal/auth"
+	"github.com/patyukin/mdb/internal/cdc"
+	"github.com/patyukin/mdb/internal/config"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+)
+
+// Коды ошибок, передаваемые клиентам. Значения являются частью протокола и не меняются
+const (
+	CodeOK              = "OK"
+	CodeNotFound        = "ERR_NOT_FOUND"
+	CodeWrongType       = "ERR_WRONG_TYPE"
+	CodeSyntax          = "ERR_SYNTAX"
+	CodeUnknownCommand  = "ERR_UNKNOWN_COMMAND"
+	CodeArity           = "ERR_ARITY"
+	CodeInvalidArgument = "ERR_INVALID_ARGUMENT"
+	CodeReadOnly        = "ERR_READ_ONLY"
+	CodeOutOfMemory     = "ERR_OOM"
+	CodeTimeout         = "ERR_TIMEOUT"
+	CodeCanceled        = "ERR_CANCELED"
+	CodeNoAuth          = "ERR_NOAUTH"
+	CodeAuth            = "ERR_AUTH"
+	CodeNoPerm          = "ERR_NOPERM"
+	CodeOffsetTrimmed   = "ERR_OFFSET_TRIMMED"
+	CodeInternal        = "ERR_INTERNAL"
+)
+
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{engine.ErrNotFound, CodeNotFound},
+	{engine.ErrWrongType, CodeWrongType},
+	{engine.ErrOutOfMemory, CodeOutOfMemory},
+	{parser.ErrSyntax, CodeSyntax},
+	{parser.ErrEmptyCommand, CodeSyntax},
+	{parser.ErrUnknownCommand, CodeUnknownCommand},
+	{parser.ErrWrongArity, CodeArity},
+	{parser.ErrTooManyArguments, CodeArity},
+	{parser.ErrInvalidArgument, CodeInvalidArgument},
+	{config.ErrUnknownParam, CodeInvalidArgument},
+	{config.ErrStaticParam, CodeInvalidArgument},
+	{config.ErrInvalidConfig, CodeInvalidArgument},
+	{config.ErrNoConfigFile, CodeInvalidArgument},
+	{auth.ErrAuthRequired, CodeNoAuth},
+	{auth.ErrInvalidCredentials, CodeAuth},
+	{auth.ErrLockedOut, CodeAuth},
+	{auth.ErrNoPerm, CodeNoPerm},
+	{auth.ErrUnknownUser, CodeNotFound},
+	{auth.ErrInvalidRule, CodeInvalidArgument},
+	{auth.ErrInvalidHash, CodeInvalidArgument},
+	{auth.ErrLastUser, CodeInvalidArgument},
+	{cdc.ErrOffsetTrimmed, CodeOffsetTrimmed},
+	{storage.ErrReadOnly, CodeReadOnly},
+	{storage.ErrTimeout, CodeTimeout},
+	{context.DeadlineExceeded, CodeTimeout},
+	{context.Canceled, CodeCanceled},
+}
+
+// ErrorCode возвращает код ошибки для передачи клиенту
+func ErrorCode(err error) string {
+	if err == nil {
+		return CodeOK
+	}
+
+	for _, ec := range errorCodes {
+		if errors.Is(err, ec.err) {
+			return ec.code
+		}
+	}
+
+	return CodeInternal
+}
+
+// FormatResponse формирует ответ клиенту: код, за которым следует результат или текст ошибки.
+// Многострочный результат начинается со следующей после кода строки
+func FormatResponse(result string, err error) string {
+	if err != nil {
+		return ErrorCode(err) + " " + err.Error()
+	}
+
+	switch {
+	case result == "":
+		return CodeOK
+	case strings.Contains(result, "\n"):
+		return CodeOK + "\n" + result
+	default:
+		return CodeOK + " " + result
+	}
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/patyukin/mdb/internal/auth"
+	"github.com/patyukin/mdb/internal/cdc"
+	"github.com/patyukin/mdb/internal/database/compute"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"Нет ошибки", nil, CodeOK},
+		{"Ключ не найден", fmt.Errorf("failed s.engine.Get, err: %w", engine.ErrNotFound), CodeNotFound},
+		{"Неверный тип", engine.ErrWrongType, CodeWrongType},
+		{"Синтаксическая ошибка", &parser.SyntaxError{Input: "a", Rune: 'a'}, CodeSyntax},
+		{"Пустая команда", parser.ErrEmptyCommand, CodeSyntax},
+		{"Неизвестная команда", fmt.Errorf("%w: FOO", parser.ErrUnknownCommand), CodeUnknownCommand},
+		{"Неверное число аргументов", &parser.ArityError{Command: "GET", Min: 1, Max: 1}, CodeArity},
+		{"Требуется аутентификация", auth.ErrAuthRequired, CodeNoAuth},
+		{"Неверный пароль", fmt.Errorf("failed s.auth.Authenticate: %w", auth.ErrInvalidCredentials), CodeAuth},
+		{"Клиент заблокирован", auth.ErrLockedOut, CodeAuth},
+		{"Нет прав", fmt.Errorf("%w: user bob cannot run the 'SET' command", auth.ErrNoPerm), CodeNoPerm},
+		{"Неизвестный пользователь", auth.ErrUnknownUser, CodeNotFound},
+		{"Неверное правило ACL", auth.ErrInvalidRule, CodeInvalidArgument},
+		{"Смещение удалено из журнала изменений", fmt.Errorf("failed s.changes.Read: %w", cdc.ErrOffsetTrimmed), CodeOffsetTrimmed},
+		{"Только чтение", storage.ErrReadOnly, CodeReadOnly},
+		{"Нехватка памяти", engine.ErrOutOfMemory, CodeOutOfMemory},
+		{"Таймаут", context.DeadlineExceeded, CodeTimeout},
+		{"Таймаут выполнения", fmt.Errorf("%w: %w", storage.ErrTimeout, context.DeadlineExceeded), CodeTimeout},
+		{"Отмена запроса", context.Canceled, CodeCanceled},
+		{"Прочие ошибки", errors.New("boom"), CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ErrorCode(tt.err))
+		})
+	}
+}
+
+func TestFormatResponse(t *testing.T) {
+	logger := zap.NewNop()
+	db := New(compute.New(parser.New(), logger), storage.New(engine.New(), logger), logger)
+
+	tests := []struct {
+		request  string
+		expected string
+	}{
+		{"SET key value", "OK"},
+		{"GET key", "OK value"},
+		{"INFO keyspace", "OK\n# Keyspace\nkeys:1"},
+		{"GET missing", "ERR_NOT_FOUND failed c.storage.Execute: failed s.engine.Get, err: 'missing' - key not found"},
+		{"GET", "ERR_ARITY failed d.cmpt.ProcessRequest: failed c.parser.Parse: failed cmd.Validate: command GET requires 1 argument"},
+		{"FOO bar", "ERR_UNKNOWN_COMMAND failed d.cmpt.ProcessRequest: failed c.parser.Parse: failed cmd.Validate: unknown command: FOO"},
+		{"get key", "ERR_SYNTAX syntax error at offset 0 in start: unexpected 'g', expected uppercase command name\nget key\n^"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.request, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FormatResponse(db.HandleQuery(context.Background(), tt.request)))
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/patyukin/mdb/internal/audit"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/slowlog"
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/patyukin/mdb/internal/trace"
+	"go.uber.org/zap"
+)
+
+// Handler выполняет разобранную команду запроса. request - текст запроса для логов
+// и трассировки, его изменение на выполнение не влияет
+type Handler func(ctx context.Context, request string) (string, error)
+
+// Interceptor оборачивает выполнение запроса. Запрос разбирается до вызова перехватчиков,
+// и команда доступна через ParsedCommand. Перехватчик может изменить результат, прервать
+// обработку, не вызывая next, либо выполнить действия до и после нее. Ошибку разбора
+// возвращает next
+type Interceptor func(ctx context.Context, request string, next Handler) (string, error)
+
+// WithInterceptors регистрирует перехватчики. Первый зарегистрированный выполняется первым
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(d *Database) {
+		d.interceptors = append(d.interceptors, interceptors...)
+	}
+}
+
+type queryKey struct{}
+
+// query - состояние запроса, которое обработчик передает перехватчикам
+type query struct {
+	command *parser.Command
+	parsed  bool  // команда передана уже разобранной, текст запроса не разбирается
+	err     error // ошибка разбора, возвращается вместо выполнения
+	// untimed - контекст запроса без таймаута, его отмена прерывает блокирующие команды
+	untimed context.Context
+}
+
+// ParsedCommand возвращает разобранную команду текущего запроса, если разбор прошел успешно
+func ParsedCommand(ctx context.Context) (*parser.Command, bool) {
+	q, ok := ctx.Value(queryKey{}).(*query)
+	if !ok || q.command == nil {
+		return nil, false
+	}
+
+	return q.command, true
+}
+
+func chain(interceptors []Interceptor, handler Handler) Handler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, request string) (string, error) {
+			return interceptor(ctx, request, next)
+		}
+	}
+
+	return handler
+}
+
+// LoggingInterceptor журналирует каждый запрос вместе с результатом и данными соединения
+func LoggingInterceptor(logger *zap.Logger) Interceptor {
+	return func(ctx context.Context, request string, next Handler) (string, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		fields := []zap.Field{
+			zap.String("request", redact(request)),
+			zap.String("code", ErrorCode(err)),
+			zap.Duration("elapsed", time.Since(start)),
+		}
+
+		if s, ok := session.FromContext(ctx); ok {
+			fields = append(fields, zap.Uint64("session", s.ID), zap.String("remote", s.RemoteAddr))
+			if user := s.User(); user != "" {
+				fields = append(fields, zap.String("user", user))
+			}
+		}
+
+		l := trace.Logger(ctx, logger)
+		if err != nil {
+			l.Warn("Request failed", append(fields, zap.Error(err))...)
+		} else {
+			l.Info("Request processed successfully", fields...)
+		}
+
+		return result, err
+	}
+}
+
+// AuthInterceptor проверяет права клиента перед выполнением команды
+func AuthInterceptor(a Authorizer) Interceptor {
+	return func(ctx context.Context, request string, next Handler) (string, error) {
+		if cmd, ok := ParsedCommand(ctx); ok {
+			if err := a.Authorize(ctx, cmd); err != nil {
+				return "", fmt.Errorf("failed a.Authorize: %w", err)
+			}
+		}
+
+		return next(ctx, request)
+	}
+}
+
+// MetricsInterceptor собирает метрики запросов: число команд, ошибки по кодам,
+// длительность разбора и выполнения
+func MetricsInterceptor(m *Metrics) Interceptor {
+	return func(ctx context.Context, request string, next Handler) (string, error) {
+		if t, ok := trace.FromContext(ctx); ok {
+			for _, span := range t.Spans() {
+				if span.Name == "parse" {
+					m.parseDuration.ObserveDuration(span.Duration)
+				}
+			}
+		}
+
+		start := time.Now()
+		result, err := next(ctx, request)
+		if cmd, ok := ParsedCommand(ctx); ok {
+			m.commands.WithLabelValues(cmd.Action).Inc()
+			m.executeDuration.WithLabelValues(cmd.Action).ObserveDuration(time.Since(start))
+		}
+
+		if err != nil {
+			m.errors.WithLabelValues(ErrorCode(err)).Inc()
+		}
+
+		return result, err
+	}
+}
+
+// SlowLogInterceptor записывает в журнал запросы, обработка которых с учетом разбора
+// заняла больше порога журнала
+func SlowLogInterceptor(l *slowlog.Log) Interceptor {
+	return func(ctx context.Context, request string, next Handler) (string, error) {
+		result, err := next(ctx, request)
+		if t, ok := trace.FromContext(ctx); ok {
+			l.Record(ctx, slowLogArgs(ctx, request), time.Since(t.Start))
+		}
+
+		return result, err
+	}
+}
+
+// slowLogArgs возвращает команду и аргументы запроса со скрытыми паролями. Аргументы
+// берутся из разобранной команды, а запрос, который не удалось разобрать, делится по пробелам
+func slowLogArgs(ctx context.Context, request string) []string {
+	cmd, ok := ParsedCommand(ctx)
+	if !ok {
+		return strings.Fields(redact(request))
+	}
+
+	args := cmd.Args
+	if masked := redactArgs(cmd.Action, args); masked != nil {
+		args = masked
+	}
+
+	return append([]string{cmd.Action}, args...)
+}
+
+// TraceInterceptor экспортирует трассировку каждого завершенного запроса
+func TraceInterceptor(e trace.Exporter, logger *zap.Logger) Interceptor {
+	return func(ctx context.Context, request string, next Handler) (string, error) {
+		result, err := next(ctx, request)
+		if t, ok := trace.FromContext(ctx); ok {
+			if exportErr := e.Export(t, redact(request), ErrorCode(err), time.Since(t.Start)); exportErr != nil {
+				trace.Logger(ctx, logger).Warn("failed e.Export", zap.Error(exportErr))
+			}
+		}
+
+		return result, err
+	}
+}
+
+// AuditInterceptor записывает в журнал аудита изменяющие и административные команды.
+// Ошибка записи в журнал не прерывает выполнение команды, но попадает в лог
+func AuditInterceptor(log *audit.Log, logger *zap.Logger) Interceptor {
+	return func(ctx context.Context, request string, next Handler) (string, error) {
+		result, err := next(ctx, request)
+
+		cmd, ok := ParsedCommand(ctx)
+		if !ok {
+			return result, err
+		}
+
+		spec, ok := parser.LookupCommand(cmd.Action)
+		if !ok || !(spec.HasCategory(parser.CategoryWrite) || spec.HasCategory(parser.CategoryAdmin)) {
+			return result, err
+		}
+
+		record := audit.Record{
+			Command: cmd.Action,
+			Args:    cmd.Args,
+			Status:  ErrorCode(err),
+		}
+
+		if args := redactArgs(cmd.Action, cmd.Args); args != nil {
+			record.Args = args
+		}
+
+		if s, ok := session.FromContext(ctx); ok {
+			record.Client = s.RemoteAddr
+			record.User = s.User()
+		}
+
+		end := trace.StartSpan(ctx, "audit")
+		_, auditErr := log.Write(record)
+		end()
+
+		if auditErr != nil {
+			trace.Logger(ctx, logger).Error("failed log.Write", zap.String("request", redact(request)), zap.Error(auditErr))
+		}
+
+		return result, err
+	}
+}
@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patyukin/mdb/internal/audit"
+	"github.com/patyukin/mdb/internal/database/compute"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/mocks"
+	"github.com/patyukin/mdb/internal/database/slowlog"
+	"github.com/patyukin/mdb/internal/database/storage"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestInterceptors_Order(t *testing.T) {
+	mockStorage := new(mocks.Storage)
+	mockCompute := new(mocks.Compute)
+
+	var calls []string
+	record := func(name string) Interceptor {
+		return func(ctx context.Context, request string, next Handler) (string, error) {
+			// команда разобрана до вызова перехватчиков
+			parsed, ok := ParsedCommand(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "GET", parsed.Action)
+
+			calls = append(calls, name+":before")
+			result, err := next(ctx, request)
+			calls = append(calls, name+":after")
+			return result, err
+		}
+	}
+
+	cmd := &parser.Command{Action: "GET", Args: []string{"key"}}
+	mockCompute.On("ProcessRequest", mock.Anything, "GET key").
+		Run(func(mock.Arguments) { calls = append(calls, "parse") }).
+		Return(cmd, nil)
+	mockStorage.On("Execute", mock.Anything, cmd).
+		Run(func(mock.Arguments) { calls = append(calls, "execute") }).
+		Return("value", nil)
+
+	db := New(mockCompute, mockStorage, zap.NewNop(), WithInterceptors(record("first"), record("second")))
+
+	result, err := db.HandleQuery(context.Background(), "GET key")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", result)
+	assert.Equal(t, []string{"parse", "first:before", "second:before", "execute", "second:after", "first:after"}, calls)
+}
+
+func TestInterceptors_ShortCircuit(t *testing.T) {
+	mockStorage := new(mocks.Storage)
+	mockCompute := new(mocks.Compute)
+	errDenied := errors.New("denied")
+
+	deny := func(ctx context.Context, request string, next Handler) (string, error) {
+		s, ok := session.FromContext(ctx)
+		if !ok || s.RemoteAddr != "10.0.0.1:5000" {
+			return "", errDenied
+		}
+
+		return next(ctx, request)
+	}
+
+	cmd := &parser.Command{Action: "GET", Args: []string{"key"}}
+	mockCompute.On("ProcessRequest", mock.Anything, "GET key").Return(cmd, nil)
+	db := New(mockCompute, mockStorage, zap.NewNop(), WithInterceptors(deny))
+
+	_, err := db.HandleQuery(context.Background(), "GET key")
+	assert.ErrorIs(t, err, errDenied)
+	mockStorage.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+
+	mockStorage.On("Execute", mock.Anything, cmd).Return("value", nil)
+
+	ctx := session.NewContext(context.Background(), session.New("10.0.0.1:5000", "127.0.0.1:3223"))
+	result, err := db.HandleQuery(ctx, "GET key")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", result)
+}
+
+func TestLoggingInterceptor(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	next := func(_ context.Context, request string) (string, error) {
+		if request == "GET missing" {
+			return "", errors.New("boom")
+		}
+
+		return "value", nil
+	}
+
+	interceptor := LoggingInterceptor(zap.New(core))
+	ctx := session.NewContext(context.Background(), session.New("10.0.0.1:5000", ""))
+
+	_, _ = interceptor(ctx, "GET key", next)
+	_, _ = interceptor(context.Background(), "GET missing", next)
+
+	entries := logs.AllUntimed()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "Request processed successfully", entries[0].Message)
+		assert.Equal(t, "10.0.0.1:5000", entries[0].ContextMap()["remote"])
+		assert.Equal(t, CodeOK, entries[0].ContextMap()["code"])
+
+		assert.Equal(t, "Request failed", entries[1].Message)
+		assert.Equal(t, CodeInternal, entries[1].ContextMap()["code"])
+	}
+}
+
+func TestAuditInterceptor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := audit.Open(path, 0)
+	require.NoError(t, err)
+
+	logger := zap.NewNop()
+	db := New(
+		compute.New(parser.New(), logger),
+		storage.New(engine.New(), logger),
+		logger,
+		WithInterceptors(AuditInterceptor(auditLog, logger)),
+	)
+
+	ctx := session.NewContext(context.Background(), session.New("10.0.0.1:5000", ""))
+	for _, request := range []string{"SET key value", "GET key", "DEL missing", "get key", "PING", "DEL key"} {
+		_, _ = db.HandleQuery(ctx, request)
+	}
+	require.NoError(t, auditLog.Close())
+
+	summary, err := audit.Verify(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Records)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var records []audit.Record
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var r audit.Record
+		require.NoError(t, json.Unmarshal([]byte(line), &r))
+		records = append(records, r)
+	}
+
+	assert.Equal(t, "SET", records[0].Command)
+	assert.Equal(t, []string{"key", "value"}, records[0].Args)
+	assert.Equal(t, "10.0.0.1:5000", records[0].Client)
+	assert.Equal(t, CodeOK, records[0].Status)
+	assert.Equal(t, "DEL", records[1].Command)
+	assert.Equal(t, CodeNotFound, records[1].Status)
+	assert.Equal(t, CodeOK, records[2].Status)
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		request  string
+		expected string
+	}{
+		{"AUTH alice secret", "AUTH alice ***"},
+		{"AUTH alice", "AUTH alice"},
+		{"ACL SETUSER bob >secret +@read >other", "ACL SETUSER bob >*** +@read >***"},
+		{"ACL SETUSER bob +@read", "ACL SETUSER bob +@read"},
+		{"SET password >secret", "SET password >secret"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, redact(tt.request))
+	}
+}
+
+func TestSlowLogInterceptor_Redact(t *testing.T) {
+	logger := zap.NewNop()
+	l := slowlog.New(time.Nanosecond, 10)
+	db := New(compute.New(parser.New(), logger), storage.New(engine.New(), logger), logger, WithInterceptors(SlowLogInterceptor(l)))
+
+	_, _ = db.HandleQuery(context.Background(), "AUTH alice secret")
+	_, _ = db.HandleQuery(context.Background(), "")
+
+	entries := l.Get(-1)
+	if assert.Len(t, entries, 2) {
+		// запрос, который не удалось разобрать, делится по пробелам
+		assert.Empty(t, entries[0].Args)
+		assert.Equal(t, []string{"AUTH", "alice", "***"}, entries[1].Args)
+	}
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"github.com/patyukin/mdb/internal/metrics"
+)
+
+// Metrics - метрики обработки запросов
+type Metrics struct {
+	commands        *metrics.CounterVec
+	errors          *metrics.CounterVec
+	parseDuration   *metrics.Histogram
+	executeDuration *metrics.HistogramVec
+}
+
+// NewMetrics создает метрики запросов и регистрирует их в registry
+func NewMetrics(registry *metrics.Registry) *Metrics {
+	m := &Metrics{
+		commands: metrics.NewCounterVec(
+			"mdb_commands_total",
+			"Number of parsed commands by name.",
+			"command",
+		),
+		errors: metrics.NewCounterVec(
+			"mdb_errors_total",
+			"Number of failed requests by error code.",
+			"code",
+		),
+		parseDuration: metrics.NewHistogram(
+			"mdb_parse_duration_seconds",
+			"Time spent parsing requests.",
+			metrics.DefBuckets,
+		),
+		executeDuration: metrics.NewHistogramVec(
+			"mdb_execute_duration_seconds",
+			"Time spent executing commands by name.",
+			metrics.DefBuckets,
+			"command",
+		),
+	}
+
+	registry.Register(m.commands, m.errors, m.parseDuration, m.executeDuration)
+
+	return m
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/patyukin/mdb/internal/database/compute"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/patyukin/mdb/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDatabase_Metrics(t *testing.T) {
+	logger := zap.NewNop()
+	registry := metrics.NewRegistry()
+	db := New(
+		compute.New(parser.New(), logger),
+		storage.New(engine.New(), logger),
+		logger,
+		WithInterceptors(MetricsInterceptor(NewMetrics(registry))),
+	)
+
+	for _, request := range []string{"SET a 1", "GET a", "GET b", "get a", "FOO a"} {
+		_, _ = db.HandleQuery(context.Background(), request)
+	}
+
+	var buf bytes.Buffer
+	_, err := registry.WriteTo(&buf)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `mdb_commands_total{command="GET"} 2`)
+	assert.Contains(t, out, `mdb_commands_total{command="SET"} 1`)
+	assert.Contains(t, out, `mdb_errors_total{code="ERR_NOT_FOUND"} 1`)
+	assert.Contains(t, out, `mdb_errors_total{code="ERR_SYNTAX"} 1`)
+	assert.Contains(t, out, `mdb_errors_total{code="ERR_UNKNOWN_COMMAND"} 1`)
+	assert.Contains(t, out, `mdb_parse_duration_seconds_count 5`)
+	assert.Contains(t, out, `mdb_execute_duration_seconds_count{command="GET"} 2`)
+}
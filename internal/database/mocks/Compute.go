@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	parser "github.com/patyukin/mdb/internal/database/compute/parser"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,9 +14,9 @@ type Compute struct {
 	mock.Mock
 }
 
-// ProcessRequest provides a mock function with given fields: request
-func (_m *Compute) ProcessRequest(request string) (*parser.Command, error) {
-	ret := _m.Called(request)
+// ProcessRequest provides a mock function with given fields: ctx, request
+func (_m *Compute) ProcessRequest(ctx context.Context, request string) (*parser.Command, error) {
+	ret := _m.Called(ctx, request)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ProcessRequest")
@@ -22,19 +24,19 @@ func (_m *Compute) ProcessRequest(request string) (*parser.Command, error) {
 
 	var r0 *parser.Command
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (*parser.Command, error)); ok {
-		return rf(request)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*parser.Command, error)); ok {
+		return rf(ctx, request)
 	}
-	if rf, ok := ret.Get(0).(func(string) *parser.Command); ok {
-		r0 = rf(request)
+	if rf, ok := ret.Get(0).(func(context.Context, string) *parser.Command); ok {
+		r0 = rf(ctx, request)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*parser.Command)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(request)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, request)
 	} else {
 		r1 = ret.Error(1)
 	}
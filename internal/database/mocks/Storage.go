@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	parser "github.com/patyukin/mdb/internal/database/compute/parser"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,9 +14,9 @@ type Storage struct {
 	mock.Mock
 }
 
-// Execute provides a mock function with given fields: _a0
-func (_m *Storage) Execute(_a0 *parser.Command) (string, error) {
-	ret := _m.Called(_a0)
+// Execute provides a mock function with given fields: _a0, _a1
+func (_m *Storage) Execute(_a0 context.Context, _a1 *parser.Command) (string, error) {
+	ret := _m.Called(_a0, _a1)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Execute")
@@ -22,17 +24,17 @@ func (_m *Storage) Execute(_a0 *parser.Command) (string, error) {
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(*parser.Command) (string, error)); ok {
-		return rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *parser.Command) (string, error)); ok {
+		return rf(_a0, _a1)
 	}
-	if rf, ok := ret.Get(0).(func(*parser.Command) string); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *parser.Command) string); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(*parser.Command) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, *parser.Command) error); ok {
+		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
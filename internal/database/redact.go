@@ -0,0 +1,48 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+)
+
+const redacted = "***"
+
+// redact скрывает пароли в запросе перед записью запроса в логи, slowlog и трассировку
+func redact(request string) string {
+	fields := strings.Fields(request)
+	if len(fields) == 0 {
+		return request
+	}
+
+	args := redactArgs(strings.ToUpper(fields[0]), fields[1:])
+	if args == nil {
+		return request
+	}
+
+	return strings.Join(append(fields[:1], args...), " ")
+}
+
+// redactArgs возвращает копию аргументов со скрытыми паролями: вторым аргументом AUTH
+// и правилами >пароль в ACL SETUSER. Если паролей нет, возвращает nil
+func redactArgs(action string, args []string) []string {
+	switch {
+	case action == parser.AUTH && len(args) > 1:
+		return append([]string{args[0]}, redacted)
+	case action == parser.ACL && len(args) > 2 && strings.EqualFold(args[0], parser.SETUSER):
+		var masked []string
+		for i, arg := range args {
+			if strings.HasPrefix(arg, ">") {
+				if masked == nil {
+					masked = append([]string(nil), args...)
+				}
+
+				masked[i] = ">" + redacted
+			}
+		}
+
+		return masked
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/trace"
+)
+
+const maxRequestIDLen = 64
+
+// requestID выбирает идентификатор запроса: из префикса "@id " в запросе, из контекста
+// или новый случайный. Возвращает запрос без префикса
+func requestID(ctx context.Context, request string) (string, string, error) {
+	if !strings.HasPrefix(request, "@") {
+		if id := trace.RequestID(ctx); id != "" {
+			return id, request, nil
+		}
+
+		return trace.NewRequestID(), request, nil
+	}
+
+	id, rest, _ := strings.Cut(request[1:], " ")
+	if !validRequestID(id) {
+		return "", request, fmt.Errorf("%w: request id must be 1-%d letters, digits or '-_.:' characters", parser.ErrInvalidArgument, maxRequestIDLen)
+	}
+
+	return id, strings.TrimLeft(rest, " "), nil
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+
+	for _, ch := range id {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-', ch == '_', ch == '.', ch == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
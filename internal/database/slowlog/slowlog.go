@@ -0,0 +1,149 @@
+package slowlog
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/patyukin/mdb/internal/session"
+)
+
+const (
+	// DefaultMaxLen - размер буфера по умолчанию
+	DefaultMaxLen = 128
+	// MaxArgs - сколько аргументов команды сохраняется в записи
+	MaxArgs = 32
+	// MaxArgLen - сколько байт каждого аргумента сохраняется в записи
+	MaxArgLen = 128
+)
+
+// Entry - запись журнала медленных запросов
+type Entry struct {
+	ID         uint64
+	Time       time.Time
+	Duration   time.Duration
+	Args       []string
+	ClientAddr string
+}
+
+// Log - кольцевой буфер медленных запросов
+type Log struct {
+	threshold atomic.Int64
+
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	size    int
+	lastID  uint64
+}
+
+// New создает журнал. Запросы, выполнявшиеся дольше threshold, сохраняются;
+// при threshold <= 0 журнал ничего не записывает
+func New(threshold time.Duration, maxLen int) *Log {
+	if maxLen <= 0 {
+		maxLen = DefaultMaxLen
+	}
+
+	l := &Log{entries: make([]Entry, maxLen)}
+	l.threshold.Store(int64(threshold))
+
+	return l
+}
+
+// SetThreshold меняет порог записи
+func (l *Log) SetThreshold(threshold time.Duration) {
+	l.threshold.Store(int64(threshold))
+}
+
+func (l *Log) Threshold() time.Duration {
+	return time.Duration(l.threshold.Load())
+}
+
+// Record сохраняет команду с аргументами args, если ее длительность превысила порог
+func (l *Log) Record(ctx context.Context, args []string, elapsed time.Duration) {
+	threshold := l.Threshold()
+	if threshold <= 0 || elapsed <= threshold {
+		return
+	}
+
+	entry := Entry{
+		Time:     time.Now(),
+		Duration: elapsed,
+		Args:     truncate(args),
+	}
+
+	if s, ok := session.FromContext(ctx); ok {
+		entry.ClientAddr = s.RemoteAddr
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastID++
+	entry.ID = l.lastID
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	l.size = min(l.size+1, len(l.entries))
+}
+
+// Get возвращает до n последних записей, начиная с самой новой. При n < 0 возвращаются все
+func (l *Log) Get(n int) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if n < 0 || n > l.size {
+		n = l.size
+	}
+
+	result := make([]Entry, 0, n)
+	for i := 1; i <= n; i++ {
+		result = append(result, l.entries[(l.next-i+len(l.entries))%len(l.entries)])
+	}
+
+	return result
+}
+
+// Len возвращает количество записей в журнале
+func (l *Log) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.size
+}
+
+// Reset очищает журнал. Нумерация записей продолжается
+func (l *Log) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	clear(l.entries)
+	l.next = 0
+	l.size = 0
+}
+
+// truncate возвращает копию аргументов, сокращенную до MaxArgs аргументов по MaxArgLen байт
+func truncate(args []string) []string {
+	extra := len(args) - MaxArgs
+	if extra > 0 {
+		args = append(args[:MaxArgs-1:MaxArgs-1], fmt.Sprintf("... (%d more arguments)", extra+1))
+	} else {
+		args = append([]string(nil), args...)
+	}
+
+	for i, arg := range args {
+		if len(arg) > MaxArgLen {
+			// аргумент обрезается по границе символа, чтобы не оставить неполную последовательность UTF-8
+			n := MaxArgLen
+			for n > 0 && !utf8.RuneStart(arg[n]) {
+				n--
+			}
+
+			args[i] = fmt.Sprintf("%s... (%d more bytes)", arg[:n], len(arg)-n)
+		}
+	}
+
+	return args
+}
@@ -0,0 +1,81 @@
+package slowlog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog_Record(t *testing.T) {
+	l := New(10*time.Millisecond, 3)
+	ctx := session.NewContext(context.Background(), session.New("10.0.0.1:5000", ""))
+
+	l.Record(ctx, []string{"GET", "fast"}, time.Millisecond)
+	// записываются только запросы, превысившие порог
+	l.Record(ctx, []string{"GET", "exact"}, 10*time.Millisecond)
+	assert.Equal(t, 0, l.Len())
+
+	for i := 1; i <= 4; i++ {
+		l.Record(ctx, []string{"SET", fmt.Sprintf("key%d", i), "value"}, time.Duration(i)*10*time.Millisecond+time.Microsecond)
+	}
+
+	assert.Equal(t, 3, l.Len())
+
+	entries := l.Get(-1)
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, uint64(4), entries[0].ID)
+		assert.Equal(t, []string{"SET", "key4", "value"}, entries[0].Args)
+		assert.Equal(t, 40*time.Millisecond+time.Microsecond, entries[0].Duration)
+		assert.Equal(t, "10.0.0.1:5000", entries[0].ClientAddr)
+		assert.Equal(t, uint64(2), entries[2].ID)
+	}
+
+	assert.Len(t, l.Get(1), 1)
+	assert.Len(t, l.Get(10), 3)
+
+	l.Reset()
+	assert.Equal(t, 0, l.Len())
+	assert.Empty(t, l.Get(-1))
+
+	l.Record(context.Background(), []string{"DEL", "key with spaces"}, time.Second)
+	entries = l.Get(-1)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, uint64(5), entries[0].ID)
+		assert.Equal(t, []string{"DEL", "key with spaces"}, entries[0].Args)
+		assert.Equal(t, "", entries[0].ClientAddr)
+	}
+}
+
+func TestLog_Disabled(t *testing.T) {
+	l := New(0, 0)
+	l.Record(context.Background(), []string{"GET", "key"}, time.Hour)
+	assert.Equal(t, 0, l.Len())
+
+	l.SetThreshold(time.Millisecond)
+	l.Record(context.Background(), []string{"GET", "key"}, time.Hour)
+	assert.Equal(t, 1, l.Len())
+}
+
+func TestTruncate(t *testing.T) {
+	args := make([]string, MaxArgs+5)
+	for i := range args {
+		args[i] = "a"
+	}
+	args[0] = strings.Repeat("x", MaxArgLen+10)
+	// двухбайтовые символы: граница MaxArgLen приходится на середину символа
+	args[1] = "x" + strings.Repeat("я", MaxArgLen/2+5)
+
+	got := truncate(args)
+	assert.Len(t, got, MaxArgs)
+	assert.Equal(t, "a", args[MaxArgs-1], "аргументы вызывающего не меняются")
+	assert.Equal(t, strings.Repeat("x", MaxArgLen)+"... (10 more bytes)", got[0])
+	assert.Equal(t, "x"+strings.Repeat("я", MaxArgLen/2-1)+"... (12 more bytes)", got[1])
+	assert.True(t, utf8.ValidString(got[1]))
+	assert.Equal(t, "... (6 more arguments)", got[MaxArgs-1])
+}
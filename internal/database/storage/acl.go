@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/patyukin/mdb/internal/auth"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/session"
+)
+
+// defaultUser - имя, под которым работают клиенты, когда аутентификация выключена
+const defaultUser = "default"
+
+// aclArgs - допустимое число аргументов каждой подкоманды ACL, включая ее имя; -1 - без ограничения
+var aclArgs = map[string][2]int{
+	parser.SETUSER: {2, -1},
+	parser.GETUSER: {2, 2},
+	parser.DELUSER: {2, -1},
+	parser.LIST:    {1, 1},
+	parser.WHOAMI:  {1, 1},
+}
+
+func (s *Storage) aclCommand(ctx context.Context, args []string) (string, error) {
+	if s.auth == nil {
+		return "", fmt.Errorf("%w: ACL is not available", parser.ErrUnknownCommand)
+	}
+
+	subcommand := strings.ToUpper(args[0])
+	if n := aclArgs[subcommand]; len(args) < n[0] || (n[1] >= 0 && len(args) > n[1]) {
+		return "", &parser.ArityError{Command: parser.ACL + " " + subcommand, Min: n[0] - 1, Max: max(n[1]-1, -1)}
+	}
+
+	switch subcommand {
+	case parser.SETUSER:
+		if err := s.auth.ApplyRules(args[1], args[2:]); err != nil {
+			return "", fmt.Errorf("failed s.auth.ApplyRules: %w", err)
+		}
+
+		return "", nil
+	case parser.GETUSER:
+		u, ok := s.auth.User(args[1])
+		if !ok {
+			return "", fmt.Errorf("%w: %s", auth.ErrUnknownUser, args[1])
+		}
+
+		password := "yes"
+		if u.PasswordHash == "" {
+			password = "no"
+		}
+
+		return strings.Join([]string{
+			"user:" + u.Name,
+			"password:" + password,
+			"commands:" + strings.Join(u.Permissions.CommandRules(), " "),
+			"keys:" + strings.Join(u.Permissions.KeyRules(), " "),
+		}, "\n"), nil
+	case parser.DELUSER:
+		n, err := s.auth.DeleteUsers(args[1:])
+		if err != nil {
+			return "", fmt.Errorf("failed s.auth.DeleteUsers: %w", err)
+		}
+
+		return fmt.Sprint(n), nil
+	case parser.LIST:
+		users := s.auth.Users()
+		lines := make([]string, 0, len(users))
+		for _, u := range users {
+			lines = append(lines, "user "+u.Name+" "+strings.Join(u.Rules(), " "))
+		}
+
+		return strings.Join(lines, "\n"), nil
+	default:
+		if sess, ok := session.FromContext(ctx); ok && sess.User() != "" {
+			return sess.User(), nil
+		}
+
+		return defaultUser, nil
+	}
+}
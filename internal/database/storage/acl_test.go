@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/patyukin/mdb/internal/auth"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/mocks"
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStorage_Execute_ACL(t *testing.T) {
+	store := auth.NewStore(auth.WithACLFile(filepath.Join(t.TempDir(), "users.acl")))
+	storage := New(new(mocks.Engine), zap.NewNop(), WithAuth(store))
+
+	sess := session.New("127.0.0.1:1000", "")
+	ctx := session.NewContext(context.Background(), sess)
+
+	tests := []struct {
+		name           string
+		args           []string
+		expectedResult string
+		expectErr      error
+	}{
+		{name: "WHOAMI без аутентификации", args: []string{"WHOAMI"}, expectedResult: "default"},
+		{name: "SETUSER", args: []string{"SETUSER", "alice", ">secret", "+@read", "~billing:*"}},
+		{name: "SETUSER без пароля", args: []string{"setuser", "bob", "+GET"}},
+		{name: "SETUSER с неверным правилом", args: []string{"SETUSER", "bob", "+@nothing"}, expectErr: auth.ErrInvalidRule},
+		{
+			name:           "GETUSER",
+			args:           []string{"GETUSER", "alice"},
+			expectedResult: "user:alice\npassword:yes\ncommands:+@read\nkeys:~billing:*",
+		},
+		{name: "GETUSER неизвестного", args: []string{"GETUSER", "carol"}, expectErr: auth.ErrUnknownUser},
+		{name: "LIST", args: []string{"LIST"}, expectedResult: "user alice +@read ~billing:*\nuser bob +GET resetkeys"},
+		{name: "DELUSER", args: []string{"DELUSER", "bob", "carol"}, expectedResult: "1"},
+		{name: "DELUSER последнего", args: []string{"DELUSER", "alice"}, expectErr: auth.ErrLastUser},
+		{name: "GETUSER без имени", args: []string{"GETUSER"}, expectErr: parser.ErrWrongArity},
+		{name: "LIST с аргументом", args: []string{"LIST", "x"}, expectErr: parser.ErrWrongArity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := storage.Execute(ctx, &parser.Command{Action: parser.ACL, Args: tt.args})
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedResult, result)
+		})
+	}
+
+	sess.SetUser("alice")
+	result, err := storage.Execute(ctx, &parser.Command{Action: parser.ACL, Args: []string{"WHOAMI"}})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", result)
+
+	_, err = New(new(mocks.Engine), zap.NewNop()).Execute(ctx, &parser.Command{Action: parser.ACL, Args: []string{"LIST"}})
+	assert.ErrorIs(t, err, parser.ErrUnknownCommand)
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/patyukin/mdb/internal/auth"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/patyukin/mdb/internal/trace"
+	"go.uber.org/zap"
+)
+
+// WithAuth подключает хранилище учетных записей для команд AUTH и ACL
+func WithAuth(store *auth.Store) Option {
+	return func(s *Storage) {
+		s.auth = store
+	}
+}
+
+// authCommand проверяет пароль и закрепляет пользователя за сессией
+func (s *Storage) authCommand(ctx context.Context, args []string) (string, error) {
+	if s.auth == nil || !s.auth.Enabled() {
+		return "", fmt.Errorf("%w: no users are configured", parser.ErrInvalidArgument)
+	}
+
+	sess, ok := session.FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("%w: no session", parser.ErrInvalidArgument)
+	}
+
+	user := args[0]
+	if err := s.auth.Authenticate(user, args[1], sess); err != nil {
+		trace.Logger(ctx, s.logger).Warn(
+			"Authentication failed",
+			zap.String("user", user),
+			zap.String("client", sess.RemoteAddr),
+			zap.Error(err),
+		)
+
+		return "", fmt.Errorf("failed s.auth.Authenticate: %w", err)
+	}
+
+	sess.SetUser(user)
+
+	return "", nil
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/patyukin/mdb/internal/cdc"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/patyukin/mdb/internal/trace"
+)
+
+// WithCDC записывает изменения ключей в журнал изменений и включает команду CDC
+func WithCDC(l *cdc.Log) Option {
+	return func(s *Storage) {
+		s.changes = l
+	}
+}
+
+// mutate выполняет изменение ключей. plan проверяет текущие значения и возвращает
+// изменения, которые сначала записываются в журнал и только после успешной записи
+// применяются к движку, поэтому журнал не расходится с данными. Изменения выполняются
+// под writeMu: значения не меняются между проверкой и применением, а порядок
+// смещений совпадает с порядком изменений. Изменения, которые могут увеличить размер
+// данных, отклоняются при достижении предела памяти движка
+func (s *Storage) mutate(ctx context.Context, plan func() ([]cdc.Change, error)) error {
+	if s.readOnly.Load() {
+		return ErrReadOnly
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	changes, err := plan()
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+
+	if grows(changes) {
+		if err = s.engine.CheckMemory(); err != nil {
+			return fmt.Errorf("failed s.engine.CheckMemory: %w", err)
+		}
+	}
+
+	if s.changes != nil {
+		end := trace.StartSpan(ctx, "persist")
+		_, err = s.changes.Append(changes...)
+		end()
+		if err != nil {
+			return fmt.Errorf("failed s.changes.Append: %w", err)
+		}
+	}
+
+	for _, c := range changes {
+		if err = s.apply(ctx, c); err != nil {
+			return err
+		}
+
+		// имена операций журнала совпадают с типами событий keyspace
+		s.notify(c.Op, c.Key)
+	}
+
+	return nil
+}
+
+// grows сообщает, есть ли среди изменений добавляющие данные. Удаления выполняются
+// и при достижении предела памяти, чтобы его можно было освободить
+func grows(changes []cdc.Change) bool {
+	for _, c := range changes {
+		switch c.Op {
+		case cdc.OpSet, cdc.OpHSet, cdc.OpLPush, cdc.OpRPush, cdc.OpSAdd, cdc.OpZAdd:
+			return true
+		}
+	}
+
+	return false
+}
+
+// apply применяет изменение к движку. plan проверяет изменения под той же блокировкой,
+// поэтому применение проверенного изменения не завершается ошибкой
+func (s *Storage) apply(ctx context.Context, c cdc.Change) error {
+	switch c.Op {
+	case cdc.OpSet:
+		s.engine.Set(ctx, c.Key, c.Value)
+	case cdc.OpDel:
+		// коллекция удаляется вместе с последним элементом уже при применении предыдущего изменения
+		if err := s.engine.Delete(ctx, c.Key); err != nil && !errors.Is(err, engine.ErrNotFound) {
+			return fmt.Errorf("failed s.engine.Delete, err: %w", err)
+		}
+	case cdc.OpHSet, cdc.OpHDel:
+		return updateCollection(ctx, s.engine, c.Key, engine.NewHash, func(h *engine.Hash) error {
+			applyHash(h, c)
+			return nil
+		})
+	case cdc.OpLPush, cdc.OpRPush, cdc.OpLPop, cdc.OpRPop, cdc.OpLTrim:
+		return updateCollection(ctx, s.engine, c.Key, engine.NewList, func(l *engine.List) error {
+			return applyList(l, c)
+		})
+	case cdc.OpSAdd, cdc.OpSRem:
+		return updateCollection(ctx, s.engine, c.Key, engine.NewSet, func(set *engine.Set) error {
+			applySet(set, c)
+			return nil
+		})
+	case cdc.OpZAdd, cdc.OpZRem:
+		return updateCollection(ctx, s.engine, c.Key, engine.NewSortedSet, func(z *engine.SortedSet) error {
+			return applySortedSet(z, c)
+		})
+	}
+
+	return nil
+}
+
+// cdcCommand возвращает изменения по одному в строке: смещение, операция, ключ, значение
+// или элементы коллекции.
+// Пустой результат означает, что новых изменений пока нет
+func (s *Storage) cdcCommand(args []string) (string, error) {
+	if s.changes == nil {
+		return "", fmt.Errorf("%w: change data capture is disabled", parser.ErrInvalidArgument)
+	}
+
+	from, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil || from == 0 {
+		return "", fmt.Errorf("%w: offset must be a positive integer: %s", parser.ErrInvalidArgument, args[1])
+	}
+
+	count, err := strconv.Atoi(args[2])
+	if err != nil || count <= 0 {
+		return "", fmt.Errorf("%w: count must be a positive integer: %s", parser.ErrInvalidArgument, args[2])
+	}
+
+	changes, err := s.changes.Read(from, count)
+	if err != nil {
+		return "", fmt.Errorf("failed s.changes.Read: %w", err)
+	}
+
+	var b strings.Builder
+	for _, c := range changes {
+		fmt.Fprintf(&b, "%d %s %s", c.Offset, c.Op, parser.Quote(c.Key))
+		if c.Op == cdc.OpSet {
+			fmt.Fprintf(&b, " %s", parser.Quote(c.Value))
+		}
+		for _, arg := range c.Args {
+			fmt.Fprintf(&b, " %s", parser.Quote(arg))
+		}
+		b.WriteByte('\n')
+	}
+
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/patyukin/mdb/internal/cdc"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/patyukin/mdb/internal/database/storage/mocks"
+	"github.com/patyukin/mdb/internal/keyspace"
+	"github.com/patyukin/mdb/internal/pubsub"
+	"github.com/patyukin/mdb/internal/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStorage_Execute_CDC(t *testing.T) {
+	changes, err := cdc.Open(filepath.Join(t.TempDir(), "cdc.log"), 0, 0)
+	require.NoError(t, err)
+	defer func() { _ = changes.Close() }()
+
+	engine := new(mocks.Engine)
+	engine.On("CheckMemory").Return(nil).Once()
+	engine.On("Set", mock.Anything, "key1", "value 1").Once()
+	engine.On("View", mock.Anything, "key1", mock.Anything).Return(nil).Once()
+	engine.On("Delete", mock.Anything, "key1").Return(nil).Once()
+	engine.On("View", mock.Anything, "missing", mock.Anything).Return(errors.New("key not found")).Once()
+	storage := New(engine, zap.NewNop(), WithCDC(changes))
+
+	tr := trace.New("cdc")
+	ctx := trace.NewContext(context.Background(), tr)
+	for _, command := range []*parser.Command{
+		{Action: SET, Args: []string{"key1", "value 1"}},
+		{Action: DELETE, Args: []string{"key1"}},
+		{Action: DELETE, Args: []string{"missing"}},
+	} {
+		_, _ = storage.Execute(ctx, command)
+	}
+
+	persisted := 0
+	for _, span := range tr.Spans() {
+		if span.Name == "persist" {
+			persisted++
+		}
+	}
+	// неудачное удаление в журнал не попадает
+	assert.Equal(t, 2, persisted)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+		err      error
+	}{
+		{name: "Все изменения", args: []string{"READ", "1", "10"}, expected: "1 set key1 \"value 1\"\n2 del key1"},
+		{name: "Продолжение с последнего смещения", args: []string{"read", "2", "10"}, expected: "2 del key1"},
+		{name: "Новых изменений нет", args: []string{"READ", "3", "10"}, expected: ""},
+		{name: "Нулевое смещение", args: []string{"READ", "0", "10"}, err: parser.ErrInvalidArgument},
+		{name: "Нулевое количество", args: []string{"READ", "1", "0"}, err: parser.ErrInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := storage.Execute(context.Background(), &parser.Command{Action: parser.CDC, Args: tt.args})
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+
+	engine.AssertExpectations(t)
+
+	_, err = New(new(mocks.Engine), zap.NewNop()).Execute(ctx, &parser.Command{Action: parser.CDC, Args: []string{"READ", "1", "1"}})
+	assert.ErrorIs(t, err, parser.ErrInvalidArgument)
+}
+
+func TestStorage_Execute_CDCAppendFailure(t *testing.T) {
+	changes, err := cdc.Open(filepath.Join(t.TempDir(), "cdc.log"), 0, 0)
+	require.NoError(t, err)
+
+	storage := New(engine.New(), zap.NewNop(), WithCDC(changes))
+	ctx := context.Background()
+	_, err = storage.Execute(ctx, &parser.Command{Action: SET, Args: []string{"key1", "before"}})
+	require.NoError(t, err)
+
+	// закрытый журнал не принимает записи
+	require.NoError(t, changes.Close())
+
+	_, err = storage.Execute(ctx, &parser.Command{Action: SET, Args: []string{"key1", "after"}})
+	assert.ErrorIs(t, err, os.ErrClosed)
+	_, err = storage.Execute(ctx, &parser.Command{Action: DELETE, Args: []string{"key1"}})
+	assert.ErrorIs(t, err, os.ErrClosed)
+
+	// изменения, не попавшие в журнал, не применяются
+	value, err := storage.Execute(ctx, &parser.Command{Action: GET, Args: []string{"key1"}})
+	require.NoError(t, err)
+	assert.Equal(t, "before", value)
+}
+
+// newRecordingStorage возвращает хранилище с журналом изменений и подписчика на все события keyspace
+func newRecordingStorage(t *testing.T) (*Storage, *pubsub.Subscriber) {
+	t.Helper()
+
+	changes, err := cdc.Open(filepath.Join(t.TempDir(), "cdc.log"), 0, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = changes.Close() })
+
+	broker := pubsub.NewBroker(100)
+	sub := broker.NewSubscriber()
+	broker.PSubscribe(sub, keyspace.KeyeventPrefix+"*")
+	notifier := keyspace.NewNotifier(broker, keyspace.ClassKeyevent|keyspace.ClassAll)
+
+	return New(engine.New(), zap.NewNop(), WithCDC(changes), WithNotifier(notifier)), sub
+}
+
+// assertRecorded выполняет команды и проверяет журнал изменений и события keyspace в виде "тип ключ"
+func assertRecorded(t *testing.T, storage *Storage, sub *pubsub.Subscriber, commands []*parser.Command, changes string, events []string) {
+	t.Helper()
+
+	ctx := context.Background()
+	for _, command := range commands {
+		_, err := storage.Execute(ctx, command)
+		require.NoError(t, err, command.Action)
+	}
+
+	result, err := storage.Execute(ctx, &parser.Command{Action: parser.CDC, Args: []string{"READ", "1", "100"}})
+	require.NoError(t, err)
+	assert.Equal(t, changes, result)
+
+	var received []string
+	for _, m := range sub.Drain() {
+		received = append(received, strings.TrimPrefix(m.Channel, keyspace.KeyeventPrefix)+" "+m.Payload)
+	}
+	assert.Equal(t, events, received)
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/patyukin/mdb/internal/cdc"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+)
+
+// collection - значение, состоящее из элементов. Пустая коллекция не хранится:
+// ключ удаляется вместе с последним элементом
+type collection interface {
+	engine.Value
+	Len() int
+}
+
+// typedValue приводит значение ключа к типу T; для отсутствующего ключа возвращает нулевое значение
+func typedValue[T engine.Value](key string, v engine.Value) (T, error) {
+	var zero T
+	if v == nil {
+		return zero, nil
+	}
+
+	typed, ok := v.(T)
+	if !ok {
+		return zero, fmt.Errorf("'%s' holds %s - %w", key, v.Type(), engine.ErrWrongType)
+	}
+
+	return typed, nil
+}
+
+// viewCollection вызывает fn с коллекцией ключа. Отсутствующий ключ передается как пустая коллекция
+func viewCollection[T collection](ctx context.Context, e Engine, key string, empty func() T, fn func(c T) error) error {
+	found := false
+	err := e.View(ctx, key, func(v engine.Value) error {
+		found = true
+		c, err := typedValue[T](key, v)
+		if err != nil {
+			return err
+		}
+
+		return fn(c)
+	})
+	if !found && errors.Is(err, engine.ErrNotFound) {
+		return fn(empty())
+	}
+
+	return err
+}
+
+// mutateCollection вызывает plan с коллекцией ключа и выполняет возвращенные изменения
+// через mutate. Отсутствующий ключ передается как пустая коллекция; plan не должен менять ее
+func mutateCollection[T collection](ctx context.Context, s *Storage, key string, empty func() T, plan func(c T) ([]cdc.Change, error)) error {
+	return s.mutate(ctx, func() ([]cdc.Change, error) {
+		var changes []cdc.Change
+		err := viewCollection(ctx, s.engine, key, empty, func(c T) error {
+			var err error
+			changes, err = plan(c)
+			return err
+		})
+
+		return changes, err
+	})
+}
+
+// removal возвращает изменение op, удаляющее из коллекции c ключа key элементы removed,
+// и удаление ключа, если в коллекции не остается элементов
+func removal(op, key string, c collection, removed []string) []cdc.Change {
+	if len(removed) == 0 {
+		return nil
+	}
+
+	changes := []cdc.Change{{Op: op, Key: key, Args: removed}}
+	if len(removed) == c.Len() {
+		changes = append(changes, cdc.Change{Op: cdc.OpDel, Key: key})
+	}
+
+	return changes
+}
+
+// updateCollection вызывает fn с коллекцией ключа, создавая ее при необходимости
+func updateCollection[T collection](ctx context.Context, e Engine, key string, create func() T, fn func(c T) error) error {
+	return e.Update(ctx, key, func(v engine.Value) (engine.Value, error) {
+		var c T
+		var err error
+		if v == nil {
+			c = create()
+		} else if c, err = typedValue[T](key, v); err != nil {
+			return nil, err
+		}
+
+		if err = fn(c); err != nil {
+			return nil, err
+		}
+
+		if c.Len() == 0 {
+			return nil, nil
+		}
+
+		return c, nil
+	})
+}
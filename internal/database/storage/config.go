@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/patyukin/mdb/internal/config"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+)
+
+// WithConfig подключает менеджер конфигурации для команды CONFIG
+func WithConfig(m *config.Manager) Option {
+	return func(s *Storage) {
+		s.config = m
+	}
+}
+
+// configArgs - число аргументов каждой подкоманды CONFIG, включая ее имя
+var configArgs = map[string]int{
+	parser.GET:     2,
+	parser.SET:     3,
+	parser.REWRITE: 1,
+}
+
+func (s *Storage) configCommand(args []string) (string, error) {
+	if s.config == nil {
+		return "", fmt.Errorf("%w: config is not available", parser.ErrUnknownCommand)
+	}
+
+	subcommand := strings.ToUpper(args[0])
+	if n := configArgs[subcommand]; len(args) != n {
+		return "", &parser.ArityError{Command: parser.CONFIG + " " + subcommand, Min: n - 1, Max: n - 1}
+	}
+
+	switch subcommand {
+	case parser.GET:
+		params, err := s.config.Get(args[1])
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", parser.ErrInvalidArgument, err)
+		}
+
+		lines := make([]string, 0, len(params))
+		for _, param := range params {
+			lines = append(lines, param.Name+":"+param.Value)
+		}
+
+		return strings.Join(lines, "\n"), nil
+	case parser.SET:
+		if err := s.config.Set(args[1], args[2]); err != nil {
+			return "", fmt.Errorf("failed s.config.Set: %w", err)
+		}
+
+		return "", nil
+	default:
+		if err := s.config.Rewrite(); err != nil {
+			return "", fmt.Errorf("failed s.config.Rewrite: %w", err)
+		}
+
+		return "", nil
+	}
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/patyukin/mdb/internal/config"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStorage_Execute_Config(t *testing.T) {
+	m := config.NewManager("", config.Default())
+
+	storage := New(new(mocks.Engine), zap.NewNop(), WithConfig(m))
+	execute := func(args ...string) (string, error) {
+		return storage.Execute(context.Background(), &parser.Command{Action: "CONFIG", Args: args})
+	}
+
+	result, err := execute("GET", "slowlog.*")
+	require.NoError(t, err)
+	assert.Equal(t, "slowlog.max_len:128\nslowlog.threshold:10ms", result)
+
+	result, err = execute("set", "slowlog.threshold", "20ms")
+	require.NoError(t, err)
+	assert.Equal(t, "", result)
+	assert.Equal(t, "20ms", m.Current().SlowLog.Threshold.String())
+
+	_, err = execute("SET", "network.address", "127.0.0.1:4000")
+	assert.ErrorIs(t, err, config.ErrStaticParam)
+
+	_, err = execute("GET")
+	assert.ErrorIs(t, err, parser.ErrWrongArity)
+
+	_, err = execute("GET", "[")
+	assert.ErrorIs(t, err, parser.ErrInvalidArgument)
+
+	_, err = execute("REWRITE")
+	assert.ErrorIs(t, err, config.ErrNoConfigFile)
+}
+
+func TestStorage_Execute_ConfigNotConfigured(t *testing.T) {
+	storage := New(new(mocks.Engine), zap.NewNop())
+
+	_, err := storage.Execute(context.Background(), &parser.Command{Action: "CONFIG", Args: []string{"REWRITE"}})
+	assert.ErrorIs(t, err, parser.ErrUnknownCommand)
+}
@@ -1,51 +1,248 @@
 package engine
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
+
+	"github.com/patyukin/mdb/internal/trace"
+	"go.uber.org/zap"
 )
 
 var (
-	ErrNotFound = fmt.Errorf("key not found")
+	ErrNotFound    = errors.New("key not found")
+	ErrWrongType   = errors.New("operation against a key holding the wrong kind of value")
+	ErrOutOfMemory = errors.New("out of memory")
 )
 
+// Stats - статистика движка
+type Stats struct {
+	Keys       int
+	KeyBytes   int64
+	ValueBytes int64
+	Gets       uint64
+	Sets       uint64
+	Deletes    uint64
+	Hits       uint64
+	Misses     uint64
+}
+
 type Engine struct {
+	logger *zap.Logger
+
 	mu   sync.RWMutex
-	data map[string]string
+	data map[string]Value
+
+	keyBytes   int64
+	valueBytes int64
+	// maxMemory - предел размера ключей и значений в байтах, 0 - без ограничения
+	maxMemory atomic.Int64
+
+	gets    atomic.Uint64
+	sets    atomic.Uint64
+	deletes atomic.Uint64
+	hits    atomic.Uint64
+	misses  atomic.Uint64
 }
 
-func New() *Engine {
-	return &Engine{
-		data: make(map[string]string),
+// Option настраивает Engine
+type Option func(*Engine)
+
+// WithLogger задает логгер движка. Записи дополняются идентификатором запроса из контекста
+func WithLogger(l *zap.Logger) Option {
+	return func(e *Engine) {
+		e.logger = l
 	}
 }
 
-func (e *Engine) Set(key string, value string) {
+func New(options ...Option) *Engine {
+	e := &Engine{
+		logger: zap.NewNop(),
+		data:   make(map[string]Value),
+	}
+
+	for _, option := range options {
+		option(e)
+	}
+
+	return e
+}
+
+// Set записывает строковое значение, заменяя значение любого типа
+func (e *Engine) Set(ctx context.Context, key string, value string) {
+	defer trace.StartSpan(ctx, "engine.set")()
+	e.sets.Add(1)
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	e.data[key] = value
+
+	e.store(key, e.data[key], String(value))
+	trace.Logger(ctx, e.logger).Debug("Key stored", zap.String("key", key), zap.String("type", string(TypeString)))
 }
 
-func (e *Engine) Get(key string) (string, error) {
+// Get возвращает строковое значение ключа
+func (e *Engine) Get(ctx context.Context, key string) (string, error) {
+	defer trace.StartSpan(ctx, "engine.get")()
+	e.gets.Add(1)
+
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 	value, exists := e.data[key]
 	if !exists {
+		e.misses.Add(1)
+		trace.Logger(ctx, e.logger).Debug("Key not found", zap.String("key", key))
 		return "", fmt.Errorf("'%s' - %w", key, ErrNotFound)
 	}
 
-	return value, nil
+	e.hits.Add(1)
+
+	s, ok := value.(String)
+	if !ok {
+		return "", fmt.Errorf("'%s' holds %s - %w", key, value.Type(), ErrWrongType)
+	}
+
+	return string(s), nil
 }
 
-func (e *Engine) Delete(key string) error {
+func (e *Engine) Delete(ctx context.Context, key string) error {
+	defer trace.StartSpan(ctx, "engine.delete")()
+	e.deletes.Add(1)
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	_, ok := e.data[key]
+	value, ok := e.data[key]
 	if !ok {
 		return fmt.Errorf("'%s' - %w", key, ErrNotFound)
 	}
 
-	delete(e.data, key)
+	e.store(key, value, nil)
+	trace.Logger(ctx, e.logger).Debug("Key deleted", zap.String("key", key))
+
+	return nil
+}
+
+// View вызывает fn со значением ключа под блокировкой чтения. Значение нельзя
+// сохранять и менять после возврата из fn. Для отсутствующего ключа возвращается ErrNotFound
+func (e *Engine) View(ctx context.Context, key string, fn func(v Value) error) error {
+	defer trace.StartSpan(ctx, "engine.view")()
+	e.gets.Add(1)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	value, exists := e.data[key]
+	if !exists {
+		e.misses.Add(1)
+		trace.Logger(ctx, e.logger).Debug("Key not found", zap.String("key", key))
+		return fmt.Errorf("'%s' - %w", key, ErrNotFound)
+	}
+
+	e.hits.Add(1)
+
+	return fn(value)
+}
+
+// Update вызывает fn с текущим значением ключа, nil для отсутствующего, под блокировкой
+// записи. fn может изменить значение на месте и возвращает значение, которое нужно
+// сохранить; nil удаляет ключ. При ошибке fn значение не сохраняется
+func (e *Engine) Update(ctx context.Context, key string, fn func(v Value) (Value, error)) error {
+	defer trace.StartSpan(ctx, "engine.update")()
+	e.sets.Add(1)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	old, exists := e.data[key]
+	// fn может изменить значение на месте, поэтому прежний размер запоминается заранее
+	var oldSize int64
+	if exists {
+		oldSize = old.Size()
+	}
+
+	value, err := fn(old)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		e.keyBytes -= int64(len(key))
+		e.valueBytes -= oldSize
+		delete(e.data, key)
+	}
+
+	e.store(key, nil, value)
+	if value == nil {
+		trace.Logger(ctx, e.logger).Debug("Key deleted", zap.String("key", key))
+	} else {
+		trace.Logger(ctx, e.logger).Debug("Key stored", zap.String("key", key), zap.String("type", string(value.Type())))
+	}
 
 	return nil
 }
+
+// store заменяет значение ключа old на value с учетом статистики; nil удаляет ключ
+func (e *Engine) store(key string, old, value Value) {
+	if old != nil {
+		e.keyBytes -= int64(len(key))
+		e.valueBytes -= old.Size()
+		delete(e.data, key)
+	}
+
+	if value != nil {
+		e.keyBytes += int64(len(key))
+		e.valueBytes += value.Size()
+		e.data[key] = value
+	}
+}
+
+// SetMaxMemory задает предел размера ключей и значений в байтах, 0 снимает ограничение
+func (e *Engine) SetMaxMemory(bytes int64) {
+	e.maxMemory.Store(bytes)
+}
+
+// CheckMemory возвращает ErrOutOfMemory, если размер ключей и значений достиг предела.
+// Проверка выполняется перед изменениями, которые могут увеличить размер данных
+func (e *Engine) CheckMemory() error {
+	limit := e.maxMemory.Load()
+	if limit <= 0 {
+		return nil
+	}
+
+	e.mu.RLock()
+	used := e.keyBytes + e.valueBytes
+	e.mu.RUnlock()
+
+	if used >= limit {
+		return fmt.Errorf("used %d of %d bytes - %w", used, limit, ErrOutOfMemory)
+	}
+
+	return nil
+}
+
+// Len возвращает количество ключей
+func (e *Engine) Len() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return len(e.data)
+}
+
+// Stats возвращает текущую статистику движка
+func (e *Engine) Stats() Stats {
+	e.mu.RLock()
+	stats := Stats{
+		Keys:       len(e.data),
+		KeyBytes:   e.keyBytes,
+		ValueBytes: e.valueBytes,
+	}
+	e.mu.RUnlock()
+
+	stats.Gets = e.gets.Load()
+	stats.Sets = e.sets.Load()
+	stats.Deletes = e.deletes.Load()
+	stats.Hits = e.hits.Load()
+	stats.Misses = e.misses.Load()
+
+	return stats
+}
@@ -1,19 +1,23 @@
 package engine
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"testing"
 )
 
 func TestEngine_Set_Get(t *testing.T) {
 	e := New()
+	ctx := context.Background()
 	key := "testKey"
 	value := "testValue"
 
-	e.Set(key, value)
+	e.Set(ctx, key, value)
 
-	got, err := e.Get(key)
+	got, err := e.Get(ctx, key)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -25,9 +29,10 @@ func TestEngine_Set_Get(t *testing.T) {
 
 func TestEngine_Get_NotFound(t *testing.T) {
 	e := New()
+	ctx := context.Background()
 	key := "nonExistentKey"
 
-	_, err := e.Get(key)
+	_, err := e.Get(ctx, key)
 	if err == nil {
 		t.Fatalf("expected error for non-existent key, got nil")
 	}
@@ -39,15 +44,16 @@ func TestEngine_Get_NotFound(t *testing.T) {
 
 func TestEngine_Delete(t *testing.T) {
 	e := New()
+	ctx := context.Background()
 	key := "testKey"
 	value := "testValue"
 
-	e.Set(key, value)
-	if err := e.Delete(key); err != nil {
+	e.Set(ctx, key, value)
+	if err := e.Delete(ctx, key); err != nil {
 		t.Fatalf("expected error after deleting key, got nil")
 	}
 
-	_, err := e.Get(key)
+	_, err := e.Get(ctx, key)
 	if err == nil {
 		t.Fatalf("expected error after deleting key, got nil")
 	}
@@ -59,17 +65,18 @@ func TestEngine_Delete(t *testing.T) {
 
 func TestEngine_Del(t *testing.T) {
 	e := New()
+	ctx := context.Background()
 	key := "testKey"
 	value := "testValue"
 
-	e.Set(key, value)
+	e.Set(ctx, key, value)
 
-	err := e.Delete(key)
+	err := e.Delete(ctx, key)
 	if err != nil {
 		t.Fatalf("expected no error when deleting existing key, got %v", err)
 	}
 
-	_, err = e.Get(key)
+	_, err = e.Get(ctx, key)
 	if err == nil {
 		t.Fatalf("expected error after deleting key, got nil")
 	}
@@ -78,7 +85,7 @@ func TestEngine_Del(t *testing.T) {
 		t.Fatalf("expected ErrNotFound after deletion, got %v", err)
 	}
 
-	err = e.Delete("nonExistentKey")
+	err = e.Delete(ctx, "nonExistentKey")
 	if err == nil {
 		t.Fatalf("expected error when deleting non-existent key, got nil")
 	}
@@ -90,22 +97,23 @@ func TestEngine_Del(t *testing.T) {
 
 func TestEngine_ConcurrentAccess(t *testing.T) {
 	e := New()
+	ctx := context.Background()
 	key := "concurrentKey"
 	value := "initial"
 
-	e.Set(key, value)
+	e.Set(ctx, key, value)
 
 	done := make(chan bool)
 
 	for i := 0; i < 100; i++ {
 		go func(i int) {
 			if i%2 == 0 {
-				_, err := e.Get(key)
+				_, err := e.Get(ctx, key)
 				if err != nil && !errors.Is(err, ErrNotFound) {
 					t.Errorf("unexpected error during Get: %v", err)
 				}
 			} else {
-				e.Set(key, fmt.Sprintf("value%d", i))
+				e.Set(ctx, key, fmt.Sprintf("value%d", i))
 			}
 			done <- true
 		}(i)
@@ -115,7 +123,7 @@ func TestEngine_ConcurrentAccess(t *testing.T) {
 		<-done
 	}
 
-	finalValue, err := e.Get(key)
+	finalValue, err := e.Get(ctx, key)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -124,3 +132,278 @@ func TestEngine_ConcurrentAccess(t *testing.T) {
 		t.Fatalf("expected non-empty final value, got empty string")
 	}
 }
+
+func TestEngine_Len(t *testing.T) {
+	e := New()
+	ctx := context.Background()
+	if e.Len() != 0 {
+		t.Fatalf("expected empty engine, got %d keys", e.Len())
+	}
+
+	e.Set(ctx, "a", "1")
+	e.Set(ctx, "b", "2")
+	e.Set(ctx, "a", "3")
+	if e.Len() != 2 {
+		t.Fatalf("expected 2 keys, got %d", e.Len())
+	}
+
+	if err := e.Delete(ctx, "a"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if e.Len() != 1 {
+		t.Fatalf("expected 1 key, got %d", e.Len())
+	}
+}
+
+func TestEngine_Stats(t *testing.T) {
+	e := New()
+	ctx := context.Background()
+
+	e.Set(ctx, "key1", "value")
+	e.Set(ctx, "key2", "v")
+	e.Set(ctx, "key1", "longer value")
+	_, _ = e.Get(ctx, "key1")
+	_, _ = e.Get(ctx, "missing")
+	_ = e.Delete(ctx, "key2")
+	_ = e.Delete(ctx, "missing")
+
+	expected := Stats{
+		Keys:       1,
+		KeyBytes:   4,
+		ValueBytes: 12,
+		Gets:       2,
+		Sets:       3,
+		Deletes:    2,
+		Hits:       1,
+		Misses:     1,
+	}
+
+	if got := e.Stats(); got != expected {
+		t.Fatalf("expected stats %+v, got %+v", expected, got)
+	}
+}
+
+func TestEngine_CheckMemory(t *testing.T) {
+	e := New()
+	ctx := context.Background()
+	e.Set(ctx, "key", "value")
+
+	if err := e.CheckMemory(); err != nil {
+		t.Fatalf("expected no limit by default, got %v", err)
+	}
+
+	e.SetMaxMemory(9)
+	if err := e.CheckMemory(); err != nil {
+		t.Fatalf("expected no error below the limit, got %v", err)
+	}
+
+	e.Set(ctx, "key", "value!")
+	if err := e.CheckMemory(); !errors.Is(err, ErrOutOfMemory) {
+		t.Fatalf("expected ErrOutOfMemory at the limit, got %v", err)
+	}
+
+	e.SetMaxMemory(0)
+	if err := e.CheckMemory(); err != nil {
+		t.Fatalf("expected no error without a limit, got %v", err)
+	}
+}
+
+func TestEngine_Update_Hash(t *testing.T) {
+	e := New()
+	ctx := context.Background()
+
+	err := e.Update(ctx, "user:1", func(v Value) (Value, error) {
+		if v != nil {
+			t.Fatalf("expected no value for a new key, got %v", v)
+		}
+
+		h := NewHash()
+		h.Set("name", "alice")
+		h.Set("age", "30")
+
+		return h, nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if got := e.Stats(); got.Keys != 1 || got.KeyBytes != 6 || got.ValueBytes != 14 {
+		t.Fatalf("unexpected stats after update: %+v", got)
+	}
+
+	// изменение на месте учитывается в статистике
+	err = e.Update(ctx, "user:1", func(v Value) (Value, error) {
+		v.(*Hash).Delete("age")
+		return v, nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if got := e.Stats().ValueBytes; got != 9 {
+		t.Fatalf("expected 9 value bytes, got %d", got)
+	}
+
+	err = e.View(ctx, "user:1", func(v Value) error {
+		if v.Type() != TypeHash {
+			t.Fatalf("expected hash, got %s", v.Type())
+		}
+
+		if name, ok := v.(*Hash).Get("name"); !ok || name != "alice" {
+			t.Fatalf("expected name alice, got %q", name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err = e.Get(ctx, "user:1"); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("expected ErrWrongType for GET on a hash, got %v", err)
+	}
+
+	// nil удаляет ключ
+	if err = e.Update(ctx, "user:1", func(Value) (Value, error) { return nil, nil }); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err = e.View(ctx, "user:1", func(Value) error { return nil }); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after removal, got %v", err)
+	}
+
+	if got := e.Stats(); got.Keys != 0 || got.KeyBytes != 0 || got.ValueBytes != 0 {
+		t.Fatalf("expected empty stats, got %+v", got)
+	}
+}
+
+func TestList(t *testing.T) {
+	l := NewList()
+
+	// вставка с обоих концов с ростом буфера через границу кольца
+	for i := 0; i < 10; i++ {
+		l.PushBack(fmt.Sprint(i))
+		l.PushFront(fmt.Sprint(-i - 1))
+	}
+
+	if l.Len() != 20 {
+		t.Fatalf("expected 20 elements, got %d", l.Len())
+	}
+
+	if got := fmt.Sprint(l.Range(0, 2), l.Range(-3, -1)); got != "[-10 -9 -8] [7 8 9]" {
+		t.Fatalf("unexpected ranges: %s", got)
+	}
+
+	if got := l.Range(5, 2); len(got) != 0 {
+		t.Fatalf("expected empty range, got %v", got)
+	}
+
+	if value, ok := l.Index(-1); !ok || value != "9" {
+		t.Fatalf("expected last element 9, got %q", value)
+	}
+
+	if _, ok := l.Index(20); ok {
+		t.Fatalf("expected no element past the end")
+	}
+
+	l.Trim(8, -9)
+	if got := fmt.Sprint(l.Range(0, -1)); got != "[-2 -1 0 1]" {
+		t.Fatalf("unexpected list after trim: %s", got)
+	}
+
+	if l.Size() != 6 {
+		t.Fatalf("expected 6 bytes, got %d", l.Size())
+	}
+
+	for _, expected := range []string{"-2", "1", "-1", "0"} {
+		var value string
+		if expected == "1" || expected == "0" {
+			value, _ = l.PopBack()
+		} else {
+			value, _ = l.PopFront()
+		}
+
+		if value != expected {
+			t.Fatalf("expected %q, got %q", expected, value)
+		}
+	}
+
+	if _, ok := l.PopFront(); ok || l.Len() != 0 || l.Size() != 0 {
+		t.Fatalf("expected empty list, got %d elements of %d bytes", l.Len(), l.Size())
+	}
+}
+
+func TestSortedSet(t *testing.T) {
+	z := NewSortedSet()
+
+	// оценки повторяются, чтобы проверить порядок элементов с равными оценками
+	expected := make(map[string]float64)
+	for i := 0; i < 500; i++ {
+		member := fmt.Sprintf("m%03d", (i*7919)%500)
+		score := float64(i % 37)
+		z.Add(member, score)
+		expected[member] = score
+	}
+
+	// смена оценки и удаление перестраивают список
+	for i := 0; i < 500; i += 3 {
+		member := fmt.Sprintf("m%03d", i)
+		if i%2 == 0 {
+			z.Remove(member)
+			delete(expected, member)
+		} else {
+			z.Add(member, -float64(i))
+			expected[member] = -float64(i)
+		}
+	}
+
+	members := make([]ScoredMember, 0, len(expected))
+	for member, score := range expected {
+		members = append(members, ScoredMember{Member: member, Score: score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return less(members[i].Member, members[i].Score, members[j].Member, members[j].Score)
+	})
+
+	if z.Len() != len(members) {
+		t.Fatalf("expected %d members, got %d", len(members), z.Len())
+	}
+
+	if got := z.Range(0, -1); !reflect.DeepEqual(got, members) {
+		t.Fatalf("unexpected order of members")
+	}
+
+	for rank, m := range members {
+		if got, ok := z.Rank(m.Member); !ok || got != rank {
+			t.Fatalf("expected rank %d for %s, got %d", rank, m.Member, got)
+		}
+	}
+
+	if got := z.Range(-2, -1); !reflect.DeepEqual(got, members[len(members)-2:]) {
+		t.Fatalf("unexpected tail range: %v", got)
+	}
+
+	var inRange []ScoredMember
+	for _, m := range members {
+		if m.Score > 3 && m.Score <= 5 {
+			inRange = append(inRange, m)
+		}
+	}
+
+	if got := z.RangeByScore(ScoreRange{Min: 3, Max: 5, MinExclusive: true}); !reflect.DeepEqual(got, inRange) {
+		t.Fatalf("unexpected score range: %v", got)
+	}
+
+	if _, ok := z.Rank("missing"); ok {
+		t.Fatalf("expected no rank for a missing member")
+	}
+
+	for _, m := range members {
+		z.Remove(m.Member)
+	}
+
+	if z.Len() != 0 || z.Size() != 0 || len(z.Range(0, -1)) != 0 {
+		t.Fatalf("expected empty sorted set, got %d members of %d bytes", z.Len(), z.Size())
+	}
+}
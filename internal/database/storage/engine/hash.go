@@ -0,0 +1,72 @@
+package engine
+
+import "sort"
+
+// Hash - отображение полей в строковые значения
+type Hash struct {
+	fields map[string]string
+	size   int64
+}
+
+func NewHash() *Hash {
+	return &Hash{fields: make(map[string]string)}
+}
+
+func (h *Hash) Type() Type {
+	return TypeHash
+}
+
+func (h *Hash) Size() int64 {
+	return h.size
+}
+
+// Get возвращает значение поля
+func (h *Hash) Get(field string) (string, bool) {
+	value, ok := h.fields[field]
+	return value, ok
+}
+
+// Set записывает значение поля и сообщает, было ли поле новым
+func (h *Hash) Set(field, value string) bool {
+	old, exists := h.fields[field]
+	if exists {
+		h.size -= int64(len(old))
+	} else {
+		h.size += int64(len(field))
+	}
+
+	h.size += int64(len(value))
+	h.fields[field] = value
+
+	return !exists
+}
+
+// Delete удаляет поле и сообщает, существовало ли оно
+func (h *Hash) Delete(field string) bool {
+	value, exists := h.fields[field]
+	if !exists {
+		return false
+	}
+
+	h.size -= int64(len(field) + len(value))
+	delete(h.fields, field)
+
+	return true
+}
+
+// Len возвращает число полей
+func (h *Hash) Len() int {
+	return len(h.fields)
+}
+
+// Fields возвращает имена полей в лексикографическом порядке
+func (h *Hash) Fields() []string {
+	fields := make([]string, 0, len(h.fields))
+	for field := range h.fields {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	return fields
+}
@@ -0,0 +1,166 @@
+package engine
+
+// minListCapacity - емкость буфера пустого списка, меньше которой буфер не сжимается
+const minListCapacity = 8
+
+// List - список строк на кольцевом буфере: вставка и удаление с обоих концов
+// и доступ по индексу выполняются за O(1). Отрицательный индекс отсчитывается
+// с конца списка, -1 - последний элемент
+type List struct {
+	buf  []string
+	head int
+	n    int
+	size int64
+}
+
+func NewList() *List {
+	return &List{buf: make([]string, minListCapacity)}
+}
+
+func (l *List) Type() Type {
+	return TypeList
+}
+
+func (l *List) Size() int64 {
+	return l.size
+}
+
+// Len возвращает число элементов
+func (l *List) Len() int {
+	return l.n
+}
+
+// PushFront добавляет элемент в начало списка
+func (l *List) PushFront(value string) {
+	l.grow()
+	l.head = (l.head - 1 + len(l.buf)) % len(l.buf)
+	l.buf[l.head] = value
+	l.n++
+	l.size += int64(len(value))
+}
+
+// PushBack добавляет элемент в конец списка
+func (l *List) PushBack(value string) {
+	l.grow()
+	l.buf[(l.head+l.n)%len(l.buf)] = value
+	l.n++
+	l.size += int64(len(value))
+}
+
+// PopFront удаляет и возвращает первый элемент
+func (l *List) PopFront() (string, bool) {
+	if l.n == 0 {
+		return "", false
+	}
+
+	value := l.buf[l.head]
+	l.buf[l.head] = ""
+	l.head = (l.head + 1) % len(l.buf)
+	l.removed(value)
+
+	return value, true
+}
+
+// PopBack удаляет и возвращает последний элемент
+func (l *List) PopBack() (string, bool) {
+	if l.n == 0 {
+		return "", false
+	}
+
+	i := (l.head + l.n - 1) % len(l.buf)
+	value := l.buf[i]
+	l.buf[i] = ""
+	l.removed(value)
+
+	return value, true
+}
+
+// Index возвращает элемент по индексу
+func (l *List) Index(i int) (string, bool) {
+	if i < 0 {
+		i += l.n
+	}
+
+	if i < 0 || i >= l.n {
+		return "", false
+	}
+
+	return l.at(i), true
+}
+
+// Range возвращает элементы с start по stop включительно
+func (l *List) Range(start, stop int) []string {
+	start, stop, ok := l.Bounds(start, stop)
+	if !ok {
+		return nil
+	}
+
+	values := make([]string, 0, stop-start+1)
+	for i := start; i <= stop; i++ {
+		values = append(values, l.at(i))
+	}
+
+	return values
+}
+
+// Trim оставляет в списке только элементы с start по stop включительно
+func (l *List) Trim(start, stop int) {
+	start, stop, ok := l.Bounds(start, stop)
+	if !ok {
+		start, stop = 0, -1
+	}
+
+	for l.n > stop+1 {
+		l.PopBack()
+	}
+
+	for i := 0; i < start; i++ {
+		l.PopFront()
+	}
+}
+
+// Bounds приводит индексы диапазона к неотрицательным в пределах списка и сообщает,
+// есть ли в диапазоне элементы
+func (l *List) Bounds(start, stop int) (int, int, bool) {
+	if start < 0 {
+		start = max(start+l.n, 0)
+	}
+
+	if stop < 0 {
+		stop += l.n
+	}
+
+	stop = min(stop, l.n-1)
+
+	return start, stop, start <= stop
+}
+
+func (l *List) at(i int) string {
+	return l.buf[(l.head+i)%len(l.buf)]
+}
+
+// removed учитывает удаление элемента и сжимает буфер, если он заполнен меньше чем на четверть
+func (l *List) removed(value string) {
+	l.n--
+	l.size -= int64(len(value))
+
+	if len(l.buf) > minListCapacity && l.n <= len(l.buf)/4 {
+		l.resize(len(l.buf) / 2)
+	}
+}
+
+// grow увеличивает буфер перед вставкой в заполненный список
+func (l *List) grow() {
+	if l.n == len(l.buf) {
+		l.resize(max(2*len(l.buf), minListCapacity))
+	}
+}
+
+func (l *List) resize(capacity int) {
+	buf := make([]string, capacity)
+	for i := 0; i < l.n; i++ {
+		buf[i] = l.at(i)
+	}
+
+	l.buf, l.head = buf, 0
+}
@@ -0,0 +1,68 @@
+package engine
+
+import "sort"
+
+// Set - множество уникальных строк
+type Set struct {
+	members map[string]struct{}
+	size    int64
+}
+
+func NewSet() *Set {
+	return &Set{members: make(map[string]struct{})}
+}
+
+func (s *Set) Type() Type {
+	return TypeSet
+}
+
+func (s *Set) Size() int64 {
+	return s.size
+}
+
+// Add добавляет элемент и сообщает, был ли он новым
+func (s *Set) Add(member string) bool {
+	if _, exists := s.members[member]; exists {
+		return false
+	}
+
+	s.members[member] = struct{}{}
+	s.size += int64(len(member))
+
+	return true
+}
+
+// Remove удаляет элемент и сообщает, был ли он во множестве
+func (s *Set) Remove(member string) bool {
+	if _, exists := s.members[member]; !exists {
+		return false
+	}
+
+	delete(s.members, member)
+	s.size -= int64(len(member))
+
+	return true
+}
+
+// Contains проверяет, входит ли элемент во множество
+func (s *Set) Contains(member string) bool {
+	_, exists := s.members[member]
+	return exists
+}
+
+// Len возвращает число элементов
+func (s *Set) Len() int {
+	return len(s.members)
+}
+
+// Members возвращает элементы в лексикографическом порядке
+func (s *Set) Members() []string {
+	members := make([]string, 0, len(s.members))
+	for member := range s.members {
+		members = append(members, member)
+	}
+
+	sort.Strings(members)
+
+	return members
+}
@@ -0,0 +1,30 @@
+package engine
+
+// Type - тип значения ключа
+type Type string
+
+const (
+	TypeString Type = "string"
+	TypeHash   Type = "hash"
+	TypeList   Type = "list"
+	TypeSet    Type = "set"
+	TypeZSet   Type = "zset"
+)
+
+// Value - значение ключа. Значения, кроме String, изменяются на месте внутри Engine.Update
+type Value interface {
+	Type() Type
+	// Size возвращает объем данных значения в байтах для статистики
+	Size() int64
+}
+
+// String - строковое значение
+type String string
+
+func (s String) Type() Type {
+	return TypeString
+}
+
+func (s String) Size() int64 {
+	return int64(len(s))
+}
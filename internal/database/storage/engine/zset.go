@@ -0,0 +1,291 @@
+package engine
+
+import "math/rand"
+
+const (
+	// skipListMaxLevel достаточно для 4^32 элементов при skipListP = 1/4
+	skipListMaxLevel = 32
+	skipListP        = 0.25
+	// scoreSize - объем оценки элемента для статистики
+	scoreSize = 8
+)
+
+// ScoredMember - элемент упорядоченного множества с оценкой
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+// ScoreRange - диапазон оценок, границы включаются, если не отмечены как исключенные
+type ScoreRange struct {
+	Min, Max                   float64
+	MinExclusive, MaxExclusive bool
+}
+
+func (r ScoreRange) aboveMin(score float64) bool {
+	if r.MinExclusive {
+		return score > r.Min
+	}
+
+	return score >= r.Min
+}
+
+func (r ScoreRange) belowMax(score float64) bool {
+	if r.MaxExclusive {
+		return score < r.Max
+	}
+
+	return score <= r.Max
+}
+
+// SortedSet - множество строк, упорядоченное по оценке, а при равных оценках -
+// лексикографически. Порядок хранится в skip list, где каждая ссылка знает число
+// пропускаемых элементов, поэтому ранг и доступ по рангу занимают O(log n)
+type SortedSet struct {
+	head   *skipNode
+	level  int
+	length int
+	scores map[string]float64
+	size   int64
+}
+
+type skipNode struct {
+	member string
+	score  float64
+	levels []skipLevel
+}
+
+type skipLevel struct {
+	next *skipNode
+	// span - число элементов, через которые ведет ссылка, включая next
+	span int
+}
+
+func NewSortedSet() *SortedSet {
+	return &SortedSet{
+		head:   &skipNode{levels: make([]skipLevel, skipListMaxLevel)},
+		level:  1,
+		scores: make(map[string]float64),
+	}
+}
+
+func (z *SortedSet) Type() Type {
+	return TypeZSet
+}
+
+func (z *SortedSet) Size() int64 {
+	return z.size
+}
+
+// Len возвращает число элементов
+func (z *SortedSet) Len() int {
+	return z.length
+}
+
+// Score возвращает оценку элемента
+func (z *SortedSet) Score(member string) (float64, bool) {
+	score, ok := z.scores[member]
+	return score, ok
+}
+
+// Add добавляет элемент или меняет его оценку и сообщает, был ли элемент новым
+func (z *SortedSet) Add(member string, score float64) bool {
+	old, exists := z.scores[member]
+	if exists {
+		if old == score {
+			return false
+		}
+
+		z.delete(member, old)
+	} else {
+		z.size += int64(len(member)) + scoreSize
+	}
+
+	z.scores[member] = score
+	z.insert(member, score)
+
+	return !exists
+}
+
+// Remove удаляет элемент и сообщает, был ли он во множестве
+func (z *SortedSet) Remove(member string) bool {
+	score, exists := z.scores[member]
+	if !exists {
+		return false
+	}
+
+	z.delete(member, score)
+	delete(z.scores, member)
+	z.size -= int64(len(member)) + scoreSize
+
+	return true
+}
+
+// Rank возвращает позицию элемента по возрастанию оценки, начиная с 0
+func (z *SortedSet) Rank(member string) (int, bool) {
+	score, exists := z.scores[member]
+	if !exists {
+		return 0, false
+	}
+
+	rank := 0
+	x := z.head
+	for i := z.level - 1; i >= 0; i-- {
+		for next := x.levels[i].next; next != nil && !less(member, score, next.member, next.score); next = x.levels[i].next {
+			rank += x.levels[i].span
+			x = next
+		}
+
+		if x.member == member && x != z.head {
+			return rank - 1, true
+		}
+	}
+
+	return 0, false
+}
+
+// Range возвращает элементы с рангами от start до stop включительно.
+// Отрицательный ранг отсчитывается с конца, -1 - последний элемент
+func (z *SortedSet) Range(start, stop int) []ScoredMember {
+	if start < 0 {
+		start = max(start+z.length, 0)
+	}
+
+	if stop < 0 {
+		stop += z.length
+	}
+
+	stop = min(stop, z.length-1)
+	if start > stop {
+		return nil
+	}
+
+	members := make([]ScoredMember, 0, stop-start+1)
+	for x := z.byRank(start); x != nil && len(members) < cap(members); x = x.levels[0].next {
+		members = append(members, ScoredMember{Member: x.member, Score: x.score})
+	}
+
+	return members
+}
+
+// RangeByScore возвращает элементы с оценками из диапазона r
+func (z *SortedSet) RangeByScore(r ScoreRange) []ScoredMember {
+	x := z.head
+	for i := z.level - 1; i >= 0; i-- {
+		for next := x.levels[i].next; next != nil && !r.aboveMin(next.score); next = x.levels[i].next {
+			x = next
+		}
+	}
+
+	var members []ScoredMember
+	for x = x.levels[0].next; x != nil && r.belowMax(x.score); x = x.levels[0].next {
+		members = append(members, ScoredMember{Member: x.member, Score: x.score})
+	}
+
+	return members
+}
+
+// less сообщает, стоит ли элемент a с оценкой aScore строго раньше элемента b с оценкой bScore
+func less(a string, aScore float64, b string, bScore float64) bool {
+	return aScore < bScore || (aScore == bScore && a < b)
+}
+
+// byRank возвращает узел с рангом rank, начиная с 0
+func (z *SortedSet) byRank(rank int) *skipNode {
+	traversed := 0
+	x := z.head
+	for i := z.level - 1; i >= 0; i-- {
+		for x.levels[i].next != nil && traversed+x.levels[i].span <= rank+1 {
+			traversed += x.levels[i].span
+			x = x.levels[i].next
+		}
+
+		if traversed == rank+1 {
+			return x
+		}
+	}
+
+	return nil
+}
+
+func (z *SortedSet) insert(member string, score float64) {
+	var update [skipListMaxLevel]*skipNode
+	var rank [skipListMaxLevel]int
+
+	x := z.head
+	for i := z.level - 1; i >= 0; i-- {
+		if i < z.level-1 {
+			rank[i] = rank[i+1]
+		}
+
+		for next := x.levels[i].next; next != nil && less(next.member, next.score, member, score); next = x.levels[i].next {
+			rank[i] += x.levels[i].span
+			x = next
+		}
+
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > z.level {
+		for i := z.level; i < level; i++ {
+			update[i] = z.head
+			z.head.levels[i].span = z.length
+		}
+
+		z.level = level
+	}
+
+	n := &skipNode{member: member, score: score, levels: make([]skipLevel, level)}
+	for i := 0; i < level; i++ {
+		n.levels[i].next = update[i].levels[i].next
+		update[i].levels[i].next = n
+
+		n.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = rank[0] - rank[i] + 1
+	}
+
+	for i := level; i < z.level; i++ {
+		update[i].levels[i].span++
+	}
+
+	z.length++
+}
+
+func (z *SortedSet) delete(member string, score float64) {
+	var update [skipListMaxLevel]*skipNode
+
+	x := z.head
+	for i := z.level - 1; i >= 0; i-- {
+		for next := x.levels[i].next; next != nil && less(next.member, next.score, member, score); next = x.levels[i].next {
+			x = next
+		}
+
+		update[i] = x
+	}
+
+	x = x.levels[0].next
+	for i := 0; i < z.level; i++ {
+		if update[i].levels[i].next == x {
+			update[i].levels[i].span += x.levels[i].span - 1
+			update[i].levels[i].next = x.levels[i].next
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+
+	for z.level > 1 && z.head.levels[z.level-1].next == nil {
+		z.level--
+	}
+
+	z.length--
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skipListMaxLevel && rand.Float64() < skipListP {
+		level++
+	}
+
+	return level
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/patyukin/mdb/internal/cdc"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+)
+
+// viewHash вызывает fn с hash ключа. Отсутствующий ключ передается как пустой hash
+func (s *Storage) viewHash(ctx context.Context, key string, fn func(h *engine.Hash) error) error {
+	return viewCollection(ctx, s.engine, key, engine.NewHash, fn)
+}
+
+// mutateHash вызывает plan с hash ключа и выполняет возвращенные изменения. Hash без полей удаляется
+func (s *Storage) mutateHash(ctx context.Context, key string, plan func(h *engine.Hash) ([]cdc.Change, error)) error {
+	return mutateCollection(ctx, s, key, engine.NewHash, plan)
+}
+
+// applyHash применяет к hash изменение журнала
+func applyHash(h *engine.Hash, c cdc.Change) {
+	switch c.Op {
+	case cdc.OpHSet:
+		for i := 0; i+1 < len(c.Args); i += 2 {
+			h.Set(c.Args[i], c.Args[i+1])
+		}
+	case cdc.OpHDel:
+		for _, field := range c.Args {
+			h.Delete(field)
+		}
+	}
+}
+
+func (s *Storage) hashCommand(ctx context.Context, action string, args []string) (string, error) {
+	key := args[0]
+
+	var result string
+	var err error
+	switch action {
+	case parser.HSET:
+		if len(args)%2 == 0 {
+			return "", fmt.Errorf("%w: %s expects field value pairs", parser.ErrWrongArity, action)
+		}
+
+		err = s.mutateHash(ctx, key, func(h *engine.Hash) ([]cdc.Change, error) {
+			added := make(map[string]struct{})
+			for i := 1; i < len(args); i += 2 {
+				if _, ok := h.Get(args[i]); !ok {
+					added[args[i]] = struct{}{}
+				}
+			}
+
+			result = strconv.Itoa(len(added))
+			return []cdc.Change{{Op: cdc.OpHSet, Key: key, Args: args[1:]}}, nil
+		})
+	case parser.HGET:
+		err = s.viewHash(ctx, key, func(h *engine.Hash) error {
+			value, ok := h.Get(args[1])
+			if !ok {
+				return fmt.Errorf("'%s' field '%s' - %w", key, args[1], engine.ErrNotFound)
+			}
+
+			result = parser.QuoteValue(ctx, value)
+			return nil
+		})
+	case parser.HMGET:
+		err = s.viewHash(ctx, key, func(h *engine.Hash) error {
+			lines := make([]string, 0, len(args)-1)
+			for _, field := range args[1:] {
+				value, ok := h.Get(field)
+				if !ok {
+					lines = append(lines, nilValue)
+					continue
+				}
+
+				lines = append(lines, parser.Quote(value))
+			}
+
+			result = strings.Join(lines, "\n")
+			return nil
+		})
+	case parser.HDEL:
+		err = s.mutateHash(ctx, key, func(h *engine.Hash) ([]cdc.Change, error) {
+			deleted := existing(args[1:], func(field string) bool {
+				_, ok := h.Get(field)
+				return ok
+			})
+
+			result = strconv.Itoa(len(deleted))
+			return removal(cdc.OpHDel, key, h, deleted), nil
+		})
+	case parser.HEXISTS:
+		err = s.viewHash(ctx, key, func(h *engine.Hash) error {
+			_, ok := h.Get(args[1])
+			result = boolResult(ok)
+			return nil
+		})
+	case parser.HLEN:
+		err = s.viewHash(ctx, key, func(h *engine.Hash) error {
+			result = strconv.Itoa(h.Len())
+			return nil
+		})
+	case parser.HKEYS:
+		err = s.viewHash(ctx, key, func(h *engine.Hash) error {
+			fields := h.Fields()
+			for i, field := range fields {
+				fields[i] = parser.Quote(field)
+			}
+
+			result = strings.Join(fields, "\n")
+			return nil
+		})
+	case parser.HGETALL:
+		err = s.viewHash(ctx, key, func(h *engine.Hash) error {
+			fields := h.Fields()
+			lines := make([]string, 0, len(fields))
+			for _, field := range fields {
+				value, _ := h.Get(field)
+				lines = append(lines, parser.Quote(field)+" "+parser.Quote(value))
+			}
+
+			result = strings.Join(lines, "\n")
+			return nil
+		})
+	case parser.HINCRBY:
+		increment, parseErr := strconv.ParseInt(args[2], 10, 64)
+		if parseErr != nil {
+			return "", fmt.Errorf("%w: increment must be an integer: %s", parser.ErrInvalidArgument, args[2])
+		}
+
+		err = s.mutateHash(ctx, key, func(h *engine.Hash) ([]cdc.Change, error) {
+			var current int64
+			if value, ok := h.Get(args[1]); ok {
+				n, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("%w: hash value is not an integer: %s", parser.ErrInvalidArgument, value)
+				}
+
+				current = n
+			}
+
+			if (increment > 0 && current > math.MaxInt64-increment) || (increment < 0 && current < math.MinInt64-increment) {
+				return nil, fmt.Errorf("%w: increment or decrement would overflow", parser.ErrInvalidArgument)
+			}
+
+			// в журнал попадает итоговое значение, а не приращение
+			result = strconv.FormatInt(current+increment, 10)
+			return []cdc.Change{{Op: cdc.OpHSet, Key: key, Args: []string{args[1], result}}}, nil
+		})
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed %s: %w", action, err)
+	}
+
+	return result, nil
+}
+
+// existing возвращает элементы items, для которых contains истинно, без повторов
+func existing(items []string, contains func(item string) bool) []string {
+	seen := make(map[string]struct{}, len(items))
+	var found []string
+	for _, item := range items {
+		if _, ok := seen[item]; ok || !contains(item) {
+			continue
+		}
+
+		seen[item] = struct{}{}
+		found = append(found, item)
+	}
+
+	return found
+}
+
+func boolResult(ok bool) string {
+	if ok {
+		return "1"
+	}
+
+	return "0"
+}
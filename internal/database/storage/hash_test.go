@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStorage_Execute_Hash(t *testing.T) {
+	storage := New(engine.New(), zap.NewNop())
+
+	tests := []struct {
+		name     string
+		command  *parser.Command
+		expected string
+		err      error
+	}{
+		{name: "Создание hash", command: &parser.Command{Action: parser.HSET, Args: []string{"user:1", "name", "alice", "city", "New York"}}, expected: "2"},
+		{name: "Обновление и новое поле", command: &parser.Command{Action: parser.HSET, Args: []string{"user:1", "name", "bob", "age", "30"}}, expected: "1"},
+		{name: "Нечетное число аргументов", command: &parser.Command{Action: parser.HSET, Args: []string{"user:1", "name", "bob", "age"}}, err: parser.ErrWrongArity},
+		{name: "Значение поля", command: &parser.Command{Action: parser.HGET, Args: []string{"user:1", "name"}}, expected: "bob"},
+		{name: "Отсутствующее поле", command: &parser.Command{Action: parser.HGET, Args: []string{"user:1", "email"}}, err: engine.ErrNotFound},
+		{name: "Отсутствующий ключ", command: &parser.Command{Action: parser.HGET, Args: []string{"user:2", "name"}}, err: engine.ErrNotFound},
+		{name: "Несколько полей", command: &parser.Command{Action: parser.HMGET, Args: []string{"user:1", "city", "email", "age"}}, expected: "\"New York\"\n(nil)\n30"},
+		{name: "Существующее поле", command: &parser.Command{Action: parser.HEXISTS, Args: []string{"user:1", "age"}}, expected: "1"},
+		{name: "Отсутствующее поле существует", command: &parser.Command{Action: parser.HEXISTS, Args: []string{"user:1", "email"}}, expected: "0"},
+		{name: "Число полей", command: &parser.Command{Action: parser.HLEN, Args: []string{"user:1"}}, expected: "3"},
+		{name: "Число полей отсутствующего ключа", command: &parser.Command{Action: parser.HLEN, Args: []string{"user:2"}}, expected: "0"},
+		{name: "Имена полей", command: &parser.Command{Action: parser.HKEYS, Args: []string{"user:1"}}, expected: "age\ncity\nname"},
+		{name: "Поля и значения", command: &parser.Command{Action: parser.HGETALL, Args: []string{"user:1"}}, expected: "age 30\ncity \"New York\"\nname bob"},
+		{name: "Увеличение", command: &parser.Command{Action: parser.HINCRBY, Args: []string{"user:1", "age", "5"}}, expected: "35"},
+		{name: "Увеличение нового поля", command: &parser.Command{Action: parser.HINCRBY, Args: []string{"user:1", "visits", "-1"}}, expected: "-1"},
+		{name: "Увеличение нечислового поля", command: &parser.Command{Action: parser.HINCRBY, Args: []string{"user:1", "name", "1"}}, err: parser.ErrInvalidArgument},
+		{name: "Переполнение", command: &parser.Command{Action: parser.HINCRBY, Args: []string{"user:1", "age", "9223372036854775800"}}, err: parser.ErrInvalidArgument},
+		{name: "Удаление полей", command: &parser.Command{Action: parser.HDEL, Args: []string{"user:1", "age", "email", "visits"}}, expected: "2"},
+		{name: "Строковая команда для hash", command: &parser.Command{Action: parser.GET, Args: []string{"user:1"}}, err: engine.ErrWrongType},
+		{name: "Строка", command: &parser.Command{Action: parser.SET, Args: []string{"greeting", "hello"}}},
+		{name: "Команда hash для строки", command: &parser.Command{Action: parser.HSET, Args: []string{"greeting", "a", "b"}}, err: engine.ErrWrongType},
+		{name: "Чтение hash из строки", command: &parser.Command{Action: parser.HLEN, Args: []string{"greeting"}}, err: engine.ErrWrongType},
+		{name: "Удаление последних полей", command: &parser.Command{Action: parser.HDEL, Args: []string{"user:1", "name", "city"}}, expected: "2"},
+		{name: "Hash без полей удален", command: &parser.Command{Action: parser.DBSIZE}, expected: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := storage.Execute(context.Background(), tt.command)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestStorage_Execute_HashChanges(t *testing.T) {
+	storage, sub := newRecordingStorage(t)
+
+	assertRecorded(t, storage, sub, []*parser.Command{
+		{Action: parser.HSET, Args: []string{"user", "name", "alice", "age", "30"}},
+		{Action: parser.HINCRBY, Args: []string{"user", "age", "1"}},
+		// удаление отсутствующего поля ничего не меняет
+		{Action: parser.HDEL, Args: []string{"user", "missing"}},
+		{Action: parser.HDEL, Args: []string{"user", "name", "name"}},
+		{Action: parser.HDEL, Args: []string{"user", "age"}},
+	}, "1 hset user name alice age 30\n2 hset user age 31\n3 hdel user name\n4 hdel user age\n5 del user", []string{
+		"hset user", "hset user", "hdel user", "hdel user", "del user",
+	})
+}
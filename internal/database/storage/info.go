@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// InfoField - строка раздела INFO
+type InfoField struct {
+	Key   string
+	Value string
+}
+
+// InfoProvider возвращает поля раздела INFO в момент выполнения команды
+type InfoProvider func() []InfoField
+
+type infoSection struct {
+	name     string
+	provider InfoProvider
+}
+
+// Порядок разделов в выводе INFO без аргументов
+var defaultSections = []string{"server", "clients", "memory", "stats", "keyspace", "config"}
+
+// WithInfoSection добавляет раздел INFO, данные для которого хранит не Storage,
+// например сведения о клиентах или конфигурации
+func WithInfoSection(name string, provider InfoProvider) Option {
+	return func(s *Storage) {
+		s.sections = append(s.sections, infoSection{name: strings.ToLower(name), provider: provider})
+	}
+}
+
+func (s *Storage) info(section string) string {
+	providers := map[string]InfoProvider{
+		"server":   s.serverInfo,
+		"memory":   s.memoryInfo,
+		"stats":    s.statsInfo,
+		"keyspace": s.keyspaceInfo,
+	}
+
+	order := append([]string{}, defaultSections...)
+	for _, sec := range s.sections {
+		if _, ok := providers[sec.name]; !ok && !slices.Contains(order, sec.name) {
+			order = append(order, sec.name)
+		}
+
+		providers[sec.name] = sec.provider
+	}
+
+	section = strings.ToLower(section)
+	if section != "" && section != "all" && section != "default" {
+		order = []string{section}
+	}
+
+	var b strings.Builder
+	for _, name := range order {
+		provider, ok := providers[name]
+		if !ok {
+			continue
+		}
+
+		fmt.Fprintf(&b, "# %s\n", strings.ToUpper(name[:1])+name[1:])
+		for _, field := range provider() {
+			fmt.Fprintf(&b, "%s:%s\n", field.Key, field.Value)
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (s *Storage) serverInfo() []InfoField {
+	uptime := time.Since(s.startedAt)
+
+	return []InfoField{
+		{"go_version", runtime.Version()},
+		{"os", runtime.GOOS},
+		{"arch", runtime.GOARCH},
+		{"process_id", fmt.Sprint(os.Getpid())},
+		{"uptime_in_seconds", fmt.Sprint(int64(uptime.Seconds()))},
+		{"uptime_in_days", fmt.Sprint(int64(uptime.Hours() / 24))},
+	}
+}
+
+func (s *Storage) memoryInfo() []InfoField {
+	stats := s.engine.Stats()
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return []InfoField{
+		{"used_memory_dataset", fmt.Sprint(stats.KeyBytes + stats.ValueBytes)},
+		{"used_memory_keys", fmt.Sprint(stats.KeyBytes)},
+		{"used_memory_values", fmt.Sprint(stats.ValueBytes)},
+		{"heap_inuse", fmt.Sprint(mem.HeapInuse)},
+		{"heap_sys", fmt.Sprint(mem.HeapSys)},
+	}
+}
+
+func (s *Storage) statsInfo() []InfoField {
+	stats := s.engine.Stats()
+
+	return []InfoField{
+		{"total_commands_processed", fmt.Sprint(s.processed.Load())},
+		{"total_gets", fmt.Sprint(stats.Gets)},
+		{"total_sets", fmt.Sprint(stats.Sets)},
+		{"total_deletes", fmt.Sprint(stats.Deletes)},
+		{"keyspace_hits", fmt.Sprint(stats.Hits)},
+		{"keyspace_misses", fmt.Sprint(stats.Misses)},
+	}
+}
+
+func (s *Storage) keyspaceInfo() []InfoField {
+	return []InfoField{
+		{"keys", fmt.Sprint(s.engine.Stats().Keys)},
+	}
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/patyukin/mdb/internal/database/storage/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStorage_Execute_AdminCommands(t *testing.T) {
+	mockEngine := new(mocks.Engine)
+	mockEngine.On("Stats").Return(engine.Stats{Keys: 3, KeyBytes: 10, ValueBytes: 20, Gets: 5, Hits: 4, Misses: 1})
+
+	storage := New(mockEngine, zap.NewNop(), WithInfoSection("clients", func() []InfoField {
+		return []InfoField{{"connected_clients", "2"}}
+	}))
+
+	execute := func(action string, args ...string) (string, error) {
+		return storage.Execute(context.Background(), &parser.Command{Action: action, Args: args})
+	}
+
+	result, err := execute("PING")
+	require.NoError(t, err)
+	assert.Equal(t, "PONG", result)
+
+	result, err = execute("PING", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result)
+
+	result, err = execute("DBSIZE")
+	require.NoError(t, err)
+	assert.Equal(t, "3", result)
+
+	result, err = execute("TIME")
+	require.NoError(t, err)
+	parts := strings.Fields(result)
+	require.Len(t, parts, 2)
+	seconds, err := strconv.ParseInt(parts[0], 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().Unix(), seconds, 2)
+
+	result, err = execute("INFO", "keyspace")
+	require.NoError(t, err)
+	assert.Equal(t, "# Keyspace\nkeys:3", result)
+
+	result, err = execute("INFO", "CLIENTS")
+	require.NoError(t, err)
+	assert.Equal(t, "# Clients\nconnected_clients:2", result)
+
+	result, err = execute("INFO", "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, "", result)
+
+	result, err = execute("INFO")
+	require.NoError(t, err)
+	var headers []string
+	for _, line := range strings.Split(result, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			headers = append(headers, line)
+		}
+	}
+	assert.Equal(t, []string{"# Server", "# Clients", "# Memory", "# Stats", "# Keyspace"}, headers)
+	assert.Contains(t, result, "used_memory_dataset:30\n")
+	assert.Contains(t, result, "keyspace_hits:4\n")
+	assert.Contains(t, result, "total_commands_processed:")
+	assert.NotContains(t, result, "\n\n")
+
+	result, err = execute("COMMAND", "DOCS", "get")
+	require.NoError(t, err)
+	assert.Equal(t, "# GET\nsummary:Returns the value of a key.\nsyntax:GET key\ngroup:string", result)
+
+	result, err = execute("COMMAND", "DOCS")
+	require.NoError(t, err)
+	assert.Contains(t, result, "# SET\n")
+	assert.Contains(t, result, "# PING\n")
+
+	_, err = execute("COMMAND", "LIST")
+	assert.ErrorIs(t, err, parser.ErrUnknownCommand)
+
+	_, err = execute("DBSIZE", "extra")
+	assert.ErrorIs(t, err, parser.ErrWrongArity)
+	assert.EqualError(t, err, "failed command.Validate, command DBSIZE takes no arguments")
+}
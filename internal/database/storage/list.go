@@ -0,0 +1,367 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patyukin/mdb/internal/cdc"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+)
+
+// maxBlockingTimeout - наибольший таймаут BLPOP и BRPOP в секундах, представимый в time.Duration
+const maxBlockingTimeout = float64(math.MaxInt64 / int64(time.Second))
+
+// listWaiter - клиент, ожидающий элемент в BLPOP или BRPOP
+type listWaiter struct {
+	keys   []string
+	front  bool
+	result chan listElement
+}
+
+// listElement - элемент, извлеченный из списка key
+type listElement struct {
+	key   string
+	value string
+}
+
+// listWaiters - очереди клиентов, ожидающих элементы списков. Добавление элементов
+// и их передача ожидающим выполняются под mu, поэтому элемент получает клиент,
+// ждущий дольше остальных, и ни один элемент не теряется между проверкой и ожиданием
+type listWaiters struct {
+	mu     sync.Mutex
+	queues map[string][]*listWaiter
+}
+
+func (w *listWaiters) add(waiter *listWaiter) {
+	if w.queues == nil {
+		w.queues = make(map[string][]*listWaiter)
+	}
+
+	for _, key := range waiter.keys {
+		w.queues[key] = append(w.queues[key], waiter)
+	}
+}
+
+func (w *listWaiters) remove(waiter *listWaiter) {
+	for _, key := range waiter.keys {
+		queue := w.queues[key]
+		for i, queued := range queue {
+			if queued == waiter {
+				queue = append(queue[:i], queue[i+1:]...)
+				break
+			}
+		}
+
+		if len(queue) == 0 {
+			delete(w.queues, key)
+		} else {
+			w.queues[key] = queue
+		}
+	}
+}
+
+func (s *Storage) viewList(ctx context.Context, key string, fn func(l *engine.List) error) error {
+	return viewCollection(ctx, s.engine, key, engine.NewList, fn)
+}
+
+func (s *Storage) mutateList(ctx context.Context, key string, plan func(l *engine.List) ([]cdc.Change, error)) error {
+	return mutateCollection(ctx, s, key, engine.NewList, plan)
+}
+
+// applyList применяет к списку изменение журнала
+func applyList(l *engine.List, c cdc.Change) error {
+	switch c.Op {
+	case cdc.OpLPush, cdc.OpRPush:
+		for _, value := range c.Args {
+			if c.Op == cdc.OpLPush {
+				l.PushFront(value)
+			} else {
+				l.PushBack(value)
+			}
+		}
+	case cdc.OpLPop, cdc.OpRPop:
+		for range c.Args {
+			pop(l, c.Op == cdc.OpLPop)
+		}
+	case cdc.OpLTrim:
+		start, stop, err := parseRange(c.Args[0], c.Args[1])
+		if err != nil {
+			return err
+		}
+
+		l.Trim(start, stop)
+	}
+
+	return nil
+}
+
+func (s *Storage) listCommand(ctx context.Context, action string, args []string) (string, error) {
+	key := args[0]
+
+	var result string
+	var err error
+	switch action {
+	case parser.LPUSH, parser.RPUSH:
+		result, err = s.push(ctx, key, action == parser.LPUSH, args[1:])
+	case parser.LPOP, parser.RPOP:
+		count := 1
+		if len(args) == 2 {
+			if count, err = strconv.Atoi(args[1]); err != nil || count <= 0 {
+				return "", fmt.Errorf("%w: count must be a positive integer: %s", parser.ErrInvalidArgument, args[1])
+			}
+		}
+
+		err = s.mutateList(ctx, key, func(l *engine.List) ([]cdc.Change, error) {
+			if l.Len() == 0 {
+				return nil, fmt.Errorf("'%s' - %w", key, engine.ErrNotFound)
+			}
+
+			popped := peek(l, action == parser.LPOP, count)
+			// одиночный элемент возвращается так же, как значение GET
+			if len(args) == 1 {
+				result = parser.QuoteValue(ctx, popped[0])
+			} else {
+				result = formatMembers(slices.Clone(popped))
+			}
+
+			return removal(popOp(action == parser.LPOP), key, l, popped), nil
+		})
+	case parser.LRANGE, parser.LTRIM:
+		start, stop, parseErr := parseRange(args[1], args[2])
+		if parseErr != nil {
+			return "", parseErr
+		}
+
+		if action == parser.LTRIM {
+			err = s.mutateList(ctx, key, func(l *engine.List) ([]cdc.Change, error) {
+				kept := 0
+				if first, last, ok := l.Bounds(start, stop); ok {
+					kept = last - first + 1
+				}
+
+				if kept == l.Len() {
+					return nil, nil
+				}
+
+				changes := []cdc.Change{{Op: cdc.OpLTrim, Key: key, Args: []string{strconv.Itoa(start), strconv.Itoa(stop)}}}
+				if kept == 0 {
+					changes = append(changes, cdc.Change{Op: cdc.OpDel, Key: key})
+				}
+
+				return changes, nil
+			})
+			break
+		}
+
+		err = s.viewList(ctx, key, func(l *engine.List) error {
+			values := l.Range(start, stop)
+			for i, value := range values {
+				values[i] = parser.Quote(value)
+			}
+
+			result = strings.Join(values, "\n")
+			return nil
+		})
+	case parser.LLEN:
+		err = s.viewList(ctx, key, func(l *engine.List) error {
+			result = strconv.Itoa(l.Len())
+			return nil
+		})
+	case parser.LINDEX:
+		index, parseErr := strconv.Atoi(args[1])
+		if parseErr != nil {
+			return "", fmt.Errorf("%w: index must be an integer: %s", parser.ErrInvalidArgument, args[1])
+		}
+
+		err = s.viewList(ctx, key, func(l *engine.List) error {
+			value, ok := l.Index(index)
+			if !ok {
+				return fmt.Errorf("'%s' index %d - %w", key, index, engine.ErrNotFound)
+			}
+
+			result = parser.QuoteValue(ctx, value)
+			return nil
+		})
+	case parser.BLPOP, parser.BRPOP:
+		result, err = s.blockingPop(ctx, action == parser.BLPOP, args)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed %s: %w", action, err)
+	}
+
+	return result, nil
+}
+
+// peek возвращает до count элементов с начала или с конца списка в порядке извлечения
+func peek(l *engine.List, front bool, count int) []string {
+	values := make([]string, 0, min(count, l.Len()))
+	for i := 0; i < cap(values); i++ {
+		index := i
+		if !front {
+			index = -1 - i
+		}
+
+		value, _ := l.Index(index)
+		values = append(values, value)
+	}
+
+	return values
+}
+
+func popOp(front bool) string {
+	if front {
+		return cdc.OpLPop
+	}
+
+	return cdc.OpRPop
+}
+
+func pop(l *engine.List, front bool) string {
+	var value string
+	if front {
+		value, _ = l.PopFront()
+	} else {
+		value, _ = l.PopBack()
+	}
+
+	return value
+}
+
+func parseRange(startArg, stopArg string) (int, int, error) {
+	start, err := strconv.Atoi(startArg)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: start must be an integer: %s", parser.ErrInvalidArgument, startArg)
+	}
+
+	stop, err := strconv.Atoi(stopArg)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: stop must be an integer: %s", parser.ErrInvalidArgument, stopArg)
+	}
+
+	return start, stop, nil
+}
+
+// push добавляет элементы в список и передает их клиентам, ожидающим этот список.
+// Возвращает длину списка после добавления
+func (s *Storage) push(ctx context.Context, key string, front bool, values []string) (string, error) {
+	s.listWaiters.mu.Lock()
+	defer s.listWaiters.mu.Unlock()
+
+	length := 0
+	err := s.mutateList(ctx, key, func(l *engine.List) ([]cdc.Change, error) {
+		op := cdc.OpRPush
+		if front {
+			op = cdc.OpLPush
+		}
+
+		length = l.Len() + len(values)
+		return []cdc.Change{{Op: op, Key: key, Args: values}}, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for len(s.listWaiters.queues[key]) > 0 {
+		waiter := s.listWaiters.queues[key][0]
+		element, ok := s.popFirst(ctx, []string{key}, waiter.front)
+		if !ok {
+			break
+		}
+
+		s.listWaiters.remove(waiter)
+		waiter.result <- element
+	}
+
+	return strconv.Itoa(length), nil
+}
+
+// popFirst извлекает элемент из первого непустого списка среди keys. Ключи
+// с значениями других типов пропускаются
+func (s *Storage) popFirst(ctx context.Context, keys []string, front bool) (listElement, bool) {
+	for _, key := range keys {
+		var element listElement
+		err := s.mutateList(ctx, key, func(l *engine.List) ([]cdc.Change, error) {
+			if l.Len() == 0 {
+				return nil, nil
+			}
+
+			element = listElement{key: key, value: peek(l, front, 1)[0]}
+			return removal(popOp(front), key, l, []string{element.value}), nil
+		})
+		if err == nil && element.key != "" {
+			return element, true
+		}
+	}
+
+	return listElement{}, false
+}
+
+// blockingPop ждет элемент в одном из списков до истечения таймаута, заданного
+// последним аргументом в секундах. По таймауту возвращается nilValue
+func (s *Storage) blockingPop(ctx context.Context, front bool, args []string) (string, error) {
+	keys, timeoutArg := args[:len(args)-1], args[len(args)-1]
+	seconds, err := strconv.ParseFloat(timeoutArg, 64)
+	if err != nil || math.IsNaN(seconds) || seconds < 0 || seconds > maxBlockingTimeout {
+		return "", fmt.Errorf("%w: timeout must be a non-negative number of seconds: %s", parser.ErrInvalidArgument, timeoutArg)
+	}
+
+	s.listWaiters.mu.Lock()
+	for _, key := range keys {
+		if err = s.viewList(ctx, key, func(*engine.List) error { return nil }); err != nil {
+			s.listWaiters.mu.Unlock()
+			return "", err
+		}
+	}
+
+	if element, ok := s.popFirst(ctx, keys, front); ok {
+		s.listWaiters.mu.Unlock()
+		return formatListElement(element), nil
+	}
+
+	waiter := &listWaiter{keys: keys, front: front, result: make(chan listElement, 1)}
+	s.listWaiters.add(waiter)
+	s.listWaiters.mu.Unlock()
+
+	var expired <-chan time.Time
+	if seconds > 0 {
+		timer := time.NewTimer(time.Duration(seconds * float64(time.Second)))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case element := <-waiter.result:
+		return formatListElement(element), nil
+	case <-expired:
+	case <-ctx.Done():
+	}
+
+	s.listWaiters.mu.Lock()
+	defer s.listWaiters.mu.Unlock()
+
+	// элемент мог быть передан одновременно с истечением ожидания
+	select {
+	case element := <-waiter.result:
+		return formatListElement(element), nil
+	default:
+	}
+
+	s.listWaiters.remove(waiter)
+	if err = contextError(ctx); err != nil {
+		return "", err
+	}
+
+	return nilValue, nil
+}
+
+func formatListElement(e listElement) string {
+	return parser.Quote(e.key) + " " + parser.Quote(e.value)
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStorage_Execute_List(t *testing.T) {
+	storage := New(engine.New(), zap.NewNop())
+
+	tests := []struct {
+		name     string
+		command  *parser.Command
+		expected string
+		err      error
+	}{
+		{name: "Добавление в начало", command: &parser.Command{Action: parser.LPUSH, Args: []string{"jobs", "b", "a"}}, expected: "2"},
+		{name: "Добавление в конец", command: &parser.Command{Action: parser.RPUSH, Args: []string{"jobs", "c", "d e", "f"}}, expected: "5"},
+		{name: "Весь список", command: &parser.Command{Action: parser.LRANGE, Args: []string{"jobs", "0", "-1"}}, expected: "a\nb\nc\n\"d e\"\nf"},
+		{name: "Диапазон за пределами списка", command: &parser.Command{Action: parser.LRANGE, Args: []string{"jobs", "10", "20"}}, expected: ""},
+		{name: "Длина", command: &parser.Command{Action: parser.LLEN, Args: []string{"jobs"}}, expected: "5"},
+		{name: "Длина отсутствующего списка", command: &parser.Command{Action: parser.LLEN, Args: []string{"missing"}}, expected: "0"},
+		{name: "Элемент с конца", command: &parser.Command{Action: parser.LINDEX, Args: []string{"jobs", "-2"}}, expected: `"d e"`},
+		{name: "Элемент за пределами списка", command: &parser.Command{Action: parser.LINDEX, Args: []string{"jobs", "5"}}, err: engine.ErrNotFound},
+		{name: "Первый элемент", command: &parser.Command{Action: parser.LPOP, Args: []string{"jobs"}}, expected: "a"},
+		{name: "Несколько элементов с конца", command: &parser.Command{Action: parser.RPOP, Args: []string{"jobs", "2"}}, expected: "f\n\"d e\""},
+		{name: "Неверное количество", command: &parser.Command{Action: parser.LPOP, Args: []string{"jobs", "0"}}, err: parser.ErrInvalidArgument},
+		{name: "Обрезка", command: &parser.Command{Action: parser.LTRIM, Args: []string{"jobs", "1", "-1"}}},
+		{name: "Список после обрезки", command: &parser.Command{Action: parser.LRANGE, Args: []string{"jobs", "0", "-1"}}, expected: "c"},
+		{name: "Обрезка до пустого списка", command: &parser.Command{Action: parser.LTRIM, Args: []string{"jobs", "1", "0"}}},
+		{name: "Пустой список удален", command: &parser.Command{Action: parser.LPOP, Args: []string{"jobs"}}, err: engine.ErrNotFound},
+		{name: "Неверный индекс", command: &parser.Command{Action: parser.LRANGE, Args: []string{"jobs", "a", "1"}}, err: parser.ErrInvalidArgument},
+		{name: "Строка", command: &parser.Command{Action: parser.SET, Args: []string{"greeting", "hello"}}},
+		{name: "Команда списка для строки", command: &parser.Command{Action: parser.RPUSH, Args: []string{"greeting", "a"}}, err: engine.ErrWrongType},
+		{name: "Ожидание на строке", command: &parser.Command{Action: parser.BLPOP, Args: []string{"greeting", "1"}}, err: engine.ErrWrongType},
+		{name: "Неверный таймаут", command: &parser.Command{Action: parser.BLPOP, Args: []string{"jobs", "-1"}}, err: parser.ErrInvalidArgument},
+		{name: "Готовый элемент без ожидания", command: &parser.Command{Action: parser.RPUSH, Args: []string{"ready", "x", "y"}}, expected: "2"},
+		{name: "Извлечение из первого непустого списка", command: &parser.Command{Action: parser.BRPOP, Args: []string{"jobs", "ready", "0"}}, expected: "ready y"},
+		{name: "Истечение таймаута", command: &parser.Command{Action: parser.BLPOP, Args: []string{"jobs", "0.01"}}, expected: "(nil)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := storage.Execute(context.Background(), tt.command)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestStorage_Execute_BlockingPopFIFO(t *testing.T) {
+	storage := New(engine.New(), zap.NewNop())
+
+	type popResult struct {
+		client int
+		value  string
+	}
+
+	results := make(chan popResult, 3)
+	for client := 0; client < 3; client++ {
+		go func() {
+			value, err := storage.Execute(context.Background(), &parser.Command{Action: parser.BLPOP, Args: []string{"other", "queue", "5"}})
+			assert.NoError(t, err)
+			results <- popResult{client, value}
+		}()
+
+		// клиенты встают в очередь по порядку
+		require.Eventually(t, func() bool {
+			storage.listWaiters.mu.Lock()
+			defer storage.listWaiters.mu.Unlock()
+
+			return len(storage.listWaiters.queues["queue"]) == client+1
+		}, time.Second, time.Millisecond)
+	}
+
+	_, err := storage.Execute(context.Background(), &parser.Command{Action: parser.RPUSH, Args: []string{"queue", "first", "second"}})
+	require.NoError(t, err)
+	_, err = storage.Execute(context.Background(), &parser.Command{Action: parser.LPUSH, Args: []string{"other", "third"}})
+	require.NoError(t, err)
+
+	// элементы достаются клиентам в порядке ожидания
+	values := make([]string, 3)
+	for range values {
+		r := <-results
+		values[r.client] = r.value
+	}
+	assert.Equal(t, []string{"queue first", "queue second", "other third"}, values)
+
+	storage.listWaiters.mu.Lock()
+	assert.Empty(t, storage.listWaiters.queues)
+	storage.listWaiters.mu.Unlock()
+
+	// отмена запроса прерывает ожидание
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err = storage.Execute(ctx, &parser.Command{Action: parser.BRPOP, Args: []string{"queue", "0"}})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, storage.listWaiters.queues)
+}
+
+func TestStorage_Execute_ListChanges(t *testing.T) {
+	storage, sub := newRecordingStorage(t)
+
+	assertRecorded(t, storage, sub, []*parser.Command{
+		{Action: parser.RPUSH, Args: []string{"jobs", "a", "b", "c", "d"}},
+		{Action: parser.LPUSH, Args: []string{"jobs", "z"}},
+		{Action: parser.LPOP, Args: []string{"jobs"}},
+		{Action: parser.RPOP, Args: []string{"jobs", "2"}},
+		// обрезка, не удаляющая элементов, ничего не меняет
+		{Action: parser.LTRIM, Args: []string{"jobs", "0", "-1"}},
+		{Action: parser.LTRIM, Args: []string{"jobs", "1", "-1"}},
+		{Action: parser.LTRIM, Args: []string{"jobs", "5", "10"}},
+	}, "1 rpush jobs a b c d\n2 lpush jobs z\n3 lpop jobs z\n4 rpop jobs d c\n5 ltrim jobs 1 -1\n6 ltrim jobs 5 10\n7 del jobs", []string{
+		"rpush jobs", "lpush jobs", "lpop jobs", "rpop jobs", "ltrim jobs", "ltrim jobs", "del jobs",
+	})
+
+	// элемент, переданный ожидающему клиенту, попадает в журнал как извлеченный
+	popped := make(chan string, 1)
+	go func() {
+		value, err := storage.Execute(context.Background(), &parser.Command{Action: parser.BLPOP, Args: []string{"queue", "5"}})
+		assert.NoError(t, err)
+		popped <- value
+	}()
+
+	require.Eventually(t, func() bool {
+		storage.listWaiters.mu.Lock()
+		defer storage.listWaiters.mu.Unlock()
+
+		return len(storage.listWaiters.queues["queue"]) == 1
+	}, time.Second, time.Millisecond)
+
+	assertRecorded(t, storage, sub, []*parser.Command{
+		{Action: parser.RPUSH, Args: []string{"queue", "x"}},
+	}, "1 rpush jobs a b c d\n2 lpush jobs z\n3 lpop jobs z\n4 rpop jobs d c\n5 ltrim jobs 1 -1\n6 ltrim jobs 5 10\n7 del jobs\n"+
+		"8 rpush queue x\n9 lpop queue x\n10 del queue", []string{
+		"rpush queue", "lpop queue", "del queue",
+	})
+	assert.Equal(t, "queue x", <-popped)
+}
@@ -2,24 +2,47 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	engine "github.com/patyukin/mdb/internal/database/storage/engine"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // Engine is an autogenerated mock type for the Engine type
 type Engine struct {
 	mock.Mock
 }
 
-// Delete provides a mock function with given fields: key
-func (_m *Engine) Delete(key string) error {
-	ret := _m.Called(key)
+// CheckMemory provides a mock function with given fields:
+func (_m *Engine) CheckMemory() error {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for CheckMemory")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Delete provides a mock function with given fields: ctx, key
+func (_m *Engine) Delete(ctx context.Context, key string) error {
+	ret := _m.Called(ctx, key)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Delete")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(key)
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, key)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -27,9 +50,9 @@ func (_m *Engine) Delete(key string) error {
 	return r0
 }
 
-// Get provides a mock function with given fields: key
-func (_m *Engine) Get(key string) (string, error) {
-	ret := _m.Called(key)
+// Get provides a mock function with given fields: ctx, key
+func (_m *Engine) Get(ctx context.Context, key string) (string, error) {
+	ret := _m.Called(ctx, key)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Get")
@@ -37,17 +60,17 @@ func (_m *Engine) Get(key string) (string, error) {
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
-		return rf(key)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, key)
 	}
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(key)
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, key)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(key)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -55,9 +78,63 @@ func (_m *Engine) Get(key string) (string, error) {
 	return r0, r1
 }
 
-// Set provides a mock function with given fields: key, value
-func (_m *Engine) Set(key string, value string) {
-	_m.Called(key, value)
+// Set provides a mock function with given fields: ctx, key, value
+func (_m *Engine) Set(ctx context.Context, key string, value string) {
+	_m.Called(ctx, key, value)
+}
+
+// Stats provides a mock function with given fields:
+func (_m *Engine) Stats() engine.Stats {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Stats")
+	}
+
+	var r0 engine.Stats
+	if rf, ok := ret.Get(0).(func() engine.Stats); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(engine.Stats)
+	}
+
+	return r0
+}
+
+// Update provides a mock function with given fields: ctx, key, fn
+func (_m *Engine) Update(ctx context.Context, key string, fn func(engine.Value) (engine.Value, error)) error {
+	ret := _m.Called(ctx, key, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, func(engine.Value) (engine.Value, error)) error); ok {
+		r0 = rf(ctx, key, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// View provides a mock function with given fields: ctx, key, fn
+func (_m *Engine) View(ctx context.Context, key string, fn func(engine.Value) error) error {
+	ret := _m.Called(ctx, key, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for View")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, func(engine.Value) error) error); ok {
+		r0 = rf(ctx, key, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
 
 // NewEngine creates a new instance of Engine. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/session"
+)
+
+// pipelineCommand переключает режим ответов соединения. Новый режим действует
+// для запросов, прочитанных после ответа на эту команду
+func (s *Storage) pipelineCommand(ctx context.Context, args []string) (string, error) {
+	sess, ok := session.FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("%w: no session", parser.ErrInvalidArgument)
+	}
+
+	sess.SetFramed(strings.ToUpper(args[0]) == parser.FRAMED)
+
+	return "", nil
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/mocks"
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStorage_Execute_Pipeline(t *testing.T) {
+	storage := New(new(mocks.Engine), zap.NewNop())
+	sess := session.New("127.0.0.1:1000", "")
+	ctx := session.NewContext(context.Background(), sess)
+
+	_, err := storage.Execute(ctx, &parser.Command{Action: parser.PIPELINE, Args: []string{"framed"}})
+	require.NoError(t, err)
+	assert.True(t, sess.Framed())
+
+	_, err = storage.Execute(ctx, &parser.Command{Action: parser.PIPELINE, Args: []string{"ORDERED"}})
+	require.NoError(t, err)
+	assert.False(t, sess.Framed())
+
+	_, err = storage.Execute(context.Background(), &parser.Command{Action: parser.PIPELINE, Args: []string{"FRAMED"}})
+	assert.ErrorIs(t, err, parser.ErrInvalidArgument)
+}
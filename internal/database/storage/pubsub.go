@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/keyspace"
+	"github.com/patyukin/mdb/internal/pubsub"
+	"github.com/patyukin/mdb/internal/session"
+)
+
+// WithPubSub подключает брокер для команд SUBSCRIBE, PSUBSCRIBE и PUBLISH
+func WithPubSub(b *pubsub.Broker) Option {
+	return func(s *Storage) {
+		s.broker = b
+	}
+}
+
+// pubSubCommand меняет подписки соединения или публикует сообщение. Подписки возвращают
+// число оставшихся у соединения подписок, PUBLISH - число получателей
+func (s *Storage) pubSubCommand(ctx context.Context, action string, args []string) (string, error) {
+	if s.broker == nil {
+		return "", fmt.Errorf("%w: pub/sub is disabled", parser.ErrInvalidArgument)
+	}
+
+	if action == parser.PUBLISH {
+		// клиенты не могут подделать уведомление об изменении ключа
+		if keyspace.IsNotificationChannel(args[0]) {
+			return "", fmt.Errorf("%w: channel %s is reserved for keyspace notifications", parser.ErrInvalidArgument, args[0])
+		}
+
+		return strconv.Itoa(s.broker.Publish(args[0], args[1])), nil
+	}
+
+	sess, ok := session.FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("%w: no session", parser.ErrInvalidArgument)
+	}
+
+	sub, ok := sess.Subscriber()
+	if !ok {
+		return "", fmt.Errorf("%w: the connection cannot receive messages", parser.ErrInvalidArgument)
+	}
+
+	if s.auth != nil {
+		// уведомления об изменении ключей получают только пользователи с доступом к ключу
+		sub.SetFilter(func(m pubsub.Message) bool {
+			key, ok := keyspace.MessageKey(m)
+			return !ok || s.auth.AllowsKey(sess.User(), key)
+		})
+	}
+
+	var subscriptions int
+	switch action {
+	case parser.SUBSCRIBE:
+		subscriptions = s.broker.Subscribe(sub, args...)
+	case parser.UNSUBSCRIBE:
+		subscriptions = s.broker.Unsubscribe(sub, args...)
+	case parser.PSUBSCRIBE:
+		subscriptions = s.broker.PSubscribe(sub, args...)
+	case parser.PUNSUBSCRIBE:
+		subscriptions = s.broker.PUnsubscribe(sub, args...)
+	}
+
+	return strconv.Itoa(subscriptions), nil
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/patyukin/mdb/internal/auth"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/patyukin/mdb/internal/database/storage/mocks"
+	"github.com/patyukin/mdb/internal/keyspace"
+	"github.com/patyukin/mdb/internal/pubsub"
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStorage_Execute_PubSub(t *testing.T) {
+	broker := pubsub.NewBroker(10)
+	storage := New(new(mocks.Engine), zap.NewNop(), WithPubSub(broker))
+
+	sess := session.New("127.0.0.1:1000", "")
+	sub := broker.NewSubscriber()
+	sess.SetSubscriber(sub)
+	ctx := session.NewContext(context.Background(), sess)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		command  *parser.Command
+		expected string
+		err      error
+	}{
+		{name: "Подписка на каналы", ctx: ctx, command: &parser.Command{Action: parser.SUBSCRIBE, Args: []string{"a", "b"}}, expected: "2"},
+		{name: "Подписка по шаблону", ctx: ctx, command: &parser.Command{Action: parser.PSUBSCRIBE, Args: []string{"cache:*", "__key*"}}, expected: "4"},
+		{name: "Публикация", ctx: context.Background(), command: &parser.Command{Action: parser.PUBLISH, Args: []string{"a", "hello"}}, expected: "1"},
+		{name: "Публикация по шаблону", ctx: context.Background(), command: &parser.Command{Action: parser.PUBLISH, Args: []string{"cache:x", "hi"}}, expected: "1"},
+		{name: "Отписка от канала", ctx: ctx, command: &parser.Command{Action: parser.UNSUBSCRIBE, Args: []string{"a"}}, expected: "3"},
+		{name: "Отписка от всех шаблонов", ctx: ctx, command: &parser.Command{Action: parser.PUNSUBSCRIBE}, expected: "1"},
+		{name: "Подделка уведомления о ключе", ctx: context.Background(), command: &parser.Command{Action: parser.PUBLISH, Args: []string{"__keyspace__:a", "del"}}, err: parser.ErrInvalidArgument},
+		{name: "Подделка уведомления о событии", ctx: context.Background(), command: &parser.Command{Action: parser.PUBLISH, Args: []string{"__keyevent__:del", "a"}}, err: parser.ErrInvalidArgument},
+		{name: "Без получателей", ctx: context.Background(), command: &parser.Command{Action: parser.PUBLISH, Args: []string{"a", "x"}}, expected: "0"},
+		{
+			name:    "Соединение без очереди сообщений",
+			ctx:     session.NewContext(context.Background(), session.New("127.0.0.1:1001", "")),
+			command: &parser.Command{Action: parser.SUBSCRIBE, Args: []string{"a"}},
+			err:     parser.ErrInvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := storage.Execute(tt.ctx, tt.command)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+
+	assert.Equal(t, []pubsub.Message{
+		{Channel: "a", Payload: "hello"},
+		{Pattern: "cache:*", Channel: "cache:x", Payload: "hi"},
+	}, sub.Drain())
+
+	_, err := New(new(mocks.Engine), zap.NewNop()).Execute(ctx, &parser.Command{Action: parser.PUBLISH, Args: []string{"a", "b"}})
+	assert.ErrorIs(t, err, parser.ErrInvalidArgument)
+}
+
+func TestStorage_Execute_KeyspaceEvents(t *testing.T) {
+	engine := new(mocks.Engine)
+	engine.On("CheckMemory").Return(nil).Once()
+	engine.On("Set", mock.Anything, "key1", "value1").Once()
+	engine.On("View", mock.Anything, "key1", mock.Anything).Return(nil).Once()
+	engine.On("Delete", mock.Anything, "key1").Return(nil).Once()
+	engine.On("View", mock.Anything, "missing", mock.Anything).Return(errors.New("key not found")).Once()
+
+	broker := pubsub.NewBroker(10)
+	sub := broker.NewSubscriber()
+	broker.PSubscribe(sub, "__keyevent__:*")
+	storage := New(engine, zap.NewNop(), WithNotifier(keyspace.NewNotifier(broker, keyspace.ClassKeyevent|keyspace.ClassAll)))
+
+	ctx := context.Background()
+	_, err := storage.Execute(ctx, &parser.Command{Action: SET, Args: []string{"key1", "value1"}})
+	require.NoError(t, err)
+	_, err = storage.Execute(ctx, &parser.Command{Action: DELETE, Args: []string{"key1"}})
+	require.NoError(t, err)
+	// неудачное удаление событие не порождает
+	_, err = storage.Execute(ctx, &parser.Command{Action: DELETE, Args: []string{"missing"}})
+	require.Error(t, err)
+
+	assert.Equal(t, []pubsub.Message{
+		{Pattern: "__keyevent__:*", Channel: "__keyevent__:set", Payload: "key1"},
+		{Pattern: "__keyevent__:*", Channel: "__keyevent__:del", Payload: "key1"},
+	}, sub.Drain())
+	engine.AssertExpectations(t)
+}
+
+func TestStorage_Execute_KeyspaceEventsACL(t *testing.T) {
+	store := auth.NewStore()
+	require.NoError(t, store.ApplyRules("alice", []string{">secret", "+@all", "~user:*"}))
+
+	broker := pubsub.NewBroker(10)
+	notifier := keyspace.NewNotifier(broker, keyspace.ClassKeyspace|keyspace.ClassKeyevent|keyspace.ClassAll)
+	storage := New(engine.New(), zap.NewNop(), WithPubSub(broker), WithNotifier(notifier), WithAuth(store))
+
+	sess := session.New("127.0.0.1:1000", "")
+	sess.SetUser("alice")
+	sub := broker.NewSubscriber()
+	sess.SetSubscriber(sub)
+	ctx := session.NewContext(context.Background(), sess)
+
+	_, err := storage.Execute(ctx, &parser.Command{Action: parser.PSUBSCRIBE, Args: []string{"__key*__:*", "news"}})
+	require.NoError(t, err)
+
+	for _, key := range []string{"user:1", "billing:1"} {
+		_, err = storage.Execute(context.Background(), &parser.Command{Action: SET, Args: []string{key, "value"}})
+		require.NoError(t, err)
+	}
+	_, err = storage.Execute(context.Background(), &parser.Command{Action: parser.PUBLISH, Args: []string{"news", "billing:1"}})
+	require.NoError(t, err)
+
+	// уведомления о ключах вне шаблонов пользователя не доставляются, обычные сообщения - доставляются
+	var received []string
+	for _, m := range sub.Drain() {
+		received = append(received, m.Channel+" "+m.Payload)
+	}
+	assert.Equal(t, []string{"__keyspace__:user:1 set", "__keyevent__:set user:1", "news billing:1"}, received)
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/patyukin/mdb/internal/cdc"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+)
+
+func (s *Storage) viewSet(ctx context.Context, key string, fn func(set *engine.Set) error) error {
+	return viewCollection(ctx, s.engine, key, engine.NewSet, fn)
+}
+
+func (s *Storage) mutateSet(ctx context.Context, key string, plan func(set *engine.Set) ([]cdc.Change, error)) error {
+	return mutateCollection(ctx, s, key, engine.NewSet, plan)
+}
+
+// applySet применяет к множеству изменение журнала
+func applySet(set *engine.Set, c cdc.Change) {
+	for _, member := range c.Args {
+		if c.Op == cdc.OpSAdd {
+			set.Add(member)
+		} else {
+			set.Remove(member)
+		}
+	}
+}
+
+func (s *Storage) setCommand(ctx context.Context, action string, args []string) (string, error) {
+	key := args[0]
+
+	var result string
+	var err error
+	switch action {
+	case parser.SADD:
+		err = s.mutateSet(ctx, key, func(set *engine.Set) ([]cdc.Change, error) {
+			added := existing(args[1:], func(member string) bool { return !set.Contains(member) })
+			result = strconv.Itoa(len(added))
+			if len(added) == 0 {
+				return nil, nil
+			}
+
+			return []cdc.Change{{Op: cdc.OpSAdd, Key: key, Args: added}}, nil
+		})
+	case parser.SREM:
+		err = s.mutateSet(ctx, key, func(set *engine.Set) ([]cdc.Change, error) {
+			removed := existing(args[1:], set.Contains)
+			result = strconv.Itoa(len(removed))
+			return removal(cdc.OpSRem, key, set, removed), nil
+		})
+	case parser.SISMEMBER:
+		err = s.viewSet(ctx, key, func(set *engine.Set) error {
+			result = boolResult(set.Contains(args[1]))
+			return nil
+		})
+	case parser.SMEMBERS:
+		err = s.viewSet(ctx, key, func(set *engine.Set) error {
+			result = formatMembers(set.Members())
+			return nil
+		})
+	case parser.SINTER, parser.SUNION, parser.SDIFF:
+		var members []string
+		if members, err = s.combineSets(ctx, action, args); err == nil {
+			result = formatMembers(members)
+		}
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed %s: %w", action, err)
+	}
+
+	return result, nil
+}
+
+// combineSets возвращает пересечение, объединение или разность множеств keys.
+// Отсутствующие ключи считаются пустыми множествами
+func (s *Storage) combineSets(ctx context.Context, action string, keys []string) ([]string, error) {
+	var combined map[string]struct{}
+	for i, key := range keys {
+		err := s.viewSet(ctx, key, func(set *engine.Set) error {
+			if i == 0 {
+				combined = make(map[string]struct{}, set.Len())
+				for _, member := range set.Members() {
+					combined[member] = struct{}{}
+				}
+
+				return nil
+			}
+
+			switch action {
+			case parser.SINTER:
+				for member := range combined {
+					if !set.Contains(member) {
+						delete(combined, member)
+					}
+				}
+			case parser.SUNION:
+				for _, member := range set.Members() {
+					combined[member] = struct{}{}
+				}
+			case parser.SDIFF:
+				for member := range combined {
+					if set.Contains(member) {
+						delete(combined, member)
+					}
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	members := make([]string, 0, len(combined))
+	for member := range combined {
+		members = append(members, member)
+	}
+
+	sort.Strings(members)
+
+	return members, nil
+}
+
+// formatMembers возвращает элементы по одному в строке
+func formatMembers(members []string) string {
+	for i, member := range members {
+		members[i] = parser.Quote(member)
+	}
+
+	return strings.Join(members, "\n")
+}
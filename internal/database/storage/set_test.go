@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStorage_Execute_Set(t *testing.T) {
+	storage := New(engine.New(), zap.NewNop())
+
+	tests := []struct {
+		name     string
+		command  *parser.Command
+		expected string
+		err      error
+	}{
+		{name: "Добавление элементов", command: &parser.Command{Action: parser.SADD, Args: []string{"a", "x", "y", "z", "x"}}, expected: "3"},
+		{name: "Повторное добавление", command: &parser.Command{Action: parser.SADD, Args: []string{"a", "x", "w v"}}, expected: "1"},
+		{name: "Элементы по порядку", command: &parser.Command{Action: parser.SMEMBERS, Args: []string{"a"}}, expected: "\"w v\"\nx\ny\nz"},
+		{name: "Элемент есть", command: &parser.Command{Action: parser.SISMEMBER, Args: []string{"a", "y"}}, expected: "1"},
+		{name: "Элемента нет", command: &parser.Command{Action: parser.SISMEMBER, Args: []string{"a", "q"}}, expected: "0"},
+		{name: "Второе множество", command: &parser.Command{Action: parser.SADD, Args: []string{"b", "y", "z", "q"}}, expected: "3"},
+		{name: "Пересечение", command: &parser.Command{Action: parser.SINTER, Args: []string{"a", "b"}}, expected: "y\nz"},
+		{name: "Пересечение с отсутствующим", command: &parser.Command{Action: parser.SINTER, Args: []string{"a", "missing"}}, expected: ""},
+		{name: "Объединение", command: &parser.Command{Action: parser.SUNION, Args: []string{"a", "b", "missing"}}, expected: "q\n\"w v\"\nx\ny\nz"},
+		{name: "Разность", command: &parser.Command{Action: parser.SDIFF, Args: []string{"a", "b"}}, expected: "\"w v\"\nx"},
+		{name: "Удаление", command: &parser.Command{Action: parser.SREM, Args: []string{"b", "q", "missing"}}, expected: "1"},
+		{name: "Тип множества", command: &parser.Command{Action: parser.TYPE, Args: []string{"a"}}, expected: "set"},
+		{name: "Удаление последних элементов", command: &parser.Command{Action: parser.SREM, Args: []string{"b", "y", "z"}}, expected: "2"},
+		{name: "Пустое множество удалено", command: &parser.Command{Action: parser.TYPE, Args: []string{"b"}}, expected: "none"},
+		{name: "Строка", command: &parser.Command{Action: parser.SET, Args: []string{"greeting", "hello"}}},
+		{name: "Тип строки", command: &parser.Command{Action: parser.TYPE, Args: []string{"greeting"}}, expected: "string"},
+		{name: "Команда множества для строки", command: &parser.Command{Action: parser.SADD, Args: []string{"greeting", "x"}}, err: engine.ErrWrongType},
+		{name: "Строка среди множеств", command: &parser.Command{Action: parser.SUNION, Args: []string{"a", "greeting"}}, err: engine.ErrWrongType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := storage.Execute(context.Background(), tt.command)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestStorage_Execute_SetChanges(t *testing.T) {
+	storage, sub := newRecordingStorage(t)
+
+	assertRecorded(t, storage, sub, []*parser.Command{
+		{Action: parser.SADD, Args: []string{"tags", "go", "db", "go"}},
+		// повторное добавление ничего не меняет
+		{Action: parser.SADD, Args: []string{"tags", "db"}},
+		{Action: parser.SREM, Args: []string{"tags", "go", "missing"}},
+		{Action: parser.SREM, Args: []string{"tags", "db"}},
+	}, "1 sadd tags go db\n2 srem tags go\n3 srem tags db\n4 del tags", []string{
+		"sadd tags", "srem tags", "srem tags", "del tags",
+	})
+}
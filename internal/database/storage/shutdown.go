@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+)
+
+// ShutdownFunc запускает остановку сервера. save сообщает, нужно ли перед остановкой
+// сбросить на диск данные подсистем хранения
+type ShutdownFunc func(save bool)
+
+// WithShutdown подключает остановку сервера для команды SHUTDOWN
+func WithShutdown(fn ShutdownFunc) Option {
+	return func(s *Storage) {
+		s.shutdown = fn
+	}
+}
+
+// shutdownCommand запускает остановку и сразу отвечает клиенту: ответ отправляется
+// до закрытия соединения, поскольку сервер дожидается выполняемых запросов
+func (s *Storage) shutdownCommand(args []string) (string, error) {
+	if s.shutdown == nil {
+		return "", fmt.Errorf("%w: shutdown is not available", parser.ErrUnknownCommand)
+	}
+
+	save := len(args) == 0 || strings.ToUpper(args[0]) == parser.SAVE
+	s.shutdown(save)
+
+	return "", nil
+}
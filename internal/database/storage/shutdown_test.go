@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStorage_Execute_Shutdown(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		expectedSave bool
+	}{
+		{name: "Без аргументов", args: nil, expectedSave: true},
+		{name: "SAVE", args: []string{"SAVE"}, expectedSave: true},
+		{name: "NOSAVE", args: []string{"nosave"}, expectedSave: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []bool
+			storage := New(new(mocks.Engine), zap.NewNop(), WithShutdown(func(save bool) {
+				calls = append(calls, save)
+			}))
+
+			result, err := storage.Execute(context.Background(), &parser.Command{Action: "SHUTDOWN", Args: tt.args})
+			require.NoError(t, err)
+			assert.Equal(t, "", result)
+			assert.Equal(t, []bool{tt.expectedSave}, calls)
+		})
+	}
+
+	_, err := New(new(mocks.Engine), zap.NewNop()).Execute(context.Background(), &parser.Command{Action: "SHUTDOWN"})
+	assert.ErrorIs(t, err, parser.ErrUnknownCommand)
+
+	_, err = New(new(mocks.Engine), zap.NewNop(), WithShutdown(func(bool) {})).
+		Execute(context.Background(), &parser.Command{Action: "SHUTDOWN", Args: []string{"NOW"}})
+	assert.ErrorIs(t, err, parser.ErrUnknownCommand)
+}
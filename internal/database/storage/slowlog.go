@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/slowlog"
+)
+
+// defaultSlowLogCount - сколько записей возвращает SLOWLOG GET без аргумента
+const defaultSlowLogCount = 10
+
+// WithSlowLog подключает журнал медленных запросов для команды SLOWLOG
+func WithSlowLog(l *slowlog.Log) Option {
+	return func(s *Storage) {
+		s.slowLog = l
+	}
+}
+
+func (s *Storage) slowLogCommand(args []string) (string, error) {
+	if s.slowLog == nil {
+		return "", fmt.Errorf("%w: slow log is not configured", parser.ErrUnknownCommand)
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case parser.LEN:
+		return strconv.Itoa(s.slowLog.Len()), nil
+	case parser.RESET:
+		s.slowLog.Reset()
+		return "", nil
+	default:
+		count := defaultSlowLogCount
+		if len(args) == 2 {
+			var err error
+			count, err = strconv.Atoi(args[1])
+			if err != nil || count < -1 {
+				return "", fmt.Errorf("%w: count must be an integer not less than -1: %s", parser.ErrInvalidArgument, args[1])
+			}
+		}
+
+		var b strings.Builder
+		for _, entry := range s.slowLog.Get(count) {
+			fmt.Fprintf(&b, "# %d\n", entry.ID)
+			fmt.Fprintf(&b, "timestamp:%d\n", entry.Time.Unix())
+			fmt.Fprintf(&b, "duration_us:%d\n", entry.Duration.Microseconds())
+			args := make([]string, len(entry.Args))
+			for i, arg := range entry.Args {
+				args[i] = parser.Quote(arg)
+			}
+			fmt.Fprintf(&b, "command:%s\n", strings.Join(args, " "))
+			fmt.Fprintf(&b, "client:%s\n", entry.ClientAddr)
+		}
+
+		return strings.TrimSuffix(b.String(), "\n"), nil
+	}
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/slowlog"
+	"github.com/patyukin/mdb/internal/database/storage/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStorage_Execute_SlowLog(t *testing.T) {
+	l := slowlog.New(time.Millisecond, 10)
+	l.Record(context.Background(), []string{"SET", "key1", "value 1\n"}, 2*time.Millisecond)
+	l.Record(context.Background(), []string{"GET", "key1"}, 3*time.Millisecond)
+
+	storage := New(new(mocks.Engine), zap.NewNop(), WithSlowLog(l))
+	execute := func(args ...string) (string, error) {
+		return storage.Execute(context.Background(), &parser.Command{Action: "SLOWLOG", Args: args})
+	}
+
+	result, err := execute("LEN")
+	require.NoError(t, err)
+	assert.Equal(t, "2", result)
+
+	result, err = execute("get", "1")
+	require.NoError(t, err)
+	assert.Regexp(t, `^# 2\ntimestamp:\d+\nduration_us:3000\ncommand:GET key1\nclient:$`, result)
+
+	result, err = execute("GET")
+	require.NoError(t, err)
+	assert.Contains(t, result, "# 1\n")
+	// аргументы выводятся в кавычках, чтобы перевод строки не разорвал ответ
+	assert.Contains(t, result, `command:SET key1 "value 1\n"`+"\n")
+
+	_, err = execute("GET", "many")
+	assert.ErrorIs(t, err, parser.ErrInvalidArgument)
+
+	result, err = execute("RESET")
+	require.NoError(t, err)
+	assert.Equal(t, "", result)
+	assert.Equal(t, 0, l.Len())
+
+	_, err = execute("FLUSH")
+	assert.ErrorIs(t, err, parser.ErrUnknownCommand)
+}
+
+func TestStorage_Execute_SlowLogNotConfigured(t *testing.T) {
+	storage := New(new(mocks.Engine), zap.NewNop())
+
+	_, err := storage.Execute(context.Background(), &parser.Command{Action: "SLOWLOG", Args: []string{"LEN"}})
+	assert.ErrorIs(t, err, parser.ErrUnknownCommand)
+}
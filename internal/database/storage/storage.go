@@ -1,9 +1,23 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/patyukin/mdb/internal/auth"
+	"github.com/patyukin/mdb/internal/cdc"
+	"github.com/patyukin/mdb/internal/config"
 	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/slowlog"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/patyukin/mdb/internal/keyspace"
+	"github.com/patyukin/mdb/internal/pubsub"
+	"github.com/patyukin/mdb/internal/trace"
 	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -12,53 +26,231 @@ const (
 	DELETE = "DEL"
 )
 
+var (
+	ErrReadOnly = errors.New("storage is read-only")
+	ErrTimeout  = errors.New("operation timed out")
+)
+
 //go:generate go run github.com/vektra/mockery/v2@v2.45.1 --name=Engine --output ./mocks
 type Engine interface {
-	Set(key string, value string)
-	Get(key string) (string, error)
-	Delete(key string) error
+	Set(ctx context.Context, key string, value string)
+	Get(ctx context.Context, key string) (string, error)
+	Delete(ctx context.Context, key string) error
+	View(ctx context.Context, key string, fn func(v engine.Value) error) error
+	Update(ctx context.Context, key string, fn func(v engine.Value) (engine.Value, error)) error
+	Stats() engine.Stats
+	CheckMemory() error
 }
 
 type Storage struct {
-	engine Engine
-	logger *zap.Logger
+	engine    Engine
+	logger    *zap.Logger
+	startedAt time.Time
+	sections  []infoSection
+	processed atomic.Uint64
+	slowLog   *slowlog.Log
+	config    *config.Manager
+	shutdown  ShutdownFunc
+	auth      *auth.Store
+	broker    *pubsub.Broker
+	notifier  *keyspace.Notifier
+	changes   *cdc.Log
+	readOnly  atomic.Bool
+	// writeMu упорядочивает изменения ключей, см. mutate
+	writeMu sync.Mutex
+	// listWaiters - клиенты, ожидающие элементы в BLPOP и BRPOP
+	listWaiters listWaiters
 }
 
-func New(e Engine, l *zap.Logger) *Storage {
-	return &Storage{
-		engine: e,
-		logger: l,
+// Option настраивает Storage
+type Option func(*Storage)
+
+func New(e Engine, l *zap.Logger, options ...Option) *Storage {
+	s := &Storage{
+		engine:    e,
+		logger:    l,
+		startedAt: time.Now(),
 	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
 }
 
-func (s *Storage) Execute(command *parser.Command) (string, error) {
+func (s *Storage) Execute(ctx context.Context, command *parser.Command) (string, error) {
+	end := trace.StartSpan(ctx, "validate")
 	err := command.Validate()
+	end()
 	if err != nil {
 		return "", fmt.Errorf("failed command.Validate, %w", err)
 	}
 
-	s.logger.Info("Executing command", zap.String("action", command.Action), zap.Strings("args", command.Args))
+	// проверка непосредственно перед обращением к движку: отмененный запрос не должен менять данные
+	if err = contextError(ctx); err != nil {
+		return "", err
+	}
+
+	s.processed.Add(1)
+	// пароли AUTH и ACL SETUSER не попадают в лог
+	args := command.Args
+	switch command.Action {
+	case parser.AUTH:
+		args = args[:1]
+	case parser.ACL:
+		args = args[:min(len(args), 2)]
+	}
+	trace.Logger(ctx, s.logger).Debug("Executing command", zap.String("action", command.Action), zap.Strings("args", args))
+
+	defer trace.StartSpan(ctx, "execute")()
+
 	switch command.Action {
 	case GET:
 		key := command.Args[0]
 		var value string
-		value, err = s.engine.Get(key)
+		value, err = s.engine.Get(ctx, key)
 		if err != nil {
 			return "", fmt.Errorf("failed s.engine.Get, err: %w", err)
 		}
 
-		return value, nil
+		return parser.QuoteValue(ctx, value), nil
 	case SET:
-		s.engine.Set(command.Args[0], command.Args[1])
-		return "", nil
+		return "", s.mutate(ctx, func() ([]cdc.Change, error) {
+			return []cdc.Change{{Op: cdc.OpSet, Key: command.Args[0], Value: command.Args[1]}}, nil
+		})
 	case DELETE:
 		key := command.Args[0]
-		if err = s.engine.Delete(key); err != nil {
-			return "", fmt.Errorf("failed s.engine.Delete, err: %w", err)
+		return "", s.mutate(ctx, func() ([]cdc.Change, error) {
+			if err := s.engine.View(ctx, key, func(engine.Value) error { return nil }); err != nil {
+				return nil, fmt.Errorf("failed s.engine.View, err: %w", err)
+			}
+
+			return []cdc.Change{{Op: cdc.OpDel, Key: key}}, nil
+		})
+	case parser.PING:
+		if len(command.Args) == 1 {
+			return command.Args[0], nil
 		}
 
-		return "", nil
+		return "PONG", nil
+	case parser.TIME:
+		now := time.Now()
+		return fmt.Sprintf("%d %d", now.Unix(), now.Nanosecond()/int(time.Microsecond)), nil
+	case parser.DBSIZE:
+		return fmt.Sprintf("%d", s.engine.Stats().Keys), nil
+	case parser.INFO:
+		section := ""
+		if len(command.Args) == 1 {
+			section = command.Args[0]
+		}
+
+		return s.info(section), nil
+	case parser.COMMAND:
+		return commandDocs(command.Args[1:]), nil
+	case parser.SLOWLOG:
+		return s.slowLogCommand(command.Args)
+	case parser.CONFIG:
+		return s.configCommand(command.Args)
+	case parser.SHUTDOWN:
+		return s.shutdownCommand(command.Args)
+	case parser.AUTH:
+		return s.authCommand(ctx, command.Args)
+	case parser.ACL:
+		return s.aclCommand(ctx, command.Args)
+	case parser.PIPELINE:
+		return s.pipelineCommand(ctx, command.Args)
+	case parser.SUBSCRIBE, parser.UNSUBSCRIBE, parser.PSUBSCRIBE, parser.PUNSUBSCRIBE, parser.PUBLISH:
+		return s.pubSubCommand(ctx, command.Action, command.Args)
+	case parser.CDC:
+		return s.cdcCommand(command.Args)
+	case parser.HSET, parser.HGET, parser.HMGET, parser.HDEL, parser.HEXISTS, parser.HLEN, parser.HKEYS, parser.HGETALL, parser.HINCRBY:
+		return s.hashCommand(ctx, command.Action, command.Args)
+	case parser.LPUSH, parser.RPUSH, parser.LPOP, parser.RPOP, parser.LRANGE, parser.LLEN, parser.LINDEX, parser.LTRIM, parser.BLPOP, parser.BRPOP:
+		return s.listCommand(ctx, command.Action, command.Args)
+	case parser.SADD, parser.SREM, parser.SISMEMBER, parser.SMEMBERS, parser.SINTER, parser.SUNION, parser.SDIFF:
+		return s.setCommand(ctx, command.Action, command.Args)
+	case parser.ZADD, parser.ZREM, parser.ZSCORE, parser.ZRANK, parser.ZRANGE, parser.ZINCRBY:
+		return s.sortedSetCommand(ctx, command.Action, command.Args)
+	case parser.TYPE:
+		return s.typeCommand(ctx, command.Args[0])
 	default:
-		return "", fmt.Errorf("unknown command: %s", command.Action)
+		return "", fmt.Errorf("%w: %s", parser.ErrUnknownCommand, command.Action)
+	}
+}
+
+// typeCommand возвращает имя типа значения ключа или none для отсутствующего ключа
+func (s *Storage) typeCommand(ctx context.Context, key string) (string, error) {
+	var t engine.Type
+	err := s.engine.View(ctx, key, func(v engine.Value) error {
+		t = v.Type()
+		return nil
+	})
+	if errors.Is(err, engine.ErrNotFound) {
+		return "none", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed %s: %w", parser.TYPE, err)
+	}
+
+	return string(t), nil
+}
+
+// WithNotifier сообщает об изменениях ключей подписчикам уведомлений
+func WithNotifier(n *keyspace.Notifier) Option {
+	return func(s *Storage) {
+		s.notifier = n
+	}
+}
+
+// WithReadOnly запрещает изменение ключей: такие команды завершаются ErrReadOnly
+func WithReadOnly(readOnly bool) Option {
+	return func(s *Storage) {
+		s.readOnly.Store(readOnly)
 	}
 }
+
+// SetReadOnly включает и выключает запрет изменения ключей без перезапуска
+func (s *Storage) SetReadOnly(readOnly bool) {
+	s.readOnly.Store(readOnly)
+}
+
+func (s *Storage) notify(event, key string) {
+	if s.notifier != nil {
+		s.notifier.Notify(event, key)
+	}
+}
+
+func contextError(ctx context.Context) error {
+	err := ctx.Err()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+
+	return err
+}
+
+// commandDocs возвращает справку по командам в формате ключ:значение
+func commandDocs(names []string) string {
+	specs := parser.Commands()
+	if len(names) > 0 {
+		specs = specs[:0]
+		for _, name := range names {
+			if spec, ok := parser.LookupCommand(strings.ToUpper(name)); ok {
+				specs = append(specs, spec)
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, spec := range specs {
+		fmt.Fprintf(&b, "# %s\n", spec.Name)
+		fmt.Fprintf(&b, "summary:%s\n", spec.Summary)
+		fmt.Fprintf(&b, "syntax:%s\n", strings.TrimSpace(spec.Name+" "+spec.Arguments))
+		fmt.Fprintf(&b, "group:%s\n", spec.Group)
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// nilValue обозначает в многострочных ответах отсутствующее значение
+const nilValue = parser.NilValue
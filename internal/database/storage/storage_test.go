@@ -1,13 +1,17 @@
 package storage
 
 import (
+	"context"
 	"errors"
 	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
 	"github.com/patyukin/mdb/internal/database/storage/mocks"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
 )
 
@@ -31,7 +35,8 @@ func TestStorage_Execute(t *testing.T) {
 				Args:   []string{"key1", "value1"},
 			},
 			setupMocks: func() {
-				mockEngine.On("Set", "key1", "value1").Once()
+				mockEngine.On("CheckMemory").Return(nil).Once()
+				mockEngine.On("Set", mock.Anything, "key1", "value1").Once()
 			},
 			expected:    "",
 			expectedErr: nil,
@@ -43,7 +48,7 @@ func TestStorage_Execute(t *testing.T) {
 				Args:   []string{"key1"},
 			},
 			setupMocks: func() {
-				mockEngine.On("Get", "key1").Return("value1", nil).Once()
+				mockEngine.On("Get", mock.Anything, "key1").Return("value1", nil).Once()
 			},
 			expected:    "value1",
 			expectedErr: nil,
@@ -55,7 +60,7 @@ func TestStorage_Execute(t *testing.T) {
 				Args:   []string{"*"},
 			},
 			setupMocks: func() {
-				mockEngine.On("Get", "*").Return("value1", nil).Once()
+				mockEngine.On("Get", mock.Anything, "*").Return("value1", nil).Once()
 			},
 			expected:    "value1",
 			expectedErr: nil,
@@ -67,7 +72,7 @@ func TestStorage_Execute(t *testing.T) {
 				Args:   []string{"nonexistent*"},
 			},
 			setupMocks: func() {
-				mockEngine.On("Get", "nonexistent*").Return("", errors.New("key not found")).Once()
+				mockEngine.On("Get", mock.Anything, "nonexistent*").Return("", errors.New("key not found")).Once()
 			},
 			expected:    "",
 			expectedErr: errors.New("failed s.engine.Get, err: key not found"),
@@ -79,7 +84,8 @@ func TestStorage_Execute(t *testing.T) {
 				Args:   []string{"key1"},
 			},
 			setupMocks: func() {
-				mockEngine.On("Delete", "key1").Return(nil).Once()
+				mockEngine.On("View", mock.Anything, "key1", mock.Anything).Return(nil).Once()
+				mockEngine.On("Delete", mock.Anything, "key1").Return(nil).Once()
 			},
 			expected:    "",
 			expectedErr: nil,
@@ -91,7 +97,8 @@ func TestStorage_Execute(t *testing.T) {
 				Args:   []string{"*"},
 			},
 			setupMocks: func() {
-				mockEngine.On("Delete", "*").Return(nil).Once()
+				mockEngine.On("View", mock.Anything, "*", mock.Anything).Return(nil).Once()
+				mockEngine.On("Delete", mock.Anything, "*").Return(nil).Once()
 			},
 			expected:    "",
 			expectedErr: nil,
@@ -103,7 +110,8 @@ func TestStorage_Execute(t *testing.T) {
 				Args:   []string{"*"},
 			},
 			setupMocks: func() {
-				mockEngine.On("Delete", "*").Return(errors.New("del by pattern failed")).Once()
+				mockEngine.On("View", mock.Anything, "*", mock.Anything).Return(nil).Once()
+				mockEngine.On("Delete", mock.Anything, "*").Return(errors.New("del by pattern failed")).Once()
 			},
 			expected:    "",
 			expectedErr: errors.New("failed s.engine.Delete, err: del by pattern failed"),
@@ -144,7 +152,7 @@ func TestStorage_Execute(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setupMocks()
 
-			result, err := storage.Execute(tt.command)
+			result, err := storage.Execute(context.Background(), tt.command)
 
 			if tt.expectedErr != nil {
 				assert.Error(t, err)
@@ -167,7 +175,7 @@ func TestStorage_Execute_EmptyArgs(t *testing.T) {
 		Args:   []string{},
 	}
 
-	result, err := storage.Execute(command)
+	result, err := storage.Execute(context.Background(), command)
 	assert.Error(t, err)
 	assert.Equal(t, "", result)
 	assert.EqualError(t, err, "failed command.Validate, 2 arguments required for SET command")
@@ -177,7 +185,7 @@ func TestStorage_Execute_EmptyArgs(t *testing.T) {
 		Args:   []string{},
 	}
 
-	result, err = storage.Execute(command)
+	result, err = storage.Execute(context.Background(), command)
 	assert.Error(t, err)
 	assert.Equal(t, "", result)
 	assert.EqualError(t, err, "failed command.Validate, command GET requires 1 argument")
@@ -187,7 +195,7 @@ func TestStorage_Execute_EmptyArgs(t *testing.T) {
 		Args:   []string{},
 	}
 
-	result, err = storage.Execute(command)
+	result, err = storage.Execute(context.Background(), command)
 	assert.Error(t, err)
 	assert.Equal(t, "", result)
 	assert.EqualError(t, err, "failed command.Validate, command DEL requires 1 argument")
@@ -203,7 +211,7 @@ func TestStorage_Execute_ExtraArgs(t *testing.T) {
 		Args:   []string{"key1", "value1", "extra"},
 	}
 
-	result, err := storage.Execute(command)
+	result, err := storage.Execute(context.Background(), command)
 	assert.Error(t, err)
 	assert.Equal(t, "", result)
 	assert.EqualError(t, err, "failed command.Validate, 2 arguments required for SET command")
@@ -213,7 +221,7 @@ func TestStorage_Execute_ExtraArgs(t *testing.T) {
 		Args:   []string{"key1", "extra"},
 	}
 
-	result, err = storage.Execute(command)
+	result, err = storage.Execute(context.Background(), command)
 	assert.Error(t, err)
 	assert.Equal(t, "", result)
 	assert.EqualError(t, err, "failed command.Validate, command GET requires 1 argument")
@@ -223,7 +231,7 @@ func TestStorage_Execute_ExtraArgs(t *testing.T) {
 		Args:   []string{"key1", "extra"},
 	}
 
-	result, err = storage.Execute(command)
+	result, err = storage.Execute(context.Background(), command)
 	assert.Error(t, err)
 	assert.Equal(t, "", result)
 	assert.EqualError(t, err, "failed command.Validate, command DEL requires 1 argument")
@@ -239,9 +247,9 @@ func TestStorage_Execute_InvalidArgs(t *testing.T) {
 		Args:   []string{"key@!"},
 	}
 
-	mockEngine.On("Get", "key@!").Return("", errors.New("invalid key")).Once()
+	mockEngine.On("Get", mock.Anything, "key@!").Return("", errors.New("invalid key")).Once()
 
-	result, err := storage.Execute(command)
+	result, err := storage.Execute(context.Background(), command)
 	assert.Error(t, err)
 	assert.Equal(t, "", result)
 	assert.EqualError(t, err, "failed s.engine.Get, err: invalid key")
@@ -257,9 +265,10 @@ func TestStorage_Execute_Set_Get(t *testing.T) {
 		Args:   []string{"key1", "value1"},
 	}
 
-	mockEngine.On("Set", "key1", "value1").Once()
+	mockEngine.On("CheckMemory").Return(nil).Once()
+	mockEngine.On("Set", mock.Anything, "key1", "value1").Once()
 
-	result, err := storage.Execute(setCommand)
+	result, err := storage.Execute(context.Background(), setCommand)
 	assert.NoError(t, err)
 	assert.Equal(t, "", result)
 
@@ -268,9 +277,9 @@ func TestStorage_Execute_Set_Get(t *testing.T) {
 		Args:   []string{"key1"},
 	}
 
-	mockEngine.On("Get", "key1").Return("value1", nil).Once()
+	mockEngine.On("Get", mock.Anything, "key1").Return("value1", nil).Once()
 
-	result, err = storage.Execute(getCommand)
+	result, err = storage.Execute(context.Background(), getCommand)
 	assert.NoError(t, err)
 	assert.Equal(t, "value1", result)
 }
@@ -291,9 +300,10 @@ func TestStorage_Execute_Logger(t *testing.T) {
 		Args:   []string{"key1", "value1"},
 	}
 
-	mockEngine.On("Set", "key1", "value1").Once()
+	mockEngine.On("CheckMemory").Return(nil).Once()
+	mockEngine.On("Set", mock.Anything, "key1", "value1").Once()
 
-	result, err := storage.Execute(command)
+	result, err := storage.Execute(context.Background(), command)
 	assert.NoError(t, err)
 	assert.Equal(t, "", result)
 }
@@ -308,9 +318,10 @@ func TestStorage_Execute_MultipleCommands(t *testing.T) {
 		Args:   []string{"key1", "value1"},
 	}
 
-	mockEngine.On("Set", "key1", "value1").Once()
+	mockEngine.On("CheckMemory").Return(nil).Once()
+	mockEngine.On("Set", mock.Anything, "key1", "value1").Once()
 
-	result, err := storage.Execute(setCommand)
+	result, err := storage.Execute(context.Background(), setCommand)
 	assert.NoError(t, err)
 	assert.Equal(t, "", result)
 
@@ -319,9 +330,9 @@ func TestStorage_Execute_MultipleCommands(t *testing.T) {
 		Args:   []string{"key1"},
 	}
 
-	mockEngine.On("Get", "key1").Return("value1", nil).Once()
+	mockEngine.On("Get", mock.Anything, "key1").Return("value1", nil).Once()
 
-	result, err = storage.Execute(getCommand)
+	result, err = storage.Execute(context.Background(), getCommand)
 	assert.NoError(t, err)
 	assert.Equal(t, "value1", result)
 
@@ -330,9 +341,60 @@ func TestStorage_Execute_MultipleCommands(t *testing.T) {
 		Args:   []string{"key1"},
 	}
 
-	mockEngine.On("Delete", "key1").Return(nil).Once()
+	mockEngine.On("View", mock.Anything, "key1", mock.Anything).Return(nil).Once()
+	mockEngine.On("Delete", mock.Anything, "key1").Return(nil).Once()
 
-	result, err = storage.Execute(delCommand)
+	result, err = storage.Execute(context.Background(), delCommand)
 	assert.NoError(t, err)
 	assert.Equal(t, "", result)
 }
+
+func TestStorage_Execute_CanceledContext(t *testing.T) {
+	mockEngine := new(mocks.Engine)
+	storage := New(mockEngine, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := storage.Execute(ctx, &parser.Command{Action: "SET", Args: []string{"key1", "value1"}})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, "", result)
+
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	_, err = storage.Execute(ctx, &parser.Command{Action: "DEL", Args: []string{"key1"}})
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	mockEngine.AssertNotCalled(t, "Set", mock.Anything, mock.Anything)
+	mockEngine.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func TestStorage_Execute_WriteLimits(t *testing.T) {
+	eng := engine.New()
+	storage := New(eng, zap.NewNop(), WithReadOnly(true))
+	ctx := context.Background()
+
+	_, err := storage.Execute(ctx, &parser.Command{Action: parser.SET, Args: []string{"key", "value"}})
+	assert.ErrorIs(t, err, ErrReadOnly)
+
+	storage.SetReadOnly(false)
+	_, err = storage.Execute(ctx, &parser.Command{Action: parser.SET, Args: []string{"key", "value"}})
+	assert.NoError(t, err)
+
+	// при достижении предела памяти запрещено только добавление данных
+	eng.SetMaxMemory(8)
+	_, err = storage.Execute(ctx, &parser.Command{Action: parser.RPUSH, Args: []string{"list", "a"}})
+	assert.ErrorIs(t, err, engine.ErrOutOfMemory)
+
+	result, err := storage.Execute(ctx, &parser.Command{Action: parser.GET, Args: []string{"key"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "value", result)
+
+	_, err = storage.Execute(ctx, &parser.Command{Action: parser.DELETE, Args: []string{"key"}})
+	assert.NoError(t, err)
+
+	_, err = storage.Execute(ctx, &parser.Command{Action: parser.RPUSH, Args: []string{"list", "a"}})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/patyukin/mdb/internal/cdc"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+)
+
+func (s *Storage) viewSortedSet(ctx context.Context, key string, fn func(z *engine.SortedSet) error) error {
+	return viewCollection(ctx, s.engine, key, engine.NewSortedSet, fn)
+}
+
+func (s *Storage) mutateSortedSet(ctx context.Context, key string, plan func(z *engine.SortedSet) ([]cdc.Change, error)) error {
+	return mutateCollection(ctx, s, key, engine.NewSortedSet, plan)
+}
+
+// applySortedSet применяет к упорядоченному множеству изменение журнала
+func applySortedSet(z *engine.SortedSet, c cdc.Change) error {
+	switch c.Op {
+	case cdc.OpZAdd:
+		for i := 0; i+1 < len(c.Args); i += 2 {
+			score, err := parseScore(c.Args[i])
+			if err != nil {
+				return err
+			}
+
+			z.Add(c.Args[i+1], score)
+		}
+	case cdc.OpZRem:
+		for _, member := range c.Args {
+			z.Remove(member)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) sortedSetCommand(ctx context.Context, action string, args []string) (string, error) {
+	key := args[0]
+
+	var result string
+	var err error
+	switch action {
+	case parser.ZADD:
+		if len(args)%2 == 0 {
+			return "", fmt.Errorf("%w: %s expects score member pairs", parser.ErrWrongArity, action)
+		}
+
+		members := make([]engine.ScoredMember, 0, len(args)/2)
+		for i := 1; i < len(args); i += 2 {
+			score, parseErr := parseScore(args[i])
+			if parseErr != nil {
+				return "", parseErr
+			}
+
+			members = append(members, engine.ScoredMember{Member: args[i+1], Score: score})
+		}
+
+		err = s.mutateSortedSet(ctx, key, func(z *engine.SortedSet) ([]cdc.Change, error) {
+			// при повторе элемента действует последняя оценка
+			scores := make(map[string]float64, len(members))
+			order := make([]string, 0, len(members))
+			for _, m := range members {
+				if _, ok := scores[m.Member]; !ok {
+					order = append(order, m.Member)
+				}
+				scores[m.Member] = m.Score
+			}
+
+			added := 0
+			var pairs []string
+			for _, member := range order {
+				current, exists := z.Score(member)
+				if !exists {
+					added++
+				}
+
+				if !exists || current != scores[member] {
+					pairs = append(pairs, formatScore(scores[member]), member)
+				}
+			}
+
+			result = strconv.Itoa(added)
+			if len(pairs) == 0 {
+				return nil, nil
+			}
+
+			return []cdc.Change{{Op: cdc.OpZAdd, Key: key, Args: pairs}}, nil
+		})
+	case parser.ZREM:
+		err = s.mutateSortedSet(ctx, key, func(z *engine.SortedSet) ([]cdc.Change, error) {
+			removed := existing(args[1:], func(member string) bool {
+				_, ok := z.Score(member)
+				return ok
+			})
+
+			result = strconv.Itoa(len(removed))
+			return removal(cdc.OpZRem, key, z, removed), nil
+		})
+	case parser.ZSCORE:
+		err = s.viewSortedSet(ctx, key, func(z *engine.SortedSet) error {
+			score, ok := z.Score(args[1])
+			if !ok {
+				return fmt.Errorf("'%s' member '%s' - %w", key, args[1], engine.ErrNotFound)
+			}
+
+			result = formatScore(score)
+			return nil
+		})
+	case parser.ZRANK:
+		err = s.viewSortedSet(ctx, key, func(z *engine.SortedSet) error {
+			rank, ok := z.Rank(args[1])
+			if !ok {
+				return fmt.Errorf("'%s' member '%s' - %w", key, args[1], engine.ErrNotFound)
+			}
+
+			result = strconv.Itoa(rank)
+			return nil
+		})
+	case parser.ZRANGE:
+		result, err = s.zrange(ctx, key, args[1:])
+	case parser.ZINCRBY:
+		increment, parseErr := parseScore(args[1])
+		if parseErr != nil {
+			return "", parseErr
+		}
+
+		err = s.mutateSortedSet(ctx, key, func(z *engine.SortedSet) ([]cdc.Change, error) {
+			score, _ := z.Score(args[2])
+			score += increment
+			if math.IsNaN(score) {
+				return nil, fmt.Errorf("%w: resulting score is not a number", parser.ErrInvalidArgument)
+			}
+
+			// в журнал попадает итоговая оценка, а не приращение
+			result = formatScore(score)
+			return []cdc.Change{{Op: cdc.OpZAdd, Key: key, Args: []string{result, args[2]}}}, nil
+		})
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed %s: %w", action, err)
+	}
+
+	return result, nil
+}
+
+// zrange возвращает элементы по рангам start и stop или, с BYSCORE, по диапазону оценок
+func (s *Storage) zrange(ctx context.Context, key string, args []string) (string, error) {
+	byScore, withScores := false, false
+	for _, option := range args[2:] {
+		switch strings.ToUpper(option) {
+		case parser.BYSCORE:
+			byScore = true
+		case parser.WITHSCORES:
+			withScores = true
+		default:
+			return "", fmt.Errorf("%w: unknown option %s", parser.ErrInvalidArgument, option)
+		}
+	}
+
+	var members []engine.ScoredMember
+	if byScore {
+		r, err := parseScoreRange(args[0], args[1])
+		if err != nil {
+			return "", err
+		}
+
+		err = s.viewSortedSet(ctx, key, func(z *engine.SortedSet) error {
+			members = z.RangeByScore(r)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	} else {
+		start, stop, err := parseRange(args[0], args[1])
+		if err != nil {
+			return "", err
+		}
+
+		err = s.viewSortedSet(ctx, key, func(z *engine.SortedSet) error {
+			members = z.Range(start, stop)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	lines := make([]string, 0, len(members))
+	for _, m := range members {
+		line := parser.Quote(m.Member)
+		if withScores {
+			line += " " + formatScore(m.Score)
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// parseScore разбирает оценку: число, inf, +inf или -inf
+func parseScore(arg string) (float64, error) {
+	score, err := strconv.ParseFloat(arg, 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, fmt.Errorf("%w: score must be a number: %s", parser.ErrInvalidArgument, arg)
+	}
+
+	return score, nil
+}
+
+// parseScoreRange разбирает границы диапазона оценок. Граница с префиксом ( исключается
+func parseScoreRange(minArg, maxArg string) (engine.ScoreRange, error) {
+	var r engine.ScoreRange
+	var err error
+
+	minArg, r.MinExclusive = strings.CutPrefix(minArg, "(")
+	if r.Min, err = parseScore(minArg); err != nil {
+		return engine.ScoreRange{}, err
+	}
+
+	maxArg, r.MaxExclusive = strings.CutPrefix(maxArg, "(")
+	if r.Max, err = parseScore(maxArg); err != nil {
+		return engine.ScoreRange{}, err
+	}
+
+	return r, nil
+}
+
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	default:
+		return strconv.FormatFloat(score, 'f', -1, 64)
+	}
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStorage_Execute_SortedSet(t *testing.T) {
+	storage := New(engine.New(), zap.NewNop())
+
+	tests := []struct {
+		name     string
+		command  *parser.Command
+		expected string
+		err      error
+	}{
+		{name: "Добавление элементов", command: &parser.Command{Action: parser.ZADD, Args: []string{"board", "3", "carol", "1", "alice", "2", "bob"}}, expected: "3"},
+		{name: "Изменение оценки", command: &parser.Command{Action: parser.ZADD, Args: []string{"board", "1", "bob", "-inf", "dave"}}, expected: "1"},
+		{name: "Диапазон по рангу", command: &parser.Command{Action: parser.ZRANGE, Args: []string{"board", "0", "-1"}}, expected: "dave\nalice\nbob\ncarol"},
+		{name: "Диапазон с оценками", command: &parser.Command{Action: parser.ZRANGE, Args: []string{"board", "1", "2", "withscores"}}, expected: "alice 1\nbob 1"},
+		{name: "Диапазон по оценке", command: &parser.Command{Action: parser.ZRANGE, Args: []string{"board", "(1", "+inf", "BYSCORE", "WITHSCORES"}}, expected: "carol 3"},
+		{name: "Диапазон по оценке включительно", command: &parser.Command{Action: parser.ZRANGE, Args: []string{"board", "-inf", "1", "BYSCORE"}}, expected: "dave\nalice\nbob"},
+		{name: "Неизвестный параметр", command: &parser.Command{Action: parser.ZRANGE, Args: []string{"board", "0", "1", "REV"}}, err: parser.ErrInvalidArgument},
+		{name: "Оценка", command: &parser.Command{Action: parser.ZSCORE, Args: []string{"board", "dave"}}, expected: "-inf"},
+		{name: "Ранг", command: &parser.Command{Action: parser.ZRANK, Args: []string{"board", "carol"}}, expected: "3"},
+		{name: "Ранг отсутствующего элемента", command: &parser.Command{Action: parser.ZRANK, Args: []string{"board", "eve"}}, err: engine.ErrNotFound},
+		{name: "Увеличение оценки", command: &parser.Command{Action: parser.ZINCRBY, Args: []string{"board", "2.5", "alice"}}, expected: "3.5"},
+		{name: "Увеличение оценки нового элемента", command: &parser.Command{Action: parser.ZINCRBY, Args: []string{"board", "-1", "eve"}}, expected: "-1"},
+		{name: "Порядок после увеличения", command: &parser.Command{Action: parser.ZRANGE, Args: []string{"board", "-2", "-1"}}, expected: "carol\nalice"},
+		{name: "Оценка не число", command: &parser.Command{Action: parser.ZINCRBY, Args: []string{"board", "inf", "dave"}}, err: parser.ErrInvalidArgument},
+		{name: "NaN в оценке", command: &parser.Command{Action: parser.ZADD, Args: []string{"board", "nan", "eve"}}, err: parser.ErrInvalidArgument},
+		{name: "Нечетное число аргументов", command: &parser.Command{Action: parser.ZADD, Args: []string{"board", "1", "eve", "2"}}, err: parser.ErrWrongArity},
+		{name: "Тип", command: &parser.Command{Action: parser.TYPE, Args: []string{"board"}}, expected: "zset"},
+		{name: "Удаление", command: &parser.Command{Action: parser.ZREM, Args: []string{"board", "dave", "eve", "zed"}}, expected: "2"},
+		{name: "Строка", command: &parser.Command{Action: parser.SET, Args: []string{"greeting", "hello"}}},
+		{name: "Команда множества для строки", command: &parser.Command{Action: parser.ZSCORE, Args: []string{"greeting", "a"}}, err: engine.ErrWrongType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := storage.Execute(context.Background(), tt.command)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestStorage_Execute_SortedSetChanges(t *testing.T) {
+	storage, sub := newRecordingStorage(t)
+
+	assertRecorded(t, storage, sub, []*parser.Command{
+		{Action: parser.ZADD, Args: []string{"board", "1", "alice", "2", "bob", "3", "alice"}},
+		// оценки не изменились
+		{Action: parser.ZADD, Args: []string{"board", "3", "alice"}},
+		{Action: parser.ZINCRBY, Args: []string{"board", "0.5", "bob"}},
+		{Action: parser.ZREM, Args: []string{"board", "alice", "missing"}},
+		{Action: parser.ZREM, Args: []string{"board", "bob"}},
+	}, "1 zadd board 3 alice 2 bob\n2 zadd board 2.5 bob\n3 zrem board alice\n4 zrem board bob\n5 del board", []string{
+		"zadd board", "zadd board", "zrem board", "zrem board", "del board",
+	})
+}
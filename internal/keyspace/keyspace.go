@@ -0,0 +1,233 @@
+package keyspace
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/patyukin/mdb/internal/pubsub"
+)
+
+// Типы событий изменения ключей
+const (
+	EventSet   = "set"
+	EventDel   = "del"
+	EventHSet  = "hset"
+	EventHDel  = "hdel"
+	EventLPush = "lpush"
+	EventRPush = "rpush"
+	EventLPop  = "lpop"
+	EventRPop  = "rpop"
+	EventLTrim = "ltrim"
+	EventSAdd  = "sadd"
+	EventSRem  = "srem"
+	EventZAdd  = "zadd"
+	EventZRem  = "zrem"
+)
+
+// Каналы уведомлений: в __keyspace__:<ключ> публикуется тип события,
+// в __keyevent__:<тип события> - ключ
+const (
+	KeyspacePrefix = "__keyspace__:"
+	KeyeventPrefix = "__keyevent__:"
+)
+
+// IsNotificationChannel сообщает, относится ли канал к уведомлениям об изменении ключей.
+// В такие каналы публикует только Notifier
+func IsNotificationChannel(channel string) bool {
+	return strings.HasPrefix(channel, KeyspacePrefix) || strings.HasPrefix(channel, KeyeventPrefix)
+}
+
+// MessageKey возвращает ключ, о котором сообщает уведомление из канала __keyspace__
+// или __keyevent__
+func MessageKey(m pubsub.Message) (string, bool) {
+	if key, ok := strings.CutPrefix(m.Channel, KeyspacePrefix); ok {
+		return key, true
+	}
+
+	if strings.HasPrefix(m.Channel, KeyeventPrefix) {
+		return m.Payload, true
+	}
+
+	return "", false
+}
+
+// Classes - набор классов событий. В строковой записи каждому классу соответствует символ:
+// K - каналы __keyspace__, E - каналы __keyevent__, g - del, $ - set, h - изменения hash,
+// l - изменения списков, s - изменения множеств, z - изменения упорядоченных множеств,
+// A - все классы событий
+type Classes uint16
+
+const (
+	ClassKeyspace Classes = 1 << iota
+	ClassKeyevent
+	ClassGeneric
+	ClassString
+	ClassHash
+	ClassList
+	ClassSet
+	ClassSortedSet
+
+	ClassAll = ClassGeneric | ClassString | ClassHash | ClassList | ClassSet | ClassSortedSet
+)
+
+var classSymbols = []struct {
+	symbol byte
+	class  Classes
+}{
+	{'K', ClassKeyspace},
+	{'E', ClassKeyevent},
+	{'g', ClassGeneric},
+	{'$', ClassString},
+	{'h', ClassHash},
+	{'l', ClassList},
+	{'s', ClassSet},
+	{'z', ClassSortedSet},
+}
+
+// ParseClasses разбирает строковую запись классов событий, например "Eg$" или "KA"
+func ParseClasses(s string) (Classes, error) {
+	var classes Classes
+	for i := 0; i < len(s); i++ {
+		if s[i] == 'A' {
+			classes |= ClassAll
+			continue
+		}
+
+		found := false
+		for _, cs := range classSymbols {
+			if cs.symbol == s[i] {
+				classes |= cs.class
+				found = true
+			}
+		}
+
+		if !found {
+			return 0, fmt.Errorf("unknown keyspace event class %q", s[i])
+		}
+	}
+
+	return classes, nil
+}
+
+func (c Classes) String() string {
+	var b strings.Builder
+	for _, cs := range classSymbols {
+		if c&cs.class != 0 {
+			b.WriteByte(cs.symbol)
+		}
+	}
+
+	return b.String()
+}
+
+// eventClass возвращает класс, к которому относится событие
+func eventClass(event string) Classes {
+	switch event {
+	case EventSet:
+		return ClassString
+	case EventDel:
+		return ClassGeneric
+	case EventHSet, EventHDel:
+		return ClassHash
+	case EventLPush, EventRPush, EventLPop, EventRPop, EventLTrim:
+		return ClassList
+	case EventSAdd, EventSRem:
+		return ClassSet
+	case EventZAdd, EventZRem:
+		return ClassSortedSet
+	default:
+		return 0
+	}
+}
+
+// Event - изменение ключа
+type Event struct {
+	Type string
+	Key  string
+}
+
+// Listener получает события в той же горутине, в которой выполняется команда,
+// поэтому не должен блокироваться
+type Listener func(Event)
+
+type listener struct {
+	classes Classes
+	fn      Listener
+}
+
+// Notifier рассылает события изменения ключей подписчикам pub/sub и обработчикам в процессе
+type Notifier struct {
+	broker  *pubsub.Broker
+	classes atomic.Uint32
+
+	mu        sync.RWMutex
+	listeners []*listener
+}
+
+// NewNotifier создает рассылку. В каналы pub/sub попадают события классов classes,
+// если среди них есть K или E
+func NewNotifier(broker *pubsub.Broker, classes Classes) *Notifier {
+	n := &Notifier{broker: broker}
+	n.SetClasses(classes)
+
+	return n
+}
+
+// SetClasses меняет классы событий, публикуемых в каналы pub/sub
+func (n *Notifier) SetClasses(classes Classes) {
+	n.classes.Store(uint32(classes))
+}
+
+// Classes возвращает классы событий, публикуемых в каналы pub/sub
+func (n *Notifier) Classes() Classes {
+	return Classes(n.classes.Load())
+}
+
+// Listen регистрирует обработчик событий классов classes независимо от настроек
+// публикации и возвращает функцию, отменяющую регистрацию
+func (n *Notifier) Listen(classes Classes, fn Listener) func() {
+	l := &listener{classes: classes, fn: fn}
+
+	n.mu.Lock()
+	n.listeners = append(n.listeners, l)
+	n.mu.Unlock()
+
+	return func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		for i, registered := range n.listeners {
+			if registered == l {
+				n.listeners = append(n.listeners[:i:i], n.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Notify сообщает об изменении ключа
+func (n *Notifier) Notify(event, key string) {
+	class := eventClass(event)
+
+	if classes := n.Classes(); n.broker != nil && classes&class != 0 {
+		if classes&ClassKeyspace != 0 {
+			n.broker.Publish(KeyspacePrefix+key, event)
+		}
+
+		if classes&ClassKeyevent != 0 {
+			n.broker.Publish(KeyeventPrefix+event, key)
+		}
+	}
+
+	n.mu.RLock()
+	listeners := n.listeners
+	n.mu.RUnlock()
+
+	for _, l := range listeners {
+		if l.classes&class != 0 {
+			l.fn(Event{Type: event, Key: key})
+		}
+	}
+}
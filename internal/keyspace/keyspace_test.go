@@ -0,0 +1,69 @@
+package keyspace
+
+import (
+	"testing"
+
+	"github.com/patyukin/mdb/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClasses(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{input: "", expected: ""},
+		{input: "KEA", expected: "KEg$hlsz"},
+		{input: "Elh", expected: "Ehl"},
+		{input: "E$", expected: "E$"},
+		{input: "gK", expected: "Kg"},
+		{input: "Kq", wantErr: true},
+		{input: "Kx", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			classes, err := ParseClasses(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, classes.String())
+		})
+	}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	broker := pubsub.NewBroker(16)
+	sub := broker.NewSubscriber()
+	broker.PSubscribe(sub, "__key*")
+
+	classes, err := ParseClasses("KE$")
+	require.NoError(t, err)
+	n := NewNotifier(broker, classes)
+
+	var events []Event
+	cancel := n.Listen(ClassAll, func(e Event) { events = append(events, e) })
+
+	n.Notify(EventSet, "user:1")
+	n.Notify(EventDel, "user:1")
+
+	// del не относится к классу $ и в каналы не публикуется, но обработчик его получает
+	assert.Equal(t, []pubsub.Message{
+		{Pattern: "__key*", Channel: "__keyspace__:user:1", Payload: "set"},
+		{Pattern: "__key*", Channel: "__keyevent__:set", Payload: "user:1"},
+	}, sub.Drain())
+	assert.Equal(t, []Event{{Type: EventSet, Key: "user:1"}, {Type: EventDel, Key: "user:1"}}, events)
+
+	n.SetClasses(ClassKeyevent | ClassGeneric)
+	cancel()
+	n.Notify(EventDel, "user:2")
+	n.Notify(EventSet, "user:2")
+
+	assert.Equal(t, []pubsub.Message{{Pattern: "__key*", Channel: "__keyevent__:del", Payload: "user:2"}}, sub.Drain())
+	assert.Len(t, events, 2)
+}
@@ -0,0 +1,292 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefBuckets - границы гистограмм длительности в секундах по умолчанию
+var DefBuckets = []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}
+
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// Counter - монотонно возрастающий счетчик
+type Counter struct {
+	desc
+	value atomic.Uint64
+}
+
+func NewCounter(name, help string) *Counter {
+	return &Counter{desc: desc{name: name, help: help}}
+}
+
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+func (c *Counter) describe() (string, string, string) {
+	return c.name, c.help, "counter"
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	writeSample(w, c.name, nil, nil, float64(c.Value()))
+}
+
+// Gauge - значение, которое может как расти, так и уменьшаться
+type Gauge struct {
+	desc
+	bits atomic.Uint64
+}
+
+func NewGauge(name, help string) *Gauge {
+	return &Gauge{desc: desc{name: name, help: help}}
+}
+
+func (g *Gauge) Set(v float64) {
+	g.bits.Store(math.Float64bits(v))
+}
+
+func (g *Gauge) Add(delta float64) {
+	for {
+		old := g.bits.Load()
+		if g.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+func (g *Gauge) describe() (string, string, string) {
+	return g.name, g.help, "gauge"
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	writeSample(w, g.name, nil, nil, g.Value())
+}
+
+// GaugeFunc вычисляет значение в момент сбора метрик
+type GaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	return &GaugeFunc{desc: desc{name: name, help: help}, fn: fn}
+}
+
+func (g *GaugeFunc) describe() (string, string, string) {
+	return g.name, g.help, "gauge"
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	writeSample(w, g.name, nil, nil, g.fn())
+}
+
+// Histogram распределяет наблюдения по корзинам
+type Histogram struct {
+	desc
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	return newHistogram(desc{name: name, help: help}, buckets)
+}
+
+func newHistogram(d desc, buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+
+	b := make([]float64, len(buckets))
+	copy(b, buckets)
+	sort.Float64s(b)
+
+	return &Histogram{desc: d, buckets: b, counts: make([]uint64, len(b))}
+}
+
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+// ObserveDuration записывает длительность в секундах
+func (h *Histogram) ObserveDuration(d time.Duration) {
+	h.Observe(d.Seconds())
+}
+
+func (h *Histogram) describe() (string, string, string) {
+	return h.name, h.help, "histogram"
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.writeWithLabels(w, nil, nil)
+}
+
+func (h *Histogram) writeWithLabels(w *bufio.Writer, names, values []string) {
+	h.mu.Lock()
+	counts := make([]uint64, len(h.counts))
+	copy(counts, h.counts)
+	sum, count := h.sum, h.count
+	h.mu.Unlock()
+
+	bucketNames := append(append([]string{}, names...), "le")
+	bucketValues := append(append([]string{}, values...), "")
+
+	var cumulative uint64
+	for i, upper := range h.buckets {
+		cumulative += counts[i]
+		bucketValues[len(bucketValues)-1] = formatFloat(upper)
+		writeSample(w, h.name+"_bucket", bucketNames, bucketValues, float64(cumulative))
+	}
+
+	bucketValues[len(bucketValues)-1] = "+Inf"
+	writeSample(w, h.name+"_bucket", bucketNames, bucketValues, float64(count))
+	writeSample(w, h.name+"_sum", names, values, sum)
+	writeSample(w, h.name+"_count", names, values, float64(count))
+}
+
+// vec хранит дочерние метрики по значениям меток
+type vec[T any] struct {
+	desc
+	newChild func() T
+
+	mu       sync.RWMutex
+	children map[string]T
+	values   map[string][]string
+}
+
+func newVec[T any](d desc, newChild func() T) vec[T] {
+	return vec[T]{desc: d, newChild: newChild, children: make(map[string]T), values: make(map[string][]string)}
+}
+
+func (v *vec[T]) withLabelValues(values ...string) T {
+	if len(values) != len(v.labels) {
+		panic("metrics: inconsistent label cardinality for " + v.name)
+	}
+
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	child, ok := v.children[key]
+	v.mu.RUnlock()
+	if ok {
+		return child
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if child, ok = v.children[key]; !ok {
+		child = v.newChild()
+		v.children[key] = child
+		v.values[key] = append([]string{}, values...)
+	}
+
+	return child
+}
+
+func (v *vec[T]) each(fn func(values []string, child T)) {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.children))
+	for key := range v.children {
+		keys = append(keys, key)
+	}
+	v.mu.RUnlock()
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		v.mu.RLock()
+		child, values := v.children[key], v.values[key]
+		v.mu.RUnlock()
+		fn(values, child)
+	}
+}
+
+// CounterVec - набор счетчиков, различающихся значениями меток
+type CounterVec struct {
+	vec[*Counter]
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	d := desc{name: name, help: help, labels: labels}
+	return &CounterVec{vec: newVec(d, func() *Counter { return &Counter{desc: d} })}
+}
+
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	return c.withLabelValues(values...)
+}
+
+func (c *CounterVec) describe() (string, string, string) {
+	return c.name, c.help, "counter"
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.each(func(values []string, child *Counter) {
+		writeSample(w, c.name, c.labels, values, float64(child.Value()))
+	})
+}
+
+// HistogramVec - набор гистограмм, различающихся значениями меток
+type HistogramVec struct {
+	vec[*Histogram]
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	d := desc{name: name, help: help, labels: labels}
+	return &HistogramVec{vec: newVec(d, func() *Histogram { return newHistogram(d, buckets) })}
+}
+
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return h.withLabelValues(values...)
+}
+
+func (h *HistogramVec) describe() (string, string, string) {
+	return h.name, h.help, "histogram"
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.each(func(values []string, child *Histogram) {
+		child.writeWithLabels(w, h.labels, values)
+	})
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	registry := NewRegistry()
+
+	commands := NewCounterVec("mdb_commands_total", "Executed commands.", "command")
+	connections := NewGauge("mdb_connections", "Active connections.")
+	keys := NewGaugeFunc("mdb_keys", "Number of keys.", func() float64 { return 42 })
+	latency := NewHistogram("mdb_latency_seconds", "Latency.", []float64{0.1, 0.5})
+	registry.Register(latency, keys, connections, commands)
+
+	commands.WithLabelValues("SET").Inc()
+	commands.WithLabelValues("GET").Add(2)
+	connections.Inc()
+	connections.Inc()
+	connections.Dec()
+	latency.Observe(0.05)
+	latency.Observe(0.3)
+	latency.Observe(2)
+
+	var buf bytes.Buffer
+	n, err := registry.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	expected := `# HELP mdb_commands_total Executed commands.
+# TYPE mdb_commands_total counter
+mdb_commands_total{command="GET"} 2
+mdb_commands_total{command="SET"} 1
+# HELP mdb_connections Active connections.
+# TYPE mdb_connections gauge
+mdb_connections 1
+# HELP mdb_keys Number of keys.
+# TYPE mdb_keys gauge
+mdb_keys 42
+# HELP mdb_latency_seconds Latency.
+# TYPE mdb_latency_seconds histogram
+mdb_latency_seconds_bucket{le="0.1"} 1
+mdb_latency_seconds_bucket{le="0.5"} 2
+mdb_latency_seconds_bucket{le="+Inf"} 3
+mdb_latency_seconds_sum 2.35
+mdb_latency_seconds_count 3
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestHistogramVec(t *testing.T) {
+	registry := NewRegistry()
+	h := NewHistogramVec("mdb_execute_seconds", "Execute stage.", []float64{1}, "command")
+	registry.Register(h)
+
+	h.WithLabelValues("GET").Observe(0.5)
+
+	var buf bytes.Buffer
+	_, err := registry.WriteTo(&buf)
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), `mdb_execute_seconds_bucket{command="GET",le="1"} 1`)
+	assert.Contains(t, buf.String(), `mdb_execute_seconds_bucket{command="GET",le="+Inf"} 1`)
+	assert.Contains(t, buf.String(), `mdb_execute_seconds_count{command="GET"} 1`)
+}
+
+func TestEscaping(t *testing.T) {
+	registry := NewRegistry()
+	c := NewCounterVec("mdb_test_total", "Line one\nline \\ two.", "value")
+	registry.Register(c)
+	c.WithLabelValues("a\"b\\c\nd").Inc()
+
+	var buf bytes.Buffer
+	_, err := registry.WriteTo(&buf)
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), `# HELP mdb_test_total Line one\nline \\ two.`)
+	assert.Contains(t, buf.String(), `mdb_test_total{value="a\"b\\c\nd"} 1`)
+}
+
+func TestRegistry_DuplicatePanics(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(NewCounter("mdb_total", ""))
+
+	assert.Panics(t, func() { registry.Register(NewGauge("mdb_total", "")) })
+}
+
+func TestCounterVec_Concurrent(t *testing.T) {
+	c := NewCounterVec("mdb_total", "", "command")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.WithLabelValues("GET").Inc()
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, uint64(50), c.WithLabelValues("GET").Value())
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(NewCounter("mdb_total", "Total."))
+
+	rec := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, string(body), "mdb_total 0")
+}
@@ -0,0 +1,248 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/network/codec"
+	"github.com/patyukin/mdb/internal/pubsub"
+	"github.com/patyukin/mdb/internal/session"
+	"go.uber.org/zap"
+)
+
+// Соединение бинарного протокола начинается с кадра OpHello, согласующего версию.
+// Затем клиент отправляет кадры OpCommand и получает на каждый кадр OpResponse
+// с тем же идентификатором. Команды выполняются по порядку, команды с флагом
+// FlagAsync - параллельно с соседними. После нарушения протокола сервер отправляет
+// ответ с кодом codec.CodeProtocol и закрывает соединение
+
+var (
+	errHelloRequired   = errors.New("hello required before commands")
+	errRepeatedHello   = errors.New("protocol version already negotiated")
+	errVersionMismatch = errors.New("frame version differs from the negotiated one")
+	errUnexpectedFrame = errors.New("unexpected frame opcode")
+)
+
+// CommandHandler выполняет команду бинарного протокола. Контекст отменяется при отключении клиента
+type CommandHandler func(ctx context.Context, cmd *parser.Command) (string, error)
+
+// WithBinaryProtocol принимает на тех же адресах соединения бинарного протокола, они
+// отличаются от текстовых первым байтом. errorCode переводит ошибку handler в код ответа
+func WithBinaryProtocol(handler CommandHandler, errorCode func(error) string) TCPServerOption {
+	return func(s *TCPServer) {
+		s.commandHandler = handler
+		s.errorCode = errorCode
+	}
+}
+
+// binaryProtocol проверяет по первому байту, что клиент использует бинарный протокол
+func (s *TCPServer) binaryProtocol(conn net.Conn, r *bufio.Reader) bool {
+	if err := conn.SetReadDeadline(time.Now().Add(time.Duration(s.idleTimeout.Load()))); err != nil {
+		return false
+	}
+
+	b, err := r.Peek(1)
+
+	return err == nil && b[0] == codec.Magic
+}
+
+func (s *TCPServer) handleBinaryConnection(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	conn net.Conn,
+	r *bufio.Reader,
+	sess *session.Session,
+) {
+	maxInFlight := int(s.maxInFlight.Load())
+	slots := make(chan struct{}, maxInFlight)
+	frames := make(chan *codec.Frame, maxInFlight)
+	w := newResponseWriter(conn, time.Duration(s.idleTimeout.Load()), func() int { return len(frames) })
+
+	var readErr error
+	go func() {
+		readErr = s.readFrames(ctx, conn, r, sess, slots, frames)
+		if readErr != nil && !errors.Is(readErr, io.EOF) && !protocolViolation(readErr) &&
+			ctx.Err() == nil && !s.draining.Load() {
+			s.logger.Warn("failed decoder.Decode", zap.String("remote", sess.RemoteAddr), zap.Error(readErr))
+		}
+
+		if !s.draining.Load() {
+			cancel()
+		}
+
+		close(frames)
+	}()
+
+	var inFlight sync.WaitGroup
+	defer inFlight.Wait()
+
+	var version uint8 // 0 - версия еще не согласована
+	for f := range frames {
+		if f.Opcode == codec.OpCommand && version != 0 && f.Version == version && f.Flags&codec.FlagAsync != 0 {
+			inFlight.Add(1)
+			go func() {
+				defer inFlight.Done()
+				s.respondBinary(ctx, cancel, f, version, slots, w, sess)
+			}()
+
+			continue
+		}
+
+		inFlight.Wait()
+
+		var err error
+		switch {
+		case f.Opcode == codec.OpHello && version != 0:
+			err = errRepeatedHello
+		case f.Opcode == codec.OpHello:
+			if version, err = codec.Negotiate(f); err == nil {
+				<-slots
+				err = w.finish((&codec.Frame{Version: version, Opcode: codec.OpHello, RequestID: f.RequestID}).Append(nil))
+				if err != nil {
+					s.logger.Warn("failed w.finish", zap.String("remote", sess.RemoteAddr), zap.Error(err))
+					return
+				}
+
+				if sub, ok := sess.Subscriber(); ok {
+					negotiated := version
+					encode := func(m pubsub.Message) []byte { return pushFrame(negotiated, m) }
+					go s.forwardMessages(ctx, cancel, conn, sess, sub, w, encode)
+				}
+
+				continue
+			}
+		case version == 0:
+			err = errHelloRequired
+		case f.Version != version:
+			err = fmt.Errorf("%w: %d, negotiated %d", errVersionMismatch, f.Version, version)
+		case f.Opcode != codec.OpCommand:
+			err = fmt.Errorf("%w: %s", errUnexpectedFrame, f.Opcode)
+		default:
+			if !s.respondBinary(ctx, cancel, f, version, slots, w, sess) {
+				return
+			}
+
+			continue
+		}
+
+		s.protocolError(w, version, f.RequestID, sess, err)
+		return
+	}
+
+	inFlight.Wait()
+	if protocolViolation(readErr) {
+		s.protocolError(w, version, 0, sess, readErr)
+	}
+}
+
+// readFrames читает кадры, пока есть свободные слоты, и возвращает причину остановки
+func (s *TCPServer) readFrames(
+	ctx context.Context,
+	conn net.Conn,
+	r *bufio.Reader,
+	sess *session.Session,
+	slots chan<- struct{},
+	frames chan<- *codec.Frame,
+) error {
+	decoder := codec.NewDecoder(r, int(s.maxMessageSize.Load()))
+	for {
+		if err := conn.SetReadDeadline(s.readDeadline(sess)); err != nil {
+			return err
+		}
+
+		if s.draining.Load() {
+			return nil
+		}
+
+		f, err := decoder.Decode()
+		if err != nil {
+			return err
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		}
+
+		frames <- f
+	}
+}
+
+// respondBinary выполняет команду из кадра и отправляет ответ, освобождая слот
+func (s *TCPServer) respondBinary(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	f *codec.Frame,
+	version uint8,
+	slots <-chan struct{},
+	w *responseWriter,
+	sess *session.Session,
+) bool {
+	defer func() { <-slots }()
+
+	subscribed := subscriptions(sess)
+	result, err := s.commandHandler(ctx, frameCommand(f))
+	if subscriptions(sess) != subscribed {
+		s.rearmReadDeadline(ctx, w.conn, sess)
+	}
+
+	if ctx.Err() != nil {
+		_ = w.finish(nil)
+		return false
+	}
+
+	response := codec.Response(version, f.RequestID, []byte(result))
+	if err != nil {
+		response = codec.ErrorResponse(version, f.RequestID, s.errorCode(err), err.Error())
+	}
+
+	if err = w.finish(response.Append(nil)); err != nil {
+		s.logger.Warn("failed w.finish", zap.String("remote", sess.RemoteAddr), zap.Error(err))
+		cancel()
+		return false
+	}
+
+	return true
+}
+
+// protocolError сообщает клиенту о нарушении протокола перед закрытием соединения
+func (s *TCPServer) protocolError(w *responseWriter, version uint8, id uint32, sess *session.Session, err error) {
+	s.logger.Warn("Binary protocol violation", zap.String("remote", sess.RemoteAddr), zap.Error(err))
+
+	if version == 0 {
+		version = codec.MinVersion
+	}
+
+	_ = w.finish(codec.ErrorResponse(version, id, codec.CodeProtocol, err.Error()).Append(nil))
+}
+
+// protocolViolation проверяет, что поток кадров поврежден и продолжать чтение нельзя
+func protocolViolation(err error) bool {
+	return errors.Is(err, codec.ErrBadMagic) || errors.Is(err, codec.ErrFrameTooLarge) ||
+		errors.Is(err, codec.ErrMalformedFrame)
+}
+
+// frameCommand переводит кадр в команду без разбора текста: первое поле - имя команды,
+// остальные - аргументы, которые могут содержать любые байты
+func frameCommand(f *codec.Frame) *parser.Command {
+	cmd := &parser.Command{}
+	if len(f.Fields) == 0 {
+		return cmd
+	}
+
+	cmd.Action = strings.ToUpper(string(f.Fields[0]))
+	for _, field := range f.Fields[1:] {
+		cmd.Args = append(cmd.Args, string(field))
+	}
+
+	return cmd
+}
@@ -0,0 +1,169 @@
+package network
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/patyukin/mdb/internal/network/codec"
+)
+
+const maxResponseSize = 64 << 20
+
+var ErrUnexpectedResponse = errors.New("unexpected response frame")
+
+// ResponseError - ошибка, которую сервер вернул по бинарному протоколу
+type ResponseError struct {
+	Code    string
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	return e.Code + " " + e.Message
+}
+
+// BinaryClient выполняет команды по бинарному протоколу, по одной за раз
+type BinaryClient struct {
+	conn        net.Conn
+	decoder     *codec.Decoder
+	idleTimeout time.Duration
+	version     uint8
+	nextID      uint32
+	messages    [][][]byte // сообщения pub/sub, прочитанные в ожидании ответа
+}
+
+// NewBinaryClient устанавливает соединение и согласует версию протокола
+func NewBinaryClient(address string, idleTimeout time.Duration) (*BinaryClient, error) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed net.Dial: %w", err)
+	}
+
+	c, err := NewBinaryClientConn(conn, idleTimeout)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// NewBinaryClientConn согласует версию протокола на уже установленном соединении
+func NewBinaryClientConn(conn net.Conn, idleTimeout time.Duration) (*BinaryClient, error) {
+	if idleTimeout <= 0 {
+		idleTimeout = defaultIdleTimeout
+	}
+
+	c := &BinaryClient{
+		conn:        conn,
+		decoder:     codec.NewDecoder(bufio.NewReader(conn), maxResponseSize),
+		idleTimeout: idleTimeout,
+	}
+
+	hello, err := c.roundTrip(codec.Hello(codec.MinVersion, codec.MaxVersion))
+	if err != nil {
+		return nil, err
+	}
+
+	if hello.Opcode != codec.OpHello {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedResponse, hello.Opcode)
+	}
+
+	c.version = hello.Version
+
+	return c, nil
+}
+
+// Version возвращает согласованную версию протокола
+func (c *BinaryClient) Version() uint8 {
+	return c.version
+}
+
+// Do выполняет команду и возвращает результат. Ошибка сервера возвращается как *ResponseError
+func (c *BinaryClient) Do(args ...[]byte) ([]byte, error) {
+	response, err := c.roundTrip(&codec.Frame{Version: c.version, Opcode: codec.OpCommand, Fields: args})
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Opcode != codec.OpResponse || len(response.Fields) != 1 {
+		return nil, fmt.Errorf("%w: %s with %d fields", ErrUnexpectedResponse, response.Opcode, len(response.Fields))
+	}
+
+	return response.Fields[0], nil
+}
+
+// roundTrip отправляет кадр с новым идентификатором и читает ответ на него
+func (c *BinaryClient) roundTrip(f *codec.Frame) (*codec.Frame, error) {
+	c.nextID++
+	f.RequestID = c.nextID
+
+	if err := c.conn.SetDeadline(time.Now().Add(c.idleTimeout)); err != nil {
+		return nil, fmt.Errorf("failed c.conn.SetDeadline: %w", err)
+	}
+
+	if _, err := c.conn.Write(f.Append(nil)); err != nil {
+		return nil, fmt.Errorf("failed c.conn.Write: %w", err)
+	}
+
+	var response *codec.Frame
+	for {
+		var err error
+		if response, err = c.decoder.Decode(); err != nil {
+			return nil, fmt.Errorf("failed c.decoder.Decode: %w", err)
+		}
+
+		if response.Opcode != codec.OpPush {
+			break
+		}
+
+		c.messages = append(c.messages, response.Fields)
+	}
+
+	if response.Flags&codec.FlagError != 0 && len(response.Fields) == 2 {
+		return nil, &ResponseError{Code: string(response.Fields[0]), Message: string(response.Fields[1])}
+	}
+
+	if response.RequestID != f.RequestID {
+		return nil, fmt.Errorf("%w: request id %d, expected %d", ErrUnexpectedResponse, response.RequestID, f.RequestID)
+	}
+
+	return response, nil
+}
+
+// Receive возвращает поля следующего сообщения pub/sub, ожидая его не дольше timeout,
+// 0 - без ограничения
+func (c *BinaryClient) Receive(timeout time.Duration) ([][]byte, error) {
+	if len(c.messages) > 0 {
+		message := c.messages[0]
+		c.messages = c.messages[1:]
+
+		return message, nil
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	if err := c.conn.SetReadDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("failed c.conn.SetReadDeadline: %w", err)
+	}
+
+	f, err := c.decoder.Decode()
+	if err != nil {
+		return nil, fmt.Errorf("failed c.decoder.Decode: %w", err)
+	}
+
+	if f.Opcode != codec.OpPush {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedResponse, f.Opcode)
+	}
+
+	return f.Fields, nil
+}
+
+func (c *BinaryClient) Close() error {
+	return c.conn.Close()
+}
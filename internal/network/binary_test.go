@@ -0,0 +1,245 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/patyukin/mdb/internal/database"
+	"github.com/patyukin/mdb/internal/database/compute"
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/database/storage"
+	"github.com/patyukin/mdb/internal/database/storage/engine"
+	"github.com/patyukin/mdb/internal/network/codec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var errTestNotFound = errors.New("key not found")
+
+func testErrorCode(err error) string {
+	if errors.Is(err, errTestNotFound) {
+		return "ERR_NOT_FOUND"
+	}
+
+	return "ERR_INTERNAL"
+}
+
+// startBinaryServer запускает сервер с хранилищем ключей в памяти для команд SET и GET
+func startBinaryServer(t *testing.T, options ...TCPServerOption) string {
+	t.Helper()
+
+	var mu sync.Mutex
+	values := make(map[string]string)
+	handler := func(_ context.Context, cmd *parser.Command) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch cmd.Action {
+		case parser.SET:
+			values[cmd.Args[0]] = cmd.Args[1]
+			return "", nil
+		case parser.GET:
+			value, ok := values[cmd.Args[0]]
+			if !ok {
+				return "", errTestNotFound
+			}
+
+			return value, nil
+		default:
+			return "", errors.New("unexpected command " + cmd.Action)
+		}
+	}
+
+	return startServer(t, func(_ context.Context, request []byte) []byte {
+		return append([]byte("text "), request...)
+	}, append(options, WithBinaryProtocol(handler, testErrorCode))...)
+}
+
+func TestTCPServer_BinaryProtocol(t *testing.T) {
+	address := startBinaryServer(t)
+
+	client, err := NewBinaryClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	assert.Equal(t, codec.MaxVersion, client.Version())
+
+	value := "line1\nline2\x00\xff"
+	_, err = client.Do([]byte("set"), []byte("key with spaces"), []byte(value))
+	require.NoError(t, err)
+
+	result, err := client.Do([]byte("GET"), []byte("key with spaces"))
+	require.NoError(t, err)
+	assert.Equal(t, value, string(result))
+
+	_, err = client.Do([]byte("GET"), []byte("missing"))
+	var responseErr *ResponseError
+	require.ErrorAs(t, err, &responseErr)
+	assert.Equal(t, "ERR_NOT_FOUND", responseErr.Code)
+
+	// текстовые клиенты обслуживаются на том же адресе
+	text, err := NewTCPClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = text.Close() }()
+
+	response, err := text.Send([]byte("PING"))
+	require.NoError(t, err)
+	assert.Equal(t, "text PING", string(response))
+}
+
+func TestTCPServer_BinaryAsync(t *testing.T) {
+	release := make(chan struct{})
+	handler := func(ctx context.Context, cmd *parser.Command) (string, error) {
+		if cmd.Action == "SLOW" {
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+		}
+
+		return cmd.Action, nil
+	}
+
+	address := startServer(t, nil, WithBinaryProtocol(handler, testErrorCode))
+
+	conn, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	_, err = NewBinaryClientConn(conn, time.Second)
+	require.NoError(t, err)
+
+	var batch []byte
+	for id, action := range []string{"SLOW", "FAST"} {
+		f := &codec.Frame{
+			Version:   codec.Version1,
+			Opcode:    codec.OpCommand,
+			Flags:     codec.FlagAsync,
+			RequestID: uint32(id + 10),
+			Fields:    [][]byte{[]byte(action)},
+		}
+		batch = f.Append(batch)
+	}
+
+	_, err = conn.Write(batch)
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+	// ответ на быструю команду приходит раньше ответа на медленную
+	decoder := codec.NewDecoder(bufio.NewReader(conn), 1<<10)
+	first, err := decoder.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(11), first.RequestID)
+	assert.Equal(t, "FAST", string(first.Fields[0]))
+
+	close(release)
+	second, err := decoder.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(10), second.RequestID)
+}
+
+func TestTCPServer_BinaryProtocolErrors(t *testing.T) {
+	address := startBinaryServer(t, WithMaxMessageSize(64))
+
+	command := &codec.Frame{Version: codec.Version1, Opcode: codec.OpCommand, RequestID: 5, Fields: [][]byte{[]byte("GET"), []byte("k")}}
+
+	tests := []struct {
+		name    string
+		frames  []*codec.Frame
+		raw     []byte
+		message string
+	}{
+		{name: "Команда до приветствия", frames: []*codec.Frame{command}, message: "hello required"},
+		{
+			name:    "Неподдерживаемая версия",
+			frames:  []*codec.Frame{codec.Hello(codec.MaxVersion+1, codec.MaxVersion+1)},
+			message: "unsupported protocol version",
+		},
+		{
+			name:    "Повторное приветствие",
+			frames:  []*codec.Frame{codec.Hello(codec.MinVersion, codec.MaxVersion), codec.Hello(codec.MinVersion, codec.MaxVersion)},
+			message: "already negotiated",
+		},
+		{
+			name:    "Слишком большой кадр",
+			frames:  []*codec.Frame{codec.Hello(codec.MinVersion, codec.MaxVersion)},
+			raw:     (&codec.Frame{Version: codec.Version1, Opcode: codec.OpCommand, Fields: [][]byte{make([]byte, 100)}}).Append(nil),
+			message: "frame too large",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", address)
+			require.NoError(t, err)
+			defer func() { _ = conn.Close() }()
+
+			var data []byte
+			for _, f := range tt.frames {
+				data = f.Append(data)
+			}
+
+			_, err = conn.Write(append(data, tt.raw...))
+			require.NoError(t, err)
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+			decoder := codec.NewDecoder(bufio.NewReader(conn), 1<<10)
+			var response *codec.Frame
+			for {
+				response, err = decoder.Decode()
+				require.NoError(t, err)
+				if response.Opcode != codec.OpHello {
+					break
+				}
+			}
+
+			require.NotZero(t, response.Flags&codec.FlagError)
+			assert.Equal(t, codec.CodeProtocol, string(response.Fields[0]))
+			assert.Contains(t, string(response.Fields[1]), tt.message)
+
+			// после нарушения протокола сервер закрывает соединение
+			_, err = decoder.Decode()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestTCPServer_BinaryValueOverText(t *testing.T) {
+	logger := zap.NewNop()
+	db := database.New(compute.New(parser.New(), logger), storage.New(engine.New(), logger), logger)
+	address := startServer(t, func(ctx context.Context, request []byte) []byte {
+		result, err := db.HandleQuery(ctx, string(request))
+		return []byte(database.FormatResponse(result, err))
+	}, WithBinaryProtocol(db.HandleCommand, database.ErrorCode))
+
+	binaryClient, err := NewBinaryClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = binaryClient.Close() }()
+
+	value := "first\n\nsecond"
+	_, err = binaryClient.Do([]byte("SET"), []byte("key"), []byte(value))
+	require.NoError(t, err)
+
+	result, err := binaryClient.Do([]byte("GET"), []byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, value, string(result))
+
+	textClient, err := NewTCPClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = textClient.Close() }()
+
+	// перевод строки в значении не завершает текстовый ответ раньше времени
+	response, err := textClient.Send([]byte("GET key"))
+	require.NoError(t, err)
+	assert.Equal(t, `OK "first\n\nsecond"`, string(response))
+
+	response, err = textClient.Send([]byte("PING"))
+	require.NoError(t, err)
+	assert.Equal(t, "OK PONG", string(response))
+}
@@ -0,0 +1,267 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Кадр бинарного протокола состоит из заголовка фиксированной длины и тела:
+//
+//	magic   uint8   всегда 0xDB, отличает кадр от текстового запроса
+//	version uint8   версия протокола
+//	opcode  uint8   тип кадра
+//	flags   uint8   флаги кадра
+//	id      uint32  идентификатор запроса, ответ несет тот же идентификатор
+//	length  uint32  длина тела
+//
+// Тело - последовательность полей, каждое поле - длина uint32 и байты значения.
+// Все числа передаются в порядке big-endian
+
+const (
+	Magic      byte = 0xDB
+	HeaderSize      = 12
+	fieldSize       = 4
+)
+
+// Версии протокола, поддерживаемые этой сборкой
+const (
+	Version1   uint8 = 1
+	MinVersion       = Version1
+	MaxVersion       = Version1
+)
+
+// CodeProtocol - код ошибки нарушения протокола, после которой сервер закрывает соединение
+const CodeProtocol = "ERR_PROTOCOL"
+
+// Opcode - тип кадра
+type Opcode uint8
+
+const (
+	// OpHello согласует версию протокола. Клиент передает в заголовке наибольшую
+	// поддерживаемую версию, а в единственном поле - наименьшую. Сервер отвечает
+	// кадром OpHello с выбранной версией
+	OpHello Opcode = 1
+	// OpCommand - команда: первое поле - имя, остальные - аргументы
+	OpCommand Opcode = 2
+	// OpResponse - ответ: результат в единственном поле или, с флагом FlagError,
+	// код и текст ошибки
+	OpResponse Opcode = 3
+	// OpPush - сообщение, которое сервер отправляет без запроса, например pub/sub.
+	// Идентификатор запроса равен нулю, первое поле - вид сообщения
+	OpPush Opcode = 4
+)
+
+func (o Opcode) String() string {
+	switch o {
+	case OpHello:
+		return "HELLO"
+	case OpCommand:
+		return "COMMAND"
+	case OpResponse:
+		return "RESPONSE"
+	case OpPush:
+		return "PUSH"
+	default:
+		return fmt.Sprintf("OPCODE(%d)", uint8(o))
+	}
+}
+
+// Flags - флаги кадра
+type Flags uint8
+
+const (
+	// FlagAsync разрешает выполнять команду параллельно с соседними, ответ может
+	// прийти раньше ответов на предыдущие команды
+	FlagAsync Flags = 1 << 0
+	// FlagError отмечает ответ с ошибкой
+	FlagError Flags = 1 << 1
+)
+
+var (
+	ErrBadMagic           = errors.New("bad frame magic")
+	ErrFrameTooLarge      = errors.New("frame too large")
+	ErrMalformedFrame     = errors.New("malformed frame")
+	ErrUnsupportedVersion = errors.New("unsupported protocol version")
+)
+
+// Frame - кадр бинарного протокола
+type Frame struct {
+	Version   uint8
+	Opcode    Opcode
+	Flags     Flags
+	RequestID uint32
+	Fields    [][]byte
+}
+
+// Hello возвращает приветствие клиента с диапазоном поддерживаемых версий
+func Hello(minVersion, maxVersion uint8) *Frame {
+	return &Frame{Version: maxVersion, Opcode: OpHello, Fields: [][]byte{{minVersion}}}
+}
+
+// Negotiate выбирает для приветствия клиента наибольшую версию, которую поддерживают обе стороны
+func Negotiate(hello *Frame) (uint8, error) {
+	if hello.Opcode != OpHello || len(hello.Fields) != 1 || len(hello.Fields[0]) != 1 {
+		return 0, fmt.Errorf("%w: hello must carry the minimum version in a single one-byte field", ErrMalformedFrame)
+	}
+
+	clientMin, clientMax := hello.Fields[0][0], hello.Version
+	version := min(clientMax, MaxVersion)
+	if clientMin > clientMax || version < max(clientMin, MinVersion) {
+		return 0, fmt.Errorf(
+			"%w: client supports %d-%d, server supports %d-%d",
+			ErrUnsupportedVersion, clientMin, clientMax, MinVersion, MaxVersion,
+		)
+	}
+
+	return version, nil
+}
+
+// Response возвращает успешный ответ на запрос id
+func Response(version uint8, id uint32, result []byte) *Frame {
+	return &Frame{Version: version, Opcode: OpResponse, RequestID: id, Fields: [][]byte{result}}
+}
+
+// ErrorResponse возвращает ответ с ошибкой на запрос id
+func ErrorResponse(version uint8, id uint32, code, message string) *Frame {
+	return &Frame{
+		Version:   version,
+		Opcode:    OpResponse,
+		Flags:     FlagError,
+		RequestID: id,
+		Fields:    [][]byte{[]byte(code), []byte(message)},
+	}
+}
+
+// Size возвращает размер закодированного кадра
+func (f *Frame) Size() int {
+	return HeaderSize + f.bodySize()
+}
+
+func (f *Frame) bodySize() int {
+	size := 0
+	for _, field := range f.Fields {
+		size += fieldSize + len(field)
+	}
+
+	return size
+}
+
+// Append дописывает закодированный кадр к dst
+func (f *Frame) Append(dst []byte) []byte {
+	dst = append(dst, Magic, f.Version, byte(f.Opcode), byte(f.Flags))
+	dst = binary.BigEndian.AppendUint32(dst, f.RequestID)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(f.bodySize()))
+	for _, field := range f.Fields {
+		dst = binary.BigEndian.AppendUint32(dst, uint32(len(field)))
+		dst = append(dst, field...)
+	}
+
+	return dst
+}
+
+// Unmarshal декодирует кадр из начала data и возвращает число прочитанных байт.
+// Если кадр в data не помещается, возвращается io.ErrUnexpectedEOF
+func Unmarshal(data []byte, maxBodySize int) (*Frame, int, error) {
+	if len(data) < HeaderSize {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+
+	f, length, err := parseHeader(data[:HeaderSize], maxBodySize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(data)-HeaderSize < length {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+
+	if f.Fields, err = parseFields(data[HeaderSize : HeaderSize+length]); err != nil {
+		return nil, 0, err
+	}
+
+	return f, HeaderSize + length, nil
+}
+
+func parseHeader(header []byte, maxBodySize int) (*Frame, int, error) {
+	if header[0] != Magic {
+		return nil, 0, fmt.Errorf("%w: 0x%02x", ErrBadMagic, header[0])
+	}
+
+	f := &Frame{
+		Version:   header[1],
+		Opcode:    Opcode(header[2]),
+		Flags:     Flags(header[3]),
+		RequestID: binary.BigEndian.Uint32(header[4:8]),
+	}
+
+	length := binary.BigEndian.Uint32(header[8:12])
+	if uint64(length) > uint64(maxBodySize) {
+		return nil, 0, fmt.Errorf("%w: %d bytes, limit %d", ErrFrameTooLarge, length, maxBodySize)
+	}
+
+	return f, int(length), nil
+}
+
+// parseFields разбирает тело кадра. Поля ссылаются на body без копирования
+func parseFields(body []byte) ([][]byte, error) {
+	var fields [][]byte
+	for len(body) > 0 {
+		if len(body) < fieldSize {
+			return nil, fmt.Errorf("%w: truncated field length", ErrMalformedFrame)
+		}
+
+		length := binary.BigEndian.Uint32(body)
+		body = body[fieldSize:]
+		if uint64(length) > uint64(len(body)) {
+			return nil, fmt.Errorf("%w: field of %d bytes exceeds frame body", ErrMalformedFrame, length)
+		}
+
+		fields = append(fields, body[:length:length])
+		body = body[length:]
+	}
+
+	return fields, nil
+}
+
+// Decoder читает кадры из потока
+type Decoder struct {
+	r           *bufio.Reader
+	maxBodySize int
+	header      [HeaderSize]byte
+}
+
+// NewDecoder создает декодер, отклоняющий кадры с телом больше maxBodySize байт
+func NewDecoder(r *bufio.Reader, maxBodySize int) *Decoder {
+	return &Decoder{r: r, maxBodySize: maxBodySize}
+}
+
+// Decode читает следующий кадр. Конец потока между кадрами возвращается как io.EOF,
+// внутри кадра - как io.ErrUnexpectedEOF
+func (d *Decoder) Decode() (*Frame, error) {
+	if _, err := io.ReadFull(d.r, d.header[:]); err != nil {
+		return nil, err
+	}
+
+	f, length, err := parseHeader(d.header[:], d.maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, length)
+	if _, err = io.ReadFull(d.r, body); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	if f.Fields, err = parseFields(body); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
@@ -0,0 +1,152 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMaxBodySize = 1 << 10
+
+func TestFrame_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame *Frame
+	}{
+		{name: "Приветствие", frame: Hello(MinVersion, MaxVersion)},
+		{
+			name: "Команда с произвольными байтами",
+			frame: &Frame{
+				Version:   Version1,
+				Opcode:    OpCommand,
+				Flags:     FlagAsync,
+				RequestID: 42,
+				Fields:    [][]byte{[]byte("SET"), []byte("key"), []byte("line1\nline2\x00\xff")},
+			},
+		},
+		{name: "Пустое поле", frame: Response(Version1, 7, []byte{})},
+		{name: "Ошибка", frame: ErrorResponse(Version1, 1<<32-1, "ERR_NOT_FOUND", "key not found")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.frame.Append(nil)
+			assert.Equal(t, tt.frame.Size(), len(data))
+
+			decoded, n, err := Unmarshal(data, testMaxBodySize)
+			require.NoError(t, err)
+			assert.Equal(t, len(data), n)
+			assert.Equal(t, tt.frame, decoded)
+
+			decoded, err = NewDecoder(bufio.NewReader(bytes.NewReader(data)), testMaxBodySize).Decode()
+			require.NoError(t, err)
+			assert.Equal(t, tt.frame, decoded)
+		})
+	}
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	valid := (&Frame{Version: Version1, Opcode: OpCommand, Fields: [][]byte{[]byte("PING")}}).Append(nil)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{name: "Неполный заголовок", data: valid[:HeaderSize-1], expected: io.ErrUnexpectedEOF},
+		{name: "Неполное тело", data: valid[:len(valid)-1], expected: io.ErrUnexpectedEOF},
+		{name: "Неверный magic", data: append([]byte{'G'}, valid[1:]...), expected: ErrBadMagic},
+		{
+			name:     "Слишком большое тело",
+			data:     []byte{Magic, Version1, byte(OpCommand), 0, 0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff},
+			expected: ErrFrameTooLarge,
+		},
+		{
+			name:     "Поле длиннее тела",
+			data:     []byte{Magic, Version1, byte(OpCommand), 0, 0, 0, 0, 1, 0, 0, 0, 5, 0, 0, 0, 9, 'x'},
+			expected: ErrMalformedFrame,
+		},
+		{
+			name:     "Обрезанная длина поля",
+			data:     []byte{Magic, Version1, byte(OpCommand), 0, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0},
+			expected: ErrMalformedFrame,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Unmarshal(tt.data, testMaxBodySize)
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+}
+
+func TestDecoder_EOF(t *testing.T) {
+	data := Hello(MinVersion, MaxVersion).Append(nil)
+
+	decoder := NewDecoder(bufio.NewReader(bytes.NewReader(data)), testMaxBodySize)
+	_, err := decoder.Decode()
+	require.NoError(t, err)
+
+	_, err = decoder.Decode()
+	assert.ErrorIs(t, err, io.EOF)
+
+	decoder = NewDecoder(bufio.NewReader(bytes.NewReader(data[:len(data)-1])), testMaxBodySize)
+	_, err = decoder.Decode()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name     string
+		hello    *Frame
+		version  uint8
+		expected error
+	}{
+		{name: "Совпадающие версии", hello: Hello(MinVersion, MaxVersion), version: MaxVersion},
+		{name: "Клиент новее сервера", hello: Hello(MinVersion, MaxVersion+3), version: MaxVersion},
+		{name: "Клиент требует более новую версию", hello: Hello(MaxVersion+1, MaxVersion+2), expected: ErrUnsupportedVersion},
+		{name: "Пустой диапазон", hello: Hello(2, 1), expected: ErrUnsupportedVersion},
+		{name: "Без наименьшей версии", hello: &Frame{Version: Version1, Opcode: OpHello}, expected: ErrMalformedFrame},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := Negotiate(tt.hello)
+			assert.ErrorIs(t, err, tt.expected)
+			assert.Equal(t, tt.version, version)
+		})
+	}
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	f.Add(Hello(MinVersion, MaxVersion).Append(nil))
+	f.Add(Response(Version1, 1, []byte("value\n")).Append(nil))
+	f.Add(ErrorResponse(Version1, 2, "ERR_SYNTAX", "bad").Append(nil))
+	f.Add([]byte{Magic, Version1, byte(OpCommand), 0, 0, 0, 0, 1, 0, 0, 0, 4, 0, 0, 0, 9})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		frame, n, err := Unmarshal(data, testMaxBodySize)
+		if err != nil {
+			return
+		}
+
+		// успешно декодированный кадр кодируется обратно в те же байты
+		if encoded := frame.Append(nil); !bytes.Equal(encoded, data[:n]) {
+			t.Fatalf("round trip mismatch: %x != %x", encoded, data[:n])
+		}
+
+		streamed, err := NewDecoder(bufio.NewReader(bytes.NewReader(data)), testMaxBodySize).Decode()
+		if err != nil {
+			t.Fatalf("Decoder failed on a frame accepted by Unmarshal: %v", err)
+		}
+
+		if !bytes.Equal(streamed.Append(nil), data[:n]) {
+			t.Fatalf("Decoder and Unmarshal disagree on %x", data[:n])
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package network
+
+import (
+	"context"
+	"sync"
+)
+
+// connLimiter ограничивает число одновременных соединений. Предел можно менять
+// во время работы: уменьшение не закрывает уже принятые соединения
+type connLimiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active int
+	max    int
+}
+
+func newConnLimiter(max int) *connLimiter {
+	l := &connLimiter{max: max}
+	l.cond = sync.NewCond(&l.mu)
+
+	return l
+}
+
+// acquire ждет свободного места. Возвращает false, если ctx отменен раньше
+func (l *connLimiter) acquire(ctx context.Context) bool {
+	stop := context.AfterFunc(ctx, func() {
+		l.mu.Lock()
+		l.cond.Broadcast()
+		l.mu.Unlock()
+	})
+	defer stop()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.max > 0 && l.active >= l.max {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		l.cond.Wait()
+	}
+
+	if ctx.Err() != nil {
+		return false
+	}
+
+	l.active++
+
+	return true
+}
+
+func (l *connLimiter) release() {
+	l.mu.Lock()
+	l.active--
+	l.mu.Unlock()
+	l.cond.Signal()
+}
+
+// setMax меняет предел, 0 - без ограничений
+func (l *connLimiter) setMax(max int) {
+	l.mu.Lock()
+	l.max = max
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
@@ -0,0 +1,35 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnLimiter(t *testing.T) {
+	l := newConnLimiter(1)
+	assert.True(t, l.acquire(context.Background()))
+
+	acquired := make(chan bool)
+	go func() { acquired <- l.acquire(context.Background()) }()
+
+	select {
+	case <-acquired:
+		t.Fatal("предел соединений не соблюдается")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// увеличение предела пропускает ожидающее соединение
+	l.setMax(2)
+	assert.True(t, <-acquired)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { acquired <- l.acquire(ctx) }()
+	cancel()
+	assert.False(t, <-acquired)
+
+	l.release()
+	assert.True(t, l.acquire(context.Background()))
+}
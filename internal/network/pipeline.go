@@ -0,0 +1,78 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"sync"
+	"time"
+)
+
+var responseTerminator = []byte("\n\n")
+
+// responseWriter буферизует ответы одного соединения и отправляет их клиенту,
+// когда в очереди не осталось прочитанных запросов или буфер заполнен
+type responseWriter struct {
+	conn    net.Conn
+	timeout time.Duration
+	queued  func() int // число прочитанных, но еще не начатых запросов
+
+	mu  sync.Mutex
+	buf *bufio.Writer
+}
+
+func newResponseWriter(conn net.Conn, timeout time.Duration, queued func() int) *responseWriter {
+	return &responseWriter{
+		conn:    conn,
+		timeout: timeout,
+		queued:  queued,
+		buf:     bufio.NewWriter(conn),
+	}
+}
+
+// finish записывает закодированный ответ на запрос; nil - запрос завершился без ответа
+func (w *responseWriter) finish(response []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.conn.SetWriteDeadline(time.Now().Add(w.timeout)); err != nil {
+		return err
+	}
+
+	if response != nil {
+		if _, err := w.buf.Write(response); err != nil {
+			return err
+		}
+	}
+
+	// ответ на следующий запрос из очереди будет готов скоро, и оба уйдут одной записью
+	if w.queued() > 0 {
+		return nil
+	}
+
+	return w.buf.Flush()
+}
+
+// textResponse кодирует ответ текстового протокола: строки ответа и пустая строка
+func textResponse(response []byte) []byte {
+	if response == nil {
+		return nil
+	}
+
+	return append(bytes.TrimRight(response, "\n"), responseTerminator...)
+}
+
+// frameID возвращает идентификатор запроса из префикса "@id "
+func frameID(request []byte) (string, bool) {
+	rest, ok := bytes.CutPrefix(request, []byte("@"))
+	if !ok {
+		return "", false
+	}
+
+	id, _, ok := bytes.Cut(rest, []byte(" "))
+	if !ok || len(id) == 0 {
+		return "", false
+	}
+
+	return string(id), true
+}
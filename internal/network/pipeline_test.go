@@ -0,0 +1,177 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// framedHandler включает режим FRAMED по запросу FRAMED, задерживает запросы SLOW
+// и отвечает текстом запроса без идентификатора
+func framedHandler(ctx context.Context, request []byte) []byte {
+	_, body, _ := strings.Cut(string(request), " ")
+	if !strings.HasPrefix(string(request), "@") {
+		body = string(request)
+	}
+
+	switch body {
+	case "FRAMED":
+		s, _ := session.FromContext(ctx)
+		s.SetFramed(true)
+	case "SLOW":
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return []byte("OK " + body)
+}
+
+func readResponses(t *testing.T, reader *bufio.Reader, n int) []string {
+	t.Helper()
+
+	responses := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+
+			if line == "\n" {
+				break
+			}
+
+			lines = append(lines, strings.TrimSuffix(line, "\n"))
+		}
+
+		responses = append(responses, strings.Join(lines, "\n"))
+	}
+
+	return responses
+}
+
+func TestTCPServer_Pipelining(t *testing.T) {
+	address := startServer(t, func(_ context.Context, request []byte) []byte {
+		if strings.HasSuffix(string(request), "0") {
+			time.Sleep(time.Millisecond)
+		}
+
+		return append([]byte("echo "), request...)
+	}, WithMaxInFlight(4))
+
+	client, err := NewTCPClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	requests := make([][]byte, 1000)
+	for i := range requests {
+		requests[i] = []byte(fmt.Sprintf("GET key%d", i))
+	}
+
+	responses, err := client.Pipeline(requests)
+	require.NoError(t, err)
+	require.Len(t, responses, len(requests))
+
+	for i, response := range responses {
+		assert.Equal(t, fmt.Sprintf("echo GET key%d", i), string(response))
+	}
+}
+
+func TestTCPServer_FramedOutOfOrder(t *testing.T) {
+	address := startServer(t, framedHandler)
+
+	conn, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+
+	// до включения режима запросы с идентификатором упорядочены, а ответы без префикса
+	_, err = conn.Write([]byte("@1 SLOW\n@2 FAST\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"OK SLOW", "OK FAST"}, readResponses(t, reader, 2))
+
+	_, err = conn.Write([]byte("FRAMED\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"OK FRAMED"}, readResponses(t, reader, 1))
+
+	_, err = conn.Write([]byte("@a SLOW\n@b FAST\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"@b OK FAST", "@a OK SLOW"}, readResponses(t, reader, 2))
+
+	// запрос без идентификатора дожидается всех предыдущих
+	_, err = conn.Write([]byte("@c SLOW\nFAST\n@d FAST\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"@c OK SLOW", "OK FAST", "@d OK FAST"}, readResponses(t, reader, 3))
+}
+
+func TestTCPServer_MaxInFlight(t *testing.T) {
+	var mu sync.Mutex
+	active, peak := 0, 0
+
+	address := startServer(t, func(ctx context.Context, request []byte) []byte {
+		if string(request) == "FRAMED" {
+			return framedHandler(ctx, request)
+		}
+
+		mu.Lock()
+		active++
+		peak = max(peak, active)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+
+		return []byte("OK")
+	}, WithMaxInFlight(3))
+
+	conn, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+
+	_, err = conn.Write([]byte("FRAMED\n"))
+	require.NoError(t, err)
+	readResponses(t, reader, 1)
+
+	var batch strings.Builder
+	for i := 0; i < 12; i++ {
+		fmt.Fprintf(&batch, "@%d GET key\n", i)
+	}
+
+	_, err = conn.Write([]byte(batch.String()))
+	require.NoError(t, err)
+	assert.Len(t, readResponses(t, reader, 12), 12)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 3, peak)
+}
+
+func TestFrameID(t *testing.T) {
+	tests := []struct {
+		request  string
+		expected string
+		ok       bool
+	}{
+		{"@req-1 GET key", "req-1", true},
+		{"GET key", "", false},
+		{"@ GET key", "", false},
+		{"@only", "", false},
+	}
+
+	for _, tt := range tests {
+		id, ok := frameID([]byte(tt.request))
+		assert.Equal(t, tt.expected, id, tt.request)
+		assert.Equal(t, tt.ok, ok, tt.request)
+	}
+}
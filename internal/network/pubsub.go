@@ -0,0 +1,116 @@
+package network
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/network/codec"
+	"github.com/patyukin/mdb/internal/pubsub"
+	"github.com/patyukin/mdb/internal/session"
+	"go.uber.org/zap"
+)
+
+// Виды сообщений pub/sub. В текстовом протоколе сообщение приходит отдельным ответом
+// "MESSAGE канал данные" или "PMESSAGE шаблон канал данные", в бинарном - кадром
+// OpPush с полями "message", канал, данные или "pmessage", шаблон, канал, данные
+const (
+	pushMessage  = "message"
+	pushPMessage = "pmessage"
+)
+
+// WithPubSub доставляет клиентам сообщения каналов, на которые они подписались
+func WithPubSub(b *pubsub.Broker) TCPServerOption {
+	return func(s *TCPServer) {
+		s.broker = b
+	}
+}
+
+// forwardMessages отправляет клиенту сообщения из очереди подписчика между ответами
+// на запросы. Подписчик, не успевающий забирать сообщения, отключается вместе с соединением
+func (s *TCPServer) forwardMessages(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	conn net.Conn,
+	sess *session.Session,
+	sub *pubsub.Subscriber,
+	w *responseWriter,
+	encode func(pubsub.Message) []byte,
+) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.Done():
+			s.logger.Warn("Disconnecting slow pub/sub subscriber", zap.String("remote", sess.RemoteAddr))
+			cancel()
+			_ = conn.Close()
+			return
+		case <-sub.Ready():
+			for _, m := range sub.Drain() {
+				if err := w.finish(encode(m)); err != nil {
+					if ctx.Err() == nil {
+						s.logger.Warn("failed w.finish", zap.String("remote", sess.RemoteAddr), zap.Error(err))
+					}
+
+					cancel()
+					return
+				}
+			}
+		}
+	}
+}
+
+// subscriptions возвращает число подписок соединения
+func subscriptions(sess *session.Session) int {
+	if sub, ok := sess.Subscriber(); ok {
+		return sub.Subscriptions()
+	}
+
+	return 0
+}
+
+// readDeadline возвращает срок ожидания следующего запроса. Соединение с подписками
+// ждет запросов без ограничения: сообщения приходят ему без запросов. Соединение,
+// запрос которого ждет события, например BLPOP, тоже не считается простаивающим
+func (s *TCPServer) readDeadline(sess *session.Session) time.Time {
+	if subscriptions(sess) > 0 || sess.Blocked() {
+		return time.Time{}
+	}
+
+	return time.Now().Add(time.Duration(s.idleTimeout.Load()))
+}
+
+// rearmReadDeadline переносит срок уже начатого ожидания запроса, когда запрос
+// изменил подписки соединения или начал либо закончил ждать события
+func (s *TCPServer) rearmReadDeadline(ctx context.Context, conn net.Conn, sess *session.Session) {
+	_ = conn.SetReadDeadline(s.readDeadline(sess))
+
+	// остановка сервера или закрытие соединения могли прервать чтение одновременно с нами
+	if ctx.Err() != nil || s.draining.Load() {
+		_ = conn.SetReadDeadline(time.Now())
+	}
+}
+
+// textMessage кодирует сообщение для текстового протокола. Части заключаются в кавычки
+// по правилам parser.Quote
+func textMessage(m pubsub.Message) []byte {
+	parts := []string{strings.ToUpper(pushMessage), parser.Quote(m.Channel), parser.Quote(m.Payload)}
+	if m.Pattern != "" {
+		parts = []string{strings.ToUpper(pushPMessage), parser.Quote(m.Pattern), parser.Quote(m.Channel), parser.Quote(m.Payload)}
+	}
+
+	return textResponse([]byte(strings.Join(parts, " ")))
+}
+
+// pushFrame кодирует сообщение для бинарного протокола
+func pushFrame(version uint8, m pubsub.Message) []byte {
+	fields := [][]byte{[]byte(pushMessage), []byte(m.Channel), []byte(m.Payload)}
+	if m.Pattern != "" {
+		fields = [][]byte{[]byte(pushPMessage), []byte(m.Pattern), []byte(m.Channel), []byte(m.Payload)}
+	}
+
+	return (&codec.Frame{Version: version, Opcode: codec.OpPush, Fields: fields}).Append(nil)
+}
@@ -0,0 +1,170 @@
+package network
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patyukin/mdb/internal/database/compute/parser"
+	"github.com/patyukin/mdb/internal/pubsub"
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startPubSubServer запускает сервер, выполняющий SUBSCRIBE, PSUBSCRIBE, UNSUBSCRIBE
+// и PUBLISH по обоим протоколам
+func startPubSubServer(t *testing.T, broker *pubsub.Broker, options ...TCPServerOption) string {
+	t.Helper()
+
+	execute := func(ctx context.Context, action string, args []string) string {
+		sess, _ := session.FromContext(ctx)
+		sub, _ := sess.Subscriber()
+
+		switch action {
+		case parser.SUBSCRIBE:
+			return strconv.Itoa(broker.Subscribe(sub, args...))
+		case parser.PSUBSCRIBE:
+			return strconv.Itoa(broker.PSubscribe(sub, args...))
+		case parser.UNSUBSCRIBE:
+			return strconv.Itoa(broker.Unsubscribe(sub, args...))
+		case parser.PUBLISH:
+			return strconv.Itoa(broker.Publish(args[0], args[1]))
+		default:
+			return action
+		}
+	}
+
+	text := func(ctx context.Context, request []byte) []byte {
+		fields := strings.Fields(string(request))
+		return []byte(execute(ctx, fields[0], fields[1:]))
+	}
+
+	binary := func(ctx context.Context, cmd *parser.Command) (string, error) {
+		return execute(ctx, cmd.Action, cmd.Args), nil
+	}
+
+	options = append(options, WithPubSub(broker), WithBinaryProtocol(binary, testErrorCode))
+
+	return startServer(t, text, options...)
+}
+
+func TestTCPServer_PubSub(t *testing.T) {
+	address := startPubSubServer(t, pubsub.NewBroker(16))
+
+	subscriber, err := NewTCPClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = subscriber.Close() }()
+
+	response, err := subscriber.Send([]byte("SUBSCRIBE news"))
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(response))
+
+	binary, err := NewBinaryClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = binary.Close() }()
+
+	result, err := binary.Do([]byte("PSUBSCRIBE"), []byte("n*"))
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(result))
+
+	publisher, err := NewTCPClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = publisher.Close() }()
+
+	response, err = publisher.Send([]byte("PUBLISH news hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(response))
+
+	message, err := subscriber.Receive(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "MESSAGE news hello", string(message))
+
+	fields, err := binary.Receive(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("pmessage"), []byte("n*"), []byte("news"), []byte("hello")}, fields)
+
+	// сообщение, пришедшее до ответа, не принимается за ответ
+	response, err = publisher.Send([]byte("PUBLISH news again"))
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(response))
+
+	response, err = subscriber.Send([]byte("UNSUBSCRIBE"))
+	require.NoError(t, err)
+	assert.Equal(t, "0", string(response))
+
+	message, err = subscriber.Receive(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "MESSAGE news again", string(message))
+}
+
+func TestTCPServer_PubSubIdleTimeout(t *testing.T) {
+	address := startPubSubServer(t, pubsub.NewBroker(16), WithIdleTimeout(50*time.Millisecond))
+
+	subscriber, err := NewTCPClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = subscriber.Close() }()
+
+	_, err = subscriber.Send([]byte("SUBSCRIBE news"))
+	require.NoError(t, err)
+
+	idle, err := NewTCPClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = idle.Close() }()
+
+	_, err = idle.Send([]byte("PING"))
+	require.NoError(t, err)
+
+	time.Sleep(150 * time.Millisecond)
+
+	// соединение без подписок закрыто по таймауту простоя, подписчик ждет сообщений
+	_, err = idle.Send([]byte("PING"))
+	assert.Error(t, err)
+
+	publisher, err := NewTCPClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = publisher.Close() }()
+
+	response, err := publisher.Send([]byte("PUBLISH news hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(response))
+
+	message, err := subscriber.Receive(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "MESSAGE news hello", string(message))
+}
+
+func TestTCPServer_PubSubSlowConsumer(t *testing.T) {
+	broker := pubsub.NewBroker(1)
+	address := startPubSubServer(t, broker, WithMaxMessageSize(1<<20))
+
+	conn, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	_, err = conn.Write([]byte("SUBSCRIBE news\n"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return broker.Channels() == 1 }, time.Second, time.Millisecond)
+
+	publisher, err := NewTCPClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = publisher.Close() }()
+
+	// подписчик не читает: сообщения заполняют буферы сокета, затем очередь подписчика
+	payload := strings.Repeat("x", 64<<10)
+	require.Eventually(t, func() bool {
+		response, err := publisher.Send([]byte("PUBLISH news " + payload))
+		return err == nil && string(response) == "0"
+	}, 5*time.Second, time.Millisecond)
+
+	assert.Equal(t, uint64(1), broker.SlowConsumers())
+
+	// сервер закрыл соединение: после уже отправленных данных чтение завершается
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = io.Copy(io.Discard, conn)
+	assert.NoError(t, err)
+}
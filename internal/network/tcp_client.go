@@ -0,0 +1,189 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+var ErrInvalidRequest = errors.New("request must be a single line")
+
+type TCPClient struct {
+	conn        net.Conn
+	reader      *bufio.Reader
+	idleTimeout time.Duration
+	messages    [][]byte // сообщения pub/sub, прочитанные в ожидании ответа
+}
+
+func NewTCPClient(address string, idleTimeout time.Duration) (*TCPClient, error) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed net.Dial: %w", err)
+	}
+
+	return NewClient(conn, idleTimeout), nil
+}
+
+// NewTLSClient устанавливает соединение по TLS
+func NewTLSClient(address string, idleTimeout time.Duration, cfg *tls.Config) (*TCPClient, error) {
+	dialer := &net.Dialer{Timeout: idleTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed tls.DialWithDialer: %w", err)
+	}
+
+	return NewClient(conn, idleTimeout), nil
+}
+
+// NewClient оборачивает уже установленное соединение
+func NewClient(conn net.Conn, idleTimeout time.Duration) *TCPClient {
+	if idleTimeout <= 0 {
+		idleTimeout = defaultIdleTimeout
+	}
+
+	return &TCPClient{
+		conn:        conn,
+		reader:      bufio.NewReader(conn),
+		idleTimeout: idleTimeout,
+	}
+}
+
+// Send отправляет запрос и возвращает ответ сервера без завершающей пустой строки
+func (c *TCPClient) Send(request []byte) ([]byte, error) {
+	request = bytes.TrimSpace(request)
+	if len(request) == 0 || bytes.ContainsAny(request, "\r\n") {
+		return nil, ErrInvalidRequest
+	}
+
+	if err := c.conn.SetDeadline(time.Now().Add(c.idleTimeout)); err != nil {
+		return nil, fmt.Errorf("failed c.conn.SetDeadline: %w", err)
+	}
+
+	if _, err := c.conn.Write(append(request, '\n')); err != nil {
+		return nil, fmt.Errorf("failed c.conn.Write: %w", err)
+	}
+
+	return c.readResponse()
+}
+
+// Pipeline отправляет запросы, не дожидаясь ответов, и возвращает ответы в порядке
+// запросов. Запросы отправляются параллельно с чтением ответов, чтобы сервер,
+// исчерпавший лимит запросов в обработке, мог продолжить отвечать
+func (c *TCPClient) Pipeline(requests [][]byte) ([][]byte, error) {
+	var batch []byte
+	for _, request := range requests {
+		request = bytes.TrimSpace(request)
+		if len(request) == 0 || bytes.ContainsAny(request, "\r\n") {
+			return nil, ErrInvalidRequest
+		}
+
+		batch = append(append(batch, request...), '\n')
+	}
+
+	if err := c.conn.SetDeadline(time.Now().Add(c.idleTimeout)); err != nil {
+		return nil, fmt.Errorf("failed c.conn.SetDeadline: %w", err)
+	}
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := c.conn.Write(batch)
+		written <- err
+	}()
+
+	responses := make([][]byte, 0, len(requests))
+	for range requests {
+		if err := c.conn.SetDeadline(time.Now().Add(c.idleTimeout)); err != nil {
+			return nil, fmt.Errorf("failed c.conn.SetDeadline: %w", err)
+		}
+
+		response, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+
+		responses = append(responses, response)
+	}
+
+	if err := <-written; err != nil {
+		return nil, fmt.Errorf("failed c.conn.Write: %w", err)
+	}
+
+	return responses, nil
+}
+
+// Receive возвращает следующее сообщение pub/sub, ожидая его не дольше timeout,
+// 0 - без ограничения
+func (c *TCPClient) Receive(timeout time.Duration) ([]byte, error) {
+	if len(c.messages) > 0 {
+		message := c.messages[0]
+		c.messages = c.messages[1:]
+
+		return message, nil
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	if err := c.conn.SetReadDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("failed c.conn.SetReadDeadline: %w", err)
+	}
+
+	message, err := c.readFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	if !isPush(message) {
+		return nil, fmt.Errorf("%w: %q", ErrUnexpectedResponse, message)
+	}
+
+	return message, nil
+}
+
+// readResponse читает ответ на запрос, откладывая сообщения pub/sub для Receive
+func (c *TCPClient) readResponse() ([]byte, error) {
+	for {
+		response, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		if !isPush(response) {
+			return response, nil
+		}
+
+		c.messages = append(c.messages, response)
+	}
+}
+
+// isPush отличает сообщение pub/sub от ответа: ответ всегда начинается с кода
+func isPush(response []byte) bool {
+	return bytes.HasPrefix(response, []byte("MESSAGE ")) || bytes.HasPrefix(response, []byte("PMESSAGE "))
+}
+
+// readFrame читает строки до пустой строки
+func (c *TCPClient) readFrame() ([]byte, error) {
+	var response []byte
+	for {
+		line, err := c.reader.ReadBytes('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed c.reader.ReadBytes: %w", err)
+		}
+
+		if len(line) == 1 {
+			return bytes.TrimSuffix(response, []byte("\n")), nil
+		}
+
+		response = append(response, line...)
+	}
+}
+
+func (c *TCPClient) Close() error {
+	return c.conn.Close()
+}
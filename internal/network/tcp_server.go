@@ -0,0 +1,511 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/patyukin/mdb/internal/pubsub"
+	"github.com/patyukin/mdb/internal/session"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxMessageSize = 4 << 10
+	defaultIdleTimeout    = 5 * time.Minute
+	defaultMaxInFlight    = 64
+)
+
+// Запросы передаются по одной строке. Ответ может состоять из нескольких непустых строк
+// и завершается пустой строкой. Клиент может отправлять запросы, не дожидаясь ответов:
+// ответы возвращаются в порядке запросов. В режиме PIPELINE FRAMED запросы
+// с префиксом "@id " выполняются параллельно, а ответы на них возвращаются по мере
+// готовности с тем же префиксом. Соединение, первый байт которого codec.Magic,
+// обслуживается бинарным протоколом, если он включен
+
+// TCPHandler обрабатывает один запрос клиента. Контекст отменяется при отключении клиента
+type TCPHandler func(ctx context.Context, request []byte) []byte
+
+type TCPServer struct {
+	address        string
+	limiter        *connLimiter
+	maxMessageSize atomic.Int64
+	idleTimeout    atomic.Int64
+	maxInFlight    atomic.Int64
+	tls            *TLSProvider
+	logger         *zap.Logger
+
+	commandHandler CommandHandler
+	errorCode      func(error) string
+	broker         *pubsub.Broker
+
+	activeConnections atomic.Int64
+	draining          atomic.Bool
+
+	unixSocket     string
+	unixSocketMode os.FileMode
+
+	mu          sync.Mutex
+	listeners   []net.Listener
+	conns       map[net.Conn]struct{}
+	cancelConns context.CancelFunc
+	done        chan struct{}
+}
+
+// TCPServerOption настраивает TCPServer
+type TCPServerOption func(*TCPServer)
+
+// WithMaxConnections ограничивает число одновременно обслуживаемых соединений, 0 - без ограничений
+func WithMaxConnections(n int) TCPServerOption {
+	return func(s *TCPServer) {
+		s.SetMaxConnections(n)
+	}
+}
+
+// WithMaxMessageSize ограничивает размер одного запроса в байтах
+func WithMaxMessageSize(size int) TCPServerOption {
+	return func(s *TCPServer) {
+		s.SetMaxMessageSize(size)
+	}
+}
+
+// WithMaxInFlight ограничивает число запросов одного соединения, прочитанных,
+// но еще не получивших ответ
+func WithMaxInFlight(n int) TCPServerOption {
+	return func(s *TCPServer) {
+		s.SetMaxInFlight(n)
+	}
+}
+
+// WithIdleTimeout задает время, после которого простаивающее соединение закрывается
+func WithIdleTimeout(timeout time.Duration) TCPServerOption {
+	return func(s *TCPServer) {
+		s.SetIdleTimeout(timeout)
+	}
+}
+
+// WithTLS включает TLS. Если клиент предъявил сертификат, сессия сразу получает
+// пользователя из его субъекта
+func WithTLS(p *TLSProvider) TCPServerOption {
+	return func(s *TCPServer) {
+		s.tls = p
+	}
+}
+
+func NewTCPServer(address string, logger *zap.Logger, options ...TCPServerOption) *TCPServer {
+	s := &TCPServer{
+		address: address,
+		limiter: newConnLimiter(0),
+		logger:  logger,
+		conns:   make(map[net.Conn]struct{}),
+	}
+	s.maxMessageSize.Store(defaultMaxMessageSize)
+	s.idleTimeout.Store(int64(defaultIdleTimeout))
+	s.maxInFlight.Store(defaultMaxInFlight)
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+// HandleQueries принимает соединения на TCP-адресе и Unix-сокете, если они заданы,
+// до отмены ctx и ждет завершения обработки уже принятых
+func (s *TCPServer) HandleQueries(ctx context.Context, handler TCPHandler) error {
+	var listeners []net.Listener
+	if s.address != "" {
+		listener, err := net.Listen("tcp", s.address)
+		if err != nil {
+			return fmt.Errorf("failed net.Listen: %w", err)
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	if s.unixSocket != "" {
+		listener, err := listenUnix(s.unixSocket, s.unixSocketMode)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+
+			return err
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	if len(listeners) == 0 {
+		return ErrNoListeners
+	}
+
+	return s.serve(ctx, listeners, handler)
+}
+
+// Serve обслуживает соединения уже открытого listener. Отмена ctx прерывает обработку
+// немедленно, Shutdown - после завершения выполняемых запросов
+func (s *TCPServer) Serve(ctx context.Context, listener net.Listener, handler TCPHandler) error {
+	return s.serve(ctx, []net.Listener{listener}, handler)
+}
+
+func (s *TCPServer) serve(ctx context.Context, listeners []net.Listener, handler TCPHandler) error {
+	for i, listener := range listeners {
+		network := listener.Addr().Network()
+		secure := s.tls != nil && network == "tcp"
+		s.logger.Info(
+			"Listening for connections",
+			zap.String("network", network),
+			zap.String("address", listener.Addr().String()),
+			zap.Bool("tls", secure),
+		)
+
+		if secure {
+			listeners[i] = tls.NewListener(listener, s.tls.Config())
+		}
+	}
+
+	connCtx, cancelConns := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	s.mu.Lock()
+	s.listeners, s.cancelConns, s.done = listeners, cancelConns, done
+	s.mu.Unlock()
+
+	stop := context.AfterFunc(ctx, s.closeListeners)
+
+	var wg sync.WaitGroup
+	defer func() {
+		stop()
+		wg.Wait()
+		cancelConns()
+		close(done)
+	}()
+
+	var accepting sync.WaitGroup
+	for _, listener := range listeners {
+		accepting.Add(1)
+		go func() {
+			defer accepting.Done()
+			s.accept(ctx, connCtx, listener, handler, &wg)
+		}()
+	}
+
+	accepting.Wait()
+
+	return nil
+}
+
+// accept принимает соединения одного listener, пока он не будет закрыт
+func (s *TCPServer) accept(ctx, connCtx context.Context, listener net.Listener, handler TCPHandler, wg *sync.WaitGroup) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || s.draining.Load() || errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			s.logger.Error("failed listener.Accept", zap.Error(err))
+			continue
+		}
+
+		if !s.limiter.acquire(ctx) {
+			_ = conn.Close()
+			return
+		}
+
+		wg.Add(1)
+		s.activeConnections.Add(1)
+		go func() {
+			defer wg.Done()
+			defer s.activeConnections.Add(-1)
+			defer s.limiter.release()
+
+			s.handleConnection(connCtx, conn, handler)
+		}()
+	}
+}
+
+func (s *TCPServer) closeListeners() {
+	s.mu.Lock()
+	listeners := s.listeners
+	s.mu.Unlock()
+
+	for _, listener := range listeners {
+		if err := listener.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			s.logger.Warn("failed listener.Close", zap.Error(err))
+		}
+	}
+}
+
+// Shutdown прекращает прием соединений и чтение новых запросов, дожидается ответов
+// на уже полученные запросы и закрывает соединения. Если ctx завершается раньше,
+// выполняемые запросы отменяются, а Shutdown возвращает ошибку
+func (s *TCPServer) Shutdown(ctx context.Context) error {
+	s.draining.Store(true)
+
+	s.mu.Lock()
+	cancelConns, done := s.cancelConns, s.done
+	for conn := range s.conns {
+		// прерывает ожидание следующего запроса, не затрагивая выполняемый
+		_ = conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+
+	s.closeListeners()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	cancelConns()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	<-done
+
+	return fmt.Errorf("failed to drain connections: %w", ctx.Err())
+}
+
+// SetMaxConnections меняет предел одновременных соединений, 0 - без ограничений
+func (s *TCPServer) SetMaxConnections(n int) {
+	s.limiter.setMax(n)
+}
+
+// SetMaxMessageSize меняет предел размера запроса для новых соединений
+func (s *TCPServer) SetMaxMessageSize(size int) {
+	if size > 0 {
+		s.maxMessageSize.Store(int64(size))
+	}
+}
+
+// SetIdleTimeout меняет время простоя, после которого соединение закрывается.
+// Новое значение действует с очередного ожидания запроса
+func (s *TCPServer) SetIdleTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		s.idleTimeout.Store(int64(timeout))
+	}
+}
+
+// SetMaxInFlight меняет предел запросов, выполняемых одновременно в одном соединении.
+// Новое значение действует для новых соединений
+func (s *TCPServer) SetMaxInFlight(n int) {
+	if n > 0 {
+		s.maxInFlight.Store(int64(n))
+	}
+}
+
+// ActiveConnections возвращает число обслуживаемых в данный момент соединений
+func (s *TCPServer) ActiveConnections() int64 {
+	return s.activeConnections.Load()
+}
+
+// handshake выполняет TLS-рукопожатие до чтения первого запроса, чтобы пользователь
+// из клиентского сертификата был известен уже при обработке этого запроса
+func (s *TCPServer) handshake(ctx context.Context, conn *tls.Conn, sess *session.Session) bool {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.idleTimeout.Load()))
+	defer cancel()
+
+	if err := conn.HandshakeContext(ctx); err != nil {
+		s.logger.Warn("failed conn.HandshakeContext", zap.String("remote", conn.RemoteAddr().String()), zap.Error(err))
+		return false
+	}
+
+	if certs := conn.ConnectionState().PeerCertificates; len(certs) > 0 {
+		sess.SetUser(CertificateUser(certs[0]))
+	}
+
+	return true
+}
+
+func (s *TCPServer) handleConnection(ctx context.Context, conn net.Conn, handler TCPHandler) {
+	defer func() {
+		if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			s.logger.Warn("failed conn.Close", zap.Error(err))
+		}
+	}()
+
+	s.mu.Lock()
+	s.conns[conn] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	sess := session.New(remoteAddr(conn), conn.LocalAddr().String())
+	if tlsConn, ok := conn.(*tls.Conn); ok && !s.handshake(ctx, tlsConn, sess) {
+		return
+	}
+
+	if s.broker != nil {
+		sub := s.broker.NewSubscriber()
+		sess.SetSubscriber(sub)
+		defer s.broker.Remove(sub)
+	}
+
+	ctx, cancel := context.WithCancel(session.NewContext(ctx, sess))
+	defer cancel()
+
+	sess.OnBlockedChange(func() { s.rearmReadDeadline(ctx, conn, sess) })
+
+	go func() {
+		<-ctx.Done()
+		// разблокирует чтение, если соединение закрывается по инициативе сервера
+		_ = conn.SetReadDeadline(time.Now())
+	}()
+
+	r := bufio.NewReader(conn)
+	if s.commandHandler != nil && s.binaryProtocol(conn, r) {
+		s.handleBinaryConnection(ctx, cancel, conn, r, sess)
+		return
+	}
+
+	// slots ограничивает число прочитанных запросов, ответы на которые еще не отправлены:
+	// когда слоты заняты, сервер перестает читать и клиент упирается в окно TCP
+	maxInFlight := int(s.maxInFlight.Load())
+	slots := make(chan struct{}, maxInFlight)
+	requests := make(chan []byte, maxInFlight)
+	w := newResponseWriter(conn, time.Duration(s.idleTimeout.Load()), func() int { return len(requests) })
+	go s.readRequests(ctx, cancel, conn, r, sess, slots, requests)
+	if sub, ok := sess.Subscriber(); ok {
+		go s.forwardMessages(ctx, cancel, conn, sess, sub, w, textMessage)
+	}
+
+	var inFlight sync.WaitGroup
+	defer inFlight.Wait()
+
+	for request := range requests {
+		id, tagged := frameID(request)
+		if !tagged || !sess.Framed() {
+			// запрос без идентификатора выполняется после всех предыдущих
+			inFlight.Wait()
+			if !s.respond(ctx, cancel, handler, request, "", slots, w, sess) {
+				return
+			}
+
+			continue
+		}
+
+		inFlight.Add(1)
+		go func() {
+			defer inFlight.Done()
+			s.respond(ctx, cancel, handler, request, id, slots, w, sess)
+		}()
+	}
+}
+
+// readRequests читает запросы, пока есть свободные слоты. Чтение идет параллельно
+// с обработкой, поэтому отключение клиента отменяет контекст выполняемых запросов.
+// При остановке сервера чтение прекращается, а выполняемые запросы завершаются
+func (s *TCPServer) readRequests(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	conn net.Conn,
+	r io.Reader,
+	sess *session.Session,
+	slots chan<- struct{},
+	requests chan<- []byte,
+) {
+	defer func() {
+		if !s.draining.Load() {
+			cancel()
+		}
+
+		close(requests)
+	}()
+
+	maxMessageSize := int(s.maxMessageSize.Load())
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, min(maxMessageSize, defaultMaxMessageSize)), maxMessageSize)
+
+	for {
+		if err := conn.SetReadDeadline(s.readDeadline(sess)); err != nil {
+			return
+		}
+
+		if s.draining.Load() {
+			return
+		}
+
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil && ctx.Err() == nil && !s.draining.Load() {
+				s.logger.Warn("failed scanner.Scan", zap.String("remote", sess.RemoteAddr), zap.Error(err))
+			}
+
+			return
+		}
+
+		request := bytes.TrimSpace(scanner.Bytes())
+		if len(request) == 0 {
+			continue
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		requests <- bytes.Clone(request)
+	}
+}
+
+// respond выполняет запрос и отправляет ответ, освобождая слот. Ответ на запрос
+// с идентификатором id начинается с "@id "
+func (s *TCPServer) respond(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	handler TCPHandler,
+	request []byte,
+	id string,
+	slots <-chan struct{},
+	w *responseWriter,
+	sess *session.Session,
+) bool {
+	defer func() { <-slots }()
+
+	subscribed := subscriptions(sess)
+	response := handler(ctx, request)
+	if subscriptions(sess) != subscribed {
+		s.rearmReadDeadline(ctx, w.conn, sess)
+	}
+
+	if ctx.Err() != nil {
+		_ = w.finish(nil)
+		return false
+	}
+
+	if id != "" {
+		response = append([]byte("@"+id+" "), response...)
+	}
+
+	if err := w.finish(textResponse(response)); err != nil {
+		s.logger.Warn("failed w.finish", zap.String("remote", sess.RemoteAddr), zap.Error(err))
+		cancel()
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,281 @@
+package network
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func startServer(t *testing.T, handler TCPHandler, options ...TCPServerOption) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	server := NewTCPServer(listener.Addr().String(), zap.NewNop(), options...)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, server.Serve(ctx, listener, handler))
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return listener.Addr().String()
+}
+
+func TestTCPServer_HandleQueries(t *testing.T) {
+	address := startServer(t, func(ctx context.Context, request []byte) []byte {
+		if _, ok := session.FromContext(ctx); !ok {
+			return []byte("no session")
+		}
+
+		if string(request) == "MULTI" {
+			return []byte("first\nsecond\n")
+		}
+
+		return append([]byte("echo "), request...)
+	})
+
+	client, err := NewTCPClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	response, err := client.Send([]byte("PING"))
+	require.NoError(t, err)
+	assert.Equal(t, "echo PING", string(response))
+
+	response, err = client.Send([]byte("MULTI"))
+	require.NoError(t, err)
+	assert.Equal(t, "first\nsecond", string(response))
+
+	_, err = client.Send([]byte("A\nB"))
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+func TestTCPServer_CancelOnDisconnect(t *testing.T) {
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+
+	address := startServer(t, func(ctx context.Context, _ []byte) []byte {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+		return nil
+	})
+
+	conn, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte("SLOW\n"))
+	require.NoError(t, err)
+
+	<-started
+	require.NoError(t, conn.Close())
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("контекст запроса не был отменен после отключения клиента")
+	}
+}
+
+func TestTCPServer_MaxConnections(t *testing.T) {
+	var mu sync.Mutex
+	active, peak := 0, 0
+
+	address := startServer(t, func(_ context.Context, request []byte) []byte {
+		mu.Lock()
+		active++
+		peak = max(peak, active)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+
+		return request
+	}, WithMaxConnections(1))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			client, err := NewTCPClient(address, time.Second)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			_, err = client.Send([]byte("GET key"))
+			assert.NoError(t, err)
+			assert.NoError(t, client.Close())
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 1, peak)
+}
+
+func TestTCPServer_ActiveConnections(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	server := NewTCPServer(listener.Addr().String(), zap.NewNop())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = server.Serve(ctx, listener, func(_ context.Context, request []byte) []byte { return request })
+	}()
+
+	client, err := NewTCPClient(listener.Addr().String(), time.Second)
+	require.NoError(t, err)
+
+	_, err = client.Send([]byte("PING"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), server.ActiveConnections())
+
+	require.NoError(t, client.Close())
+	assert.Eventually(t, func() bool { return server.ActiveConnections() == 0 }, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestTCPServer_Shutdown(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := NewTCPServer(listener.Addr().String(), zap.NewNop())
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(context.Background(), listener, func(ctx context.Context, request []byte) []byte {
+			started <- struct{}{}
+			select {
+			case <-release:
+				return request
+			case <-ctx.Done():
+				return []byte("canceled")
+			}
+		})
+	}()
+
+	idle, err := NewTCPClient(listener.Addr().String(), time.Second)
+	require.NoError(t, err)
+	defer func() { _ = idle.Close() }()
+
+	busy, err := NewTCPClient(listener.Addr().String(), time.Second)
+	require.NoError(t, err)
+	defer func() { _ = busy.Close() }()
+
+	responses := make(chan string, 1)
+	go func() {
+		response, err := busy.Send([]byte("SLOW"))
+		assert.NoError(t, err)
+		responses <- string(response)
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- server.Shutdown(context.Background()) }()
+
+	// новые соединения не принимаются, выполняемый запрос завершается
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err == nil {
+			_ = conn.Close()
+		}
+		return err != nil
+	}, time.Second, 5*time.Millisecond)
+
+	close(release)
+	assert.Equal(t, "SLOW", <-responses)
+	require.NoError(t, <-shutdown)
+	require.NoError(t, <-served)
+
+	// простаивавшее соединение закрыто сервером
+	_, err = idle.Send([]byte("PING"))
+	assert.Error(t, err)
+}
+
+func TestTCPServer_ShutdownGracePeriod(t *testing.T) {
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := NewTCPServer(listener.Addr().String(), zap.NewNop())
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(context.Background(), listener, func(ctx context.Context, _ []byte) []byte {
+			close(started)
+			<-ctx.Done()
+			close(canceled)
+			return nil
+		})
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	_, err = conn.Write([]byte("STUCK\n"))
+	require.NoError(t, err)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err = server.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	<-canceled
+	require.NoError(t, <-served)
+}
+
+func TestTCPServer_BlockedIdleTimeout(t *testing.T) {
+	address := startServer(t, func(ctx context.Context, request []byte) []byte {
+		if string(request) == "WAIT" {
+			sess, _ := session.FromContext(ctx)
+			defer sess.Block()()
+
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		return request
+	}, WithIdleTimeout(50*time.Millisecond))
+
+	client, err := NewTCPClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	// ожидание дольше таймаута простоя не закрывает соединение
+	response, err := client.Send([]byte("WAIT"))
+	require.NoError(t, err)
+	assert.Equal(t, "WAIT", string(response))
+
+	// после ожидания таймаут простоя снова действует
+	time.Sleep(150 * time.Millisecond)
+	_, err = client.Send([]byte("PING"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,151 @@
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// Режимы проверки клиентских сертификатов
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+var ErrNoCertificates = errors.New("no certificates found")
+
+// ParseClientAuth переводит режим проверки клиентских сертификатов в tls.ClientAuthType.
+// Пустой режим означает require, если задан CA клиентов, и none в противном случае
+func ParseClientAuth(mode string, hasClientCA bool) (tls.ClientAuthType, error) {
+	switch mode {
+	case "":
+		if hasClientCA {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+
+		return tls.NoClientCert, nil
+	case ClientAuthNone:
+		return tls.NoClientCert, nil
+	case ClientAuthOptional:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("unknown client auth mode %q", mode)
+	}
+}
+
+// TLSProvider хранит сертификат сервера и CA клиентов. Reload перечитывает файлы,
+// и новые соединения используют обновленные сертификаты без перезапуска сервера
+type TLSProvider struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	clientAuth   tls.ClientAuthType
+
+	current atomic.Pointer[tls.Config]
+}
+
+func NewTLSProvider(certFile, keyFile, clientCAFile string, clientAuth tls.ClientAuthType) (*TLSProvider, error) {
+	p := &TLSProvider{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		clientAuth:   clientAuth,
+	}
+
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Reload перечитывает сертификат, ключ и CA клиентов. При ошибке продолжают
+// использоваться ранее загруженные
+func (p *TLSProvider) Reload() error {
+	cert, err := tls.LoadX509KeyPair(p.certFile, p.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed tls.LoadX509KeyPair: %w", err)
+	}
+
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   p.clientAuth,
+	}
+
+	if p.clientCAFile != "" {
+		if cfg.ClientCAs, err = loadCertPool(p.clientCAFile); err != nil {
+			return err
+		}
+	}
+
+	p.current.Store(cfg)
+
+	return nil
+}
+
+// Config возвращает конфигурацию для listener: каждое рукопожатие использует
+// сертификаты, загруженные последним Reload
+func (p *TLSProvider) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return p.current.Load(), nil
+		},
+	}
+}
+
+// CertificateUser возвращает имя пользователя, которому соответствует клиентский
+// сертификат: Common Name субъекта
+func CertificateUser(cert *x509.Certificate) string {
+	return cert.Subject.CommonName
+}
+
+// ClientTLSConfig собирает конфигурацию клиента: CA для проверки сервера и, для mTLS,
+// собственный сертификат. Пустой caFile - системные корневые сертификаты
+func ClientTLSConfig(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed tls.LoadX509KeyPair: %w", err)
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed os.ReadFile: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%w in %s", ErrNoCertificates, path)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,240 @@
+package network
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/patyukin/mdb/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCert - сертификат и ключ, созданные для теста
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Minute)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	issuer, signer := template, key
+	if parent != nil {
+		issuer, signer = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, signer)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{cert: cert, key: key}
+}
+
+func newTestCA(t *testing.T, name string) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+}
+
+func newServerCert(t *testing.T, ca *testCert) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "mdb"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+	}, ca)
+}
+
+func newClientCert(t *testing.T, ca *testCert, user string) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: user},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+	}, ca)
+}
+
+// write сохраняет сертификат и ключ в PEM и возвращает пути к файлам
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certFile, keyFile
+}
+
+// userHandler отвечает именем пользователя сессии
+func userHandler(ctx context.Context, _ []byte) []byte {
+	s, _ := session.FromContext(ctx)
+	return []byte("user " + s.User())
+}
+
+func TestTCPServer_TLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "mdb-ca")
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newServerCert(t, ca).write(t, dir, "server")
+	clientCert, clientKey := newClientCert(t, ca, "alice").write(t, dir, "alice")
+
+	clientAuth, err := ParseClientAuth(ClientAuthOptional, true)
+	require.NoError(t, err)
+
+	provider, err := NewTLSProvider(certFile, keyFile, caFile, clientAuth)
+	require.NoError(t, err)
+	address := startServer(t, userHandler, WithTLS(provider))
+
+	tests := []struct {
+		name     string
+		certFile string
+		keyFile  string
+		expected string
+	}{
+		{name: "Без клиентского сертификата", expected: "user "},
+		{name: "С клиентским сертификатом", certFile: clientCert, keyFile: clientKey, expected: "user alice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ClientTLSConfig(caFile, tt.certFile, tt.keyFile, "")
+			require.NoError(t, err)
+
+			client, err := NewTLSClient(address, time.Second, cfg)
+			require.NoError(t, err)
+			defer func() { _ = client.Close() }()
+
+			response, err := client.Send([]byte("WHOAMI"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(response))
+		})
+	}
+
+	// клиент без TLS не получает ответа
+	plain, err := NewTCPClient(address, time.Second)
+	require.NoError(t, err)
+	defer func() { _ = plain.Close() }()
+
+	_, err = plain.Send([]byte("WHOAMI"))
+	assert.Error(t, err)
+}
+
+func TestTCPServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "mdb-ca")
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newServerCert(t, ca).write(t, dir, "server")
+
+	other := newTestCA(t, "other-ca")
+	foreignCert, foreignKey := newClientCert(t, other, "mallory").write(t, dir, "mallory")
+
+	clientAuth, err := ParseClientAuth("", true)
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, clientAuth)
+
+	provider, err := NewTLSProvider(certFile, keyFile, caFile, clientAuth)
+	require.NoError(t, err)
+	address := startServer(t, userHandler, WithTLS(provider))
+
+	for _, files := range [][2]string{{"", ""}, {foreignCert, foreignKey}} {
+		cfg, err := ClientTLSConfig(caFile, files[0], files[1], "")
+		require.NoError(t, err)
+
+		// в TLS 1.3 сервер отклоняет сертификат после завершения рукопожатия на стороне
+		// клиента, поэтому ошибка может проявиться только при обмене данными
+		client, err := NewTLSClient(address, time.Second, cfg)
+		if err == nil {
+			_, err = client.Send([]byte("WHOAMI"))
+			_ = client.Close()
+		}
+
+		assert.Error(t, err)
+	}
+}
+
+func TestTLSProvider_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "mdb-ca")
+	caFile, _ := ca.write(t, dir, "ca")
+	first := newServerCert(t, ca)
+	certFile, keyFile := first.write(t, dir, "server")
+
+	provider, err := NewTLSProvider(certFile, keyFile, "", tls.NoClientCert)
+	require.NoError(t, err)
+	address := startServer(t, userHandler, WithTLS(provider))
+
+	serverSerial := func() *big.Int {
+		cfg, err := ClientTLSConfig(caFile, "", "", "")
+		require.NoError(t, err)
+
+		conn, err := tls.Dial("tcp", address, cfg)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber
+	}
+
+	assert.Equal(t, first.cert.SerialNumber, serverSerial())
+
+	second := newServerCert(t, ca)
+	second.write(t, dir, "server")
+	require.NoError(t, provider.Reload())
+	assert.Equal(t, second.cert.SerialNumber, serverSerial())
+
+	// поврежденный файл не заменяет загруженный сертификат
+	require.NoError(t, os.WriteFile(certFile, []byte("garbage"), 0o600))
+	assert.Error(t, provider.Reload())
+	assert.Equal(t, second.cert.SerialNumber, serverSerial())
+}
+
+func TestParseClientAuth(t *testing.T) {
+	tests := []struct {
+		mode     string
+		hasCA    bool
+		expected tls.ClientAuthType
+	}{
+		{"", false, tls.NoClientCert},
+		{"", true, tls.RequireAndVerifyClientCert},
+		{ClientAuthNone, true, tls.NoClientCert},
+		{ClientAuthOptional, true, tls.VerifyClientCertIfGiven},
+		{ClientAuthRequire, true, tls.RequireAndVerifyClientCert},
+	}
+
+	for _, tt := range tests {
+		clientAuth, err := ParseClientAuth(tt.mode, tt.hasCA)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, clientAuth)
+	}
+
+	_, err := ParseClientAuth("always", true)
+	assert.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultUnixSocketMode - права Unix-сокета по умолчанию: доступ владельцу и группе
+const DefaultUnixSocketMode os.FileMode = 0o660
+
+var (
+	ErrNoListeners = errors.New("neither TCP address nor Unix socket is configured")
+	ErrSocketInUse = errors.New("unix socket is in use by another process")
+	ErrNotASocket  = errors.New("path exists and is not a unix socket")
+)
+
+// staleDialTimeout - время, за которое живой сервер должен принять соединение на сокете
+const staleDialTimeout = 100 * time.Millisecond
+
+// WithUnixSocket дополнительно принимает соединения на Unix-сокете path с правами mode
+func WithUnixSocket(path string, mode os.FileMode) TCPServerOption {
+	return func(s *TCPServer) {
+		s.unixSocket = path
+		s.unixSocketMode = mode
+		if mode == 0 {
+			s.unixSocketMode = DefaultUnixSocketMode
+		}
+	}
+}
+
+// listenUnix создает Unix-сокет. Сокет, оставшийся от завершившегося процесса,
+// удаляется; если на сокете кто-то принимает соединения, возвращается ErrSocketInUse.
+// Сокет создается во временном каталоге с доступом только для владельца и переносится
+// на место после установки прав mode, поэтому до этого к нему никто не подключится
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".sock")
+	if err != nil {
+		return nil, fmt.Errorf("failed os.MkdirTemp: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	tmp := filepath.Join(dir, "s")
+	listener, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, fmt.Errorf("failed net.Listen: %w", err)
+	}
+
+	// после переноса сокет удаляется по path, а не по временному пути
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err = os.Chmod(tmp, mode); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed os.Chmod: %w", err)
+	}
+
+	if err = os.Rename(tmp, path); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed os.Rename: %w", err)
+	}
+
+	return &unixListener{Listener: listener, path: path}, nil
+}
+
+// unixListener удаляет файл сокета при закрытии. Как и net.UnixListener, файл удаляется
+// до закрытия сокета: когда Accept вернет ошибку, файла уже нет
+type unixListener struct {
+	net.Listener
+	path      string
+	closeOnce sync.Once
+}
+
+func (l *unixListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		if err = os.Remove(l.path); errors.Is(err, os.ErrNotExist) {
+			err = nil
+		} else if err != nil {
+			err = fmt.Errorf("failed os.Remove: %w", err)
+		}
+	})
+
+	if closeErr := l.Listener.Close(); closeErr != nil {
+		return closeErr
+	}
+
+	return err
+}
+
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed os.Lstat: %w", err)
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%w: %s", ErrNotASocket, path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, staleDialTimeout)
+	if err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("%w: %s", ErrSocketInUse, path)
+	}
+
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed os.Remove: %w", err)
+	}
+
+	return nil
+}
+
+// remoteAddr возвращает адрес клиента. У клиентов Unix-сокета обычно нет адреса
+// (пустой или "@" в Linux), поэтому вместо него используется путь сокета
+func remoteAddr(conn net.Conn) string {
+	addr := conn.RemoteAddr()
+	if addr.Network() == "unix" && (addr.String() == "" || addr.String() == "@") {
+		return "unix:" + conn.LocalAddr().String()
+	}
+
+	return addr.String()
+}
+
+// NewUnixClient подключается к серверу через Unix-сокет
+func NewUnixClient(path string, idleTimeout time.Duration) (*TCPClient, error) {
+	conn, err := net.DialTimeout("unix", path, idleTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed net.DialTimeout: %w", err)
+	}
+
+	return NewClient(conn, idleTimeout), nil
+}
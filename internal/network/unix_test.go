@@ -0,0 +1,97 @@
+package network
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTCPServer_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mdb.sock")
+
+	// сокет, оставшийся от завершившегося процесса
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	server := NewTCPServer("", zap.NewNop(), WithUnixSocket(path, 0o600))
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- server.HandleQueries(ctx, userHandler)
+	}()
+
+	var client *TCPClient
+	require.Eventually(t, func() bool {
+		client, err = NewUnixClient(path, time.Second)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	response, err := client.Send([]byte("WHOAMI"))
+	require.NoError(t, err)
+	assert.Equal(t, "user ", string(response))
+	require.NoError(t, client.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// временный каталог, в котором сокет создавался до установки прав, удален
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "mdb.sock", entries[0].Name())
+	}
+
+	// второй сервер не должен отнимать сокет у работающего
+	err = NewTCPServer("", zap.NewNop(), WithUnixSocket(path, 0)).HandleQueries(context.Background(), userHandler)
+	assert.ErrorIs(t, err, ErrSocketInUse)
+
+	cancel()
+	require.NoError(t, <-served)
+
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist, "сокет удаляется при остановке")
+}
+
+func TestTCPServer_UnixSocketErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mdb.sock")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+	err := NewTCPServer("", zap.NewNop(), WithUnixSocket(path, 0)).HandleQueries(context.Background(), userHandler)
+	assert.ErrorIs(t, err, ErrNotASocket)
+
+	err = NewTCPServer("", zap.NewNop()).HandleQueries(context.Background(), userHandler)
+	assert.ErrorIs(t, err, ErrNoListeners)
+}
+
+func TestRemoteAddr(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mdb.sock")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := listener.Accept()
+		accepted <- conn
+	}()
+
+	client, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	conn := <-accepted
+	require.NotNil(t, conn)
+	defer func() { _ = conn.Close() }()
+
+	assert.Equal(t, "unix:"+path, remoteAddr(conn))
+}
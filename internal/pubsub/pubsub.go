@@ -0,0 +1,334 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/patyukin/mdb/pkg/glob"
+)
+
+// DefaultBufferSize - число сообщений, которые подписчик может не забрать, прежде чем
+// будет отключен как медленный
+const DefaultBufferSize = 1024
+
+// Message - сообщение, доставленное подписчику. Pattern заполнен, если подписка
+// оформлена по шаблону
+type Message struct {
+	Pattern string
+	Channel string
+	Payload string
+}
+
+// Broker рассылает сообщения подписчикам каналов и шаблонов каналов
+type Broker struct {
+	bufferSize    atomic.Int64
+	slowConsumers atomic.Uint64
+
+	mu       sync.RWMutex
+	channels map[string]map[*Subscriber]struct{}
+	patterns map[string]map[*Subscriber]struct{}
+}
+
+func NewBroker(bufferSize int) *Broker {
+	b := &Broker{
+		channels: make(map[string]map[*Subscriber]struct{}),
+		patterns: make(map[string]map[*Subscriber]struct{}),
+	}
+	b.bufferSize.Store(DefaultBufferSize)
+	b.SetBufferSize(bufferSize)
+
+	return b
+}
+
+// SetBufferSize меняет размер буфера подписчиков, действует с очередной доставки
+func (b *Broker) SetBufferSize(size int) {
+	if size > 0 {
+		b.bufferSize.Store(int64(size))
+	}
+}
+
+// NewSubscriber создает подписчика без подписок
+func (b *Broker) NewSubscriber() *Subscriber {
+	return &Subscriber{
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// Subscribe подписывает на каналы и возвращает общее число подписок подписчика
+func (b *Broker) Subscribe(sub *Subscriber, channels ...string) int {
+	return b.subscribe(b.channels, sub, sub.channels, channels)
+}
+
+// PSubscribe подписывает на каналы, имена которых соответствуют glob-шаблонам
+func (b *Broker) PSubscribe(sub *Subscriber, patterns ...string) int {
+	return b.subscribe(b.patterns, sub, sub.patterns, patterns)
+}
+
+// Unsubscribe отменяет подписку на каналы, без аргументов - на все каналы.
+// Возвращает оставшееся число подписок
+func (b *Broker) Unsubscribe(sub *Subscriber, channels ...string) int {
+	return b.unsubscribe(b.channels, sub, sub.channels, channels)
+}
+
+// PUnsubscribe отменяет подписку по шаблонам, без аргументов - по всем шаблонам
+func (b *Broker) PUnsubscribe(sub *Subscriber, patterns ...string) int {
+	return b.unsubscribe(b.patterns, sub, sub.patterns, patterns)
+}
+
+// Remove отменяет все подписки, например при закрытии соединения
+func (b *Broker) Remove(sub *Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.removeLocked(sub)
+}
+
+func (b *Broker) subscribe(index map[string]map[*Subscriber]struct{}, sub *Subscriber, own map[string]struct{}, names []string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if sub.Dropped() {
+		return 0
+	}
+
+	for _, name := range names {
+		subscribers, ok := index[name]
+		if !ok {
+			subscribers = make(map[*Subscriber]struct{})
+			index[name] = subscribers
+		}
+
+		subscribers[sub] = struct{}{}
+		own[name] = struct{}{}
+	}
+
+	return sub.updateCount()
+}
+
+func (b *Broker) unsubscribe(index map[string]map[*Subscriber]struct{}, sub *Subscriber, own map[string]struct{}, names []string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(names) == 0 {
+		for name := range own {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		unindex(index, name, sub)
+		delete(own, name)
+	}
+
+	return sub.updateCount()
+}
+
+func (b *Broker) removeLocked(sub *Subscriber) {
+	for name := range sub.channels {
+		unindex(b.channels, name, sub)
+	}
+
+	for pattern := range sub.patterns {
+		unindex(b.patterns, pattern, sub)
+	}
+
+	clear(sub.channels)
+	clear(sub.patterns)
+	sub.updateCount()
+}
+
+func unindex(index map[string]map[*Subscriber]struct{}, name string, sub *Subscriber) {
+	delete(index[name], sub)
+	if len(index[name]) == 0 {
+		delete(index, name)
+	}
+}
+
+// Publish доставляет сообщение подписчикам канала и подходящих шаблонов и возвращает
+// число доставок. Подписчик с переполненным буфером отключается и сообщение не получает
+func (b *Broker) Publish(channel, payload string) int {
+	type delivery struct {
+		sub     *Subscriber
+		message Message
+	}
+
+	b.mu.RLock()
+	var deliveries []delivery
+	for sub := range b.channels[channel] {
+		deliveries = append(deliveries, delivery{sub, Message{Channel: channel, Payload: payload}})
+	}
+
+	for pattern, subscribers := range b.patterns {
+		if !glob.Match(pattern, channel) {
+			continue
+		}
+
+		for sub := range subscribers {
+			deliveries = append(deliveries, delivery{sub, Message{Pattern: pattern, Channel: channel, Payload: payload}})
+		}
+	}
+	b.mu.RUnlock()
+
+	limit := int(b.bufferSize.Load())
+	received := 0
+	var slow []*Subscriber
+	for _, d := range deliveries {
+		if !d.sub.accepts(d.message) {
+			continue
+		}
+
+		switch d.sub.push(d.message, limit) {
+		case pushDelivered:
+			received++
+		case pushOverflow:
+			slow = append(slow, d.sub)
+		}
+	}
+
+	if len(slow) > 0 {
+		b.slowConsumers.Add(uint64(len(slow)))
+
+		b.mu.Lock()
+		for _, sub := range slow {
+			b.removeLocked(sub)
+		}
+		b.mu.Unlock()
+	}
+
+	return received
+}
+
+// Channels возвращает число каналов, на которые есть подписчики
+func (b *Broker) Channels() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.channels)
+}
+
+// Patterns возвращает число шаблонов, по которым есть подписчики
+func (b *Broker) Patterns() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.patterns)
+}
+
+// SlowConsumers возвращает число подписчиков, отключенных за переполнение буфера
+func (b *Broker) SlowConsumers() uint64 {
+	return b.slowConsumers.Load()
+}
+
+type pushResult int
+
+const (
+	pushDelivered pushResult = iota
+	pushOverflow
+	pushDropped
+)
+
+// Subscriber - очередь сообщений одного клиента. Подписки меняются только через Broker
+type Subscriber struct {
+	// подписки защищены мьютексом брокера
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	count    atomic.Int64
+	filter   atomic.Pointer[Filter]
+
+	mu      sync.Mutex
+	queue   []Message
+	dropped bool
+	notify  chan struct{}
+	done    chan struct{}
+}
+
+// updateCount обновляет число подписок, вызывается под мьютексом брокера
+func (s *Subscriber) updateCount() int {
+	n := len(s.channels) + len(s.patterns)
+	s.count.Store(int64(n))
+
+	return n
+}
+
+// Subscriptions возвращает число подписок на каналы и шаблоны
+func (s *Subscriber) Subscriptions() int {
+	return int(s.count.Load())
+}
+
+// Filter решает, получит ли подписчик сообщение. Вызывается при каждой доставке
+// вне блокировок брокера
+type Filter func(Message) bool
+
+// SetFilter задает фильтр сообщений подписчика, nil снимает фильтр
+func (s *Subscriber) SetFilter(fn Filter) {
+	if fn == nil {
+		s.filter.Store(nil)
+		return
+	}
+
+	s.filter.Store(&fn)
+}
+
+func (s *Subscriber) accepts(m Message) bool {
+	fn := s.filter.Load()
+	return fn == nil || (*fn)(m)
+}
+
+// push ставит сообщение в очередь. При переполнении подписчик отключается
+func (s *Subscriber) push(m Message, limit int) pushResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dropped {
+		return pushDropped
+	}
+
+	if len(s.queue) >= limit {
+		s.dropped = true
+		s.queue = nil
+		close(s.done)
+
+		return pushOverflow
+	}
+
+	s.queue = append(s.queue, m)
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+
+	return pushDelivered
+}
+
+// Ready сигнализирует, что в очереди появились сообщения
+func (s *Subscriber) Ready() <-chan struct{} {
+	return s.notify
+}
+
+// Done закрывается, когда подписчик отключен за переполнение буфера
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Dropped сообщает, отключен ли подписчик за переполнение буфера
+func (s *Subscriber) Dropped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dropped
+}
+
+// Drain забирает все сообщения из очереди
+func (s *Subscriber) Drain() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := s.queue
+	s.queue = nil
+
+	return messages
+}
@@ -0,0 +1,112 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroker_Publish(t *testing.T) {
+	b := NewBroker(10)
+
+	exact := b.NewSubscriber()
+	pattern := b.NewSubscriber()
+	both := b.NewSubscriber()
+
+	assert.Equal(t, 2, b.Subscribe(exact, "cache:user", "cache:order"))
+	assert.Equal(t, 1, b.PSubscribe(pattern, "cache:*"))
+	assert.Equal(t, 1, b.Subscribe(both, "cache:user"))
+	assert.Equal(t, 2, b.PSubscribe(both, "cache:u?er"))
+
+	tests := []struct {
+		name     string
+		channel  string
+		received int
+	}{
+		{name: "Канал и шаблоны", channel: "cache:user", received: 4},
+		{name: "Только шаблон", channel: "cache:item", received: 1},
+		{name: "Нет подписчиков", channel: "events", received: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.received, b.Publish(tt.channel, "invalidate"))
+		})
+	}
+
+	assert.Equal(t, []Message{{Channel: "cache:user", Payload: "invalidate"}}, exact.Drain())
+	assert.Equal(t, []Message{
+		{Pattern: "cache:*", Channel: "cache:user", Payload: "invalidate"},
+		{Pattern: "cache:*", Channel: "cache:item", Payload: "invalidate"},
+	}, pattern.Drain())
+	assert.Len(t, both.Drain(), 2)
+	assert.Empty(t, exact.Drain())
+}
+
+func TestBroker_Unsubscribe(t *testing.T) {
+	b := NewBroker(10)
+	sub := b.NewSubscriber()
+
+	b.Subscribe(sub, "a", "b", "c")
+	b.PSubscribe(sub, "x*")
+	assert.Equal(t, 4, sub.Subscriptions())
+
+	assert.Equal(t, 3, b.Unsubscribe(sub, "a"))
+	assert.Equal(t, 0, b.Publish("a", "m"))
+
+	assert.Equal(t, 1, b.Unsubscribe(sub))
+	assert.Equal(t, 0, b.PUnsubscribe(sub))
+	assert.Equal(t, 0, b.Channels())
+	assert.Equal(t, 0, b.Patterns())
+
+	b.Subscribe(sub, "a")
+	b.Remove(sub)
+	assert.Equal(t, 0, sub.Subscriptions())
+	assert.Equal(t, 0, b.Publish("a", "m"))
+}
+
+func TestBroker_SlowConsumer(t *testing.T) {
+	b := NewBroker(2)
+	slow := b.NewSubscriber()
+	fast := b.NewSubscriber()
+	b.Subscribe(slow, "events")
+	b.Subscribe(fast, "events")
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, 2, b.Publish("events", "m"))
+		fast.Drain()
+	}
+
+	// буфер медленного подписчика заполнен: он отключается и сообщение не получает
+	assert.Equal(t, 1, b.Publish("events", "m"))
+	assert.True(t, slow.Dropped())
+	assert.Empty(t, slow.Drain())
+	assert.Equal(t, uint64(1), b.SlowConsumers())
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("Done не закрыт у отключенного подписчика")
+	}
+
+	assert.Equal(t, 1, b.Publish("events", "m"))
+	assert.Equal(t, 0, b.Subscribe(slow, "events"))
+}
+
+func TestSubscriber_Filter(t *testing.T) {
+	b := NewBroker(10)
+	sub := b.NewSubscriber()
+	b.PSubscribe(sub, "cache:*")
+
+	sub.SetFilter(func(m Message) bool { return m.Payload != "secret" })
+	assert.Equal(t, 1, b.Publish("cache:a", "public"))
+	assert.Equal(t, 0, b.Publish("cache:a", "secret"))
+
+	sub.SetFilter(nil)
+	assert.Equal(t, 1, b.Publish("cache:a", "secret"))
+
+	assert.Equal(t, []Message{
+		{Pattern: "cache:*", Channel: "cache:a", Payload: "public"},
+		{Pattern: "cache:*", Channel: "cache:a", Payload: "secret"},
+	}, sub.Drain())
+}
@@ -0,0 +1,134 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/patyukin/mdb/internal/pubsub"
+)
+
+var lastID atomic.Uint64
+
+// Session описывает клиентское соединение, в рамках которого выполняется запрос
+type Session struct {
+	ID          uint64
+	RemoteAddr  string
+	LocalAddr   string
+	ConnectedAt time.Time
+
+	framed  atomic.Bool
+	blocked atomic.Int32
+
+	mu         sync.RWMutex
+	user       string
+	subscriber *pubsub.Subscriber
+	// onBlockedChange вызывается при изменении Blocked
+	onBlockedChange func()
+}
+
+func New(remoteAddr, localAddr string) *Session {
+	return &Session{
+		ID:          lastID.Add(1),
+		RemoteAddr:  remoteAddr,
+		LocalAddr:   localAddr,
+		ConnectedAt: time.Now(),
+	}
+}
+
+// User возвращает имя пользователя, прошедшего аутентификацию, или пустую строку
+func (s *Session) User() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.user
+}
+
+// SetUser закрепляет за сессией пользователя после успешной аутентификации
+func (s *Session) SetUser(user string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.user = user
+}
+
+// Framed сообщает, включен ли режим, в котором запросы с идентификатором
+// выполняются параллельно, а ответы возвращаются по мере готовности
+func (s *Session) Framed() bool {
+	return s.framed.Load()
+}
+
+// SetFramed включает или выключает режим ответов по мере готовности
+func (s *Session) SetFramed(framed bool) {
+	s.framed.Store(framed)
+}
+
+// Subscriber возвращает очередь сообщений pub/sub соединения, если соединение
+// может получать сообщения
+func (s *Session) Subscriber() (*pubsub.Subscriber, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.subscriber, s.subscriber != nil
+}
+
+// SetSubscriber закрепляет за сессией очередь, из которой сообщения отправляются клиенту
+func (s *Session) SetSubscriber(sub *pubsub.Subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscriber = sub
+}
+
+// Block отмечает, что запрос сессии ждет события, например элемента списка в BLPOP,
+// и возвращает функцию, снимающую отметку. Пока отметка есть, соединение
+// не закрывается по таймауту простоя
+func (s *Session) Block() func() {
+	s.blocked.Add(1)
+	s.blockedChanged()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.blocked.Add(-1)
+			s.blockedChanged()
+		})
+	}
+}
+
+// Blocked сообщает, ждет ли какой-либо запрос сессии события
+func (s *Session) Blocked() bool {
+	return s.blocked.Load() > 0
+}
+
+// OnBlockedChange задает функцию, которая вызывается при изменении Blocked
+func (s *Session) OnBlockedChange(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onBlockedChange = fn
+}
+
+func (s *Session) blockedChanged() {
+	s.mu.RLock()
+	fn := s.onBlockedChange
+	s.mu.RUnlock()
+
+	if fn != nil {
+		fn()
+	}
+}
+
+type contextKey struct{}
+
+// NewContext возвращает контекст, содержащий сессию
+func NewContext(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext возвращает сессию из контекста, если она была установлена
+func FromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(contextKey{}).(*Session)
+	return s, ok
+}
@@ -0,0 +1,62 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Exporter сохраняет завершенные трассировки
+type Exporter interface {
+	Export(t *Trace, request, code string, duration time.Duration) error
+}
+
+// record - строка экспорта в формате JSON lines
+type record struct {
+	RequestID string        `json:"request_id"`
+	Request   string        `json:"request"`
+	Code      string        `json:"code"`
+	Start     time.Time     `json:"start"`
+	Duration  time.Duration `json:"duration_ns"`
+	Spans     []Span        `json:"spans"`
+}
+
+// JSONExporter пишет трассировки в w по одной JSON-строке на запрос
+type JSONExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w}
+}
+
+func (e *JSONExporter) Export(t *Trace, request, code string, duration time.Duration) error {
+	spans := t.Spans()
+	if spans == nil {
+		spans = []Span{}
+	}
+
+	line, err := json.Marshal(record{
+		RequestID: t.RequestID,
+		Request:   request,
+		Code:      code,
+		Start:     t.Start.UTC(),
+		Duration:  duration,
+		Spans:     spans,
+	})
+	if err != nil {
+		return fmt.Errorf("failed json.Marshal: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, err = e.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed e.w.Write: %w", err)
+	}
+
+	return nil
+}
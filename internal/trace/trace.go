@@ -0,0 +1,111 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Span - этап обработки запроса. Start отсчитывается от начала запроса
+type Span struct {
+	Name     string        `json:"name"`
+	Start    time.Duration `json:"start_ns"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Trace собирает этапы обработки одного запроса
+type Trace struct {
+	RequestID string
+	Start     time.Time
+
+	mu    sync.Mutex
+	spans []Span
+}
+
+func New(requestID string) *Trace {
+	return &Trace{RequestID: requestID, Start: time.Now()}
+}
+
+// NewRequestID возвращает случайный идентификатор запроса
+func NewRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+
+	return hex.EncodeToString(b[:])
+}
+
+// Spans возвращает копию записанных этапов
+func (t *Trace) Spans() []Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]Span(nil), t.spans...)
+}
+
+// StartSpan начинает этап и возвращает функцию, завершающую его
+func (t *Trace) StartSpan(name string) func() {
+	start := time.Now()
+
+	return func() {
+		span := Span{Name: name, Start: start.Sub(t.Start), Duration: time.Since(start)}
+
+		t.mu.Lock()
+		t.spans = append(t.spans, span)
+		t.mu.Unlock()
+	}
+}
+
+type contextKey struct{}
+
+type requestIDKey struct{}
+
+// NewContext возвращает контекст, содержащий трассировку запроса
+func NewContext(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext возвращает трассировку из контекста, если она была установлена
+func FromContext(ctx context.Context) (*Trace, bool) {
+	t, ok := ctx.Value(contextKey{}).(*Trace)
+	return t, ok
+}
+
+// WithRequestID передает идентификатор запроса, выбранный вызывающей стороной
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID возвращает идентификатор текущего запроса или пустую строку
+func RequestID(ctx context.Context) string {
+	if t, ok := FromContext(ctx); ok {
+		return t.RequestID
+	}
+
+	id, _ := ctx.Value(requestIDKey{}).(string)
+
+	return id
+}
+
+// StartSpan начинает этап трассировки из контекста. Без трассировки ничего не записывает
+func StartSpan(ctx context.Context, name string) func() {
+	t, ok := FromContext(ctx)
+	if !ok {
+		return func() {}
+	}
+
+	return t.StartSpan(name)
+}
+
+// Logger возвращает дочерний логгер с идентификатором текущего запроса
+func Logger(ctx context.Context, l *zap.Logger) *zap.Logger {
+	id := RequestID(ctx)
+	if id == "" {
+		return l
+	}
+
+	return l.With(zap.String("request_id", id))
+}
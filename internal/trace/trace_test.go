@@ -0,0 +1,80 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestStartSpan(t *testing.T) {
+	tr := New("req-1")
+	ctx := NewContext(context.Background(), tr)
+
+	end := StartSpan(ctx, "parse")
+	time.Sleep(time.Millisecond)
+	end()
+	StartSpan(ctx, "execute")()
+
+	spans := tr.Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "parse", spans[0].Name)
+	assert.GreaterOrEqual(t, spans[0].Duration, time.Millisecond)
+	assert.Equal(t, "execute", spans[1].Name)
+	assert.GreaterOrEqual(t, spans[1].Start, spans[0].Duration)
+
+	// без трассировки в контексте этапы не записываются
+	StartSpan(context.Background(), "parse")()
+}
+
+func TestRequestID(t *testing.T) {
+	assert.Empty(t, RequestID(context.Background()))
+	assert.Equal(t, "client", RequestID(WithRequestID(context.Background(), "client")))
+	assert.Equal(t, "trace", RequestID(NewContext(WithRequestID(context.Background(), "client"), New("trace"))))
+	assert.Len(t, NewRequestID(), 16)
+	assert.NotEqual(t, NewRequestID(), NewRequestID())
+}
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	l := zap.New(core)
+
+	Logger(context.Background(), l).Info("without id")
+	Logger(NewContext(context.Background(), New("req-1")), l).Info("with id")
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+	assert.NotContains(t, entries[0].ContextMap(), "request_id")
+	assert.Equal(t, "req-1", entries[1].ContextMap()["request_id"])
+}
+
+func TestJSONExporter(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewJSONExporter(&buf)
+
+	tr := New("req-1")
+	tr.StartSpan("parse")()
+	require.NoError(t, e.Export(tr, "GET key", "OK", time.Millisecond))
+	require.NoError(t, e.Export(New("req-2"), "PING", "OK", time.Millisecond))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var r record
+	require.NoError(t, json.Unmarshal(lines[0], &r))
+	assert.Equal(t, "req-1", r.RequestID)
+	assert.Equal(t, "GET key", r.Request)
+	assert.Equal(t, time.Millisecond, r.Duration)
+	require.Len(t, r.Spans, 1)
+	assert.Equal(t, "parse", r.Spans[0].Name)
+
+	require.NoError(t, json.Unmarshal(lines[1], &r))
+	assert.Equal(t, "req-2", r.RequestID)
+	assert.Empty(t, r.Spans)
+}
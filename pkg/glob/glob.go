@@ -0,0 +1,45 @@
+// Package glob сопоставляет строки с шаблонами, где * заменяет любую последовательность
+// символов, включая пустую, а ? - ровно один символ. В отличие от path.Match
+// символ / не имеет особого значения
+package glob
+
+// Match сообщает, соответствует ли s шаблону pattern
+func Match(pattern, s string) bool {
+	p, str := []rune(pattern), []rune(s)
+
+	// позиции последней звездочки и строки в момент ее обработки для возврата
+	star, mark := -1, 0
+	i, j := 0, 0
+	for j < len(str) {
+		switch {
+		case i < len(p) && (p[i] == '?' || p[i] == str[j]):
+			i++
+			j++
+		case i < len(p) && p[i] == '*':
+			star, mark = i, j
+			i++
+		case star >= 0:
+			mark++
+			i, j = star+1, mark
+		default:
+			return false
+		}
+	}
+
+	for i < len(p) && p[i] == '*' {
+		i++
+	}
+
+	return i == len(p)
+}
+
+// HasWildcards сообщает, содержит ли шаблон символы подстановки
+func HasWildcards(pattern string) bool {
+	for _, r := range pattern {
+		if r == '*' || r == '?' {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,40 @@
+package glob
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		s        string
+		expected bool
+	}{
+		{"*", "", true},
+		{"*", "billing:1", true},
+		{"billing:*", "billing:1", true},
+		{"billing:*", "billing:", true},
+		{"billing:*", "users:1", false},
+		{"*:1", "billing:1", true},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"a/*", "a/b/c", true},
+		{"ключ:*", "ключ:значение", true},
+		{"exact", "exact", true},
+		{"exact", "exactly", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Match(tt.pattern, tt.s), "%s ~ %s", tt.pattern, tt.s)
+	}
+}
+
+func TestHasWildcards(t *testing.T) {
+	assert.True(t, HasWildcards("news.*"))
+	assert.True(t, HasWildcards("a?"))
+	assert.False(t, HasWildcards("news"))
+}
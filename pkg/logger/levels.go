@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelCore применяет собственный уровень к записям именованных логгеров.
+// Имя подсистемы сопоставляется с логгером по префиксу: уровень "storage"
+// действует и на логгер "storage.engine"
+type levelCore struct {
+	zapcore.Core
+	level     zap.AtomicLevel
+	overrides map[string]zapcore.Level
+}
+
+func newLevelCore(core zapcore.Core, level zap.AtomicLevel, overrides map[string]zapcore.Level) zapcore.Core {
+	if len(overrides) == 0 {
+		return core
+	}
+
+	return &levelCore{Core: core, level: level, overrides: overrides}
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level, overrides: c.overrides}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < c.levelFor(ent.LoggerName) {
+		return ce
+	}
+
+	return c.Core.Check(ent, ce)
+}
+
+func (c *levelCore) levelFor(name string) zapcore.Level {
+	for name != "" {
+		if level, ok := c.overrides[name]; ok {
+			return level
+		}
+
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+
+	return c.level.Level()
+}
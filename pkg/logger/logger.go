@@ -6,29 +6,108 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"os"
+	"time"
+)
+
+const (
+	outputStdout = "stdout"
+	outputStderr = "stderr"
 )
 
 func InitLogger(cfg *config.Config) (*zap.Logger, error) {
-	var level zapcore.Level
-	if err := level.Set(cfg.Logger.Level); err != nil {
-		return nil, fmt.Errorf("failed to set log level: %w", err)
+	l, _, err := InitLoggerWithLevel(cfg)
+	return l, err
+}
+
+// InitLoggerWithLevel создает логгер и возвращает его общий уровень, который можно менять
+// во время работы. Уровни подсистем из конфигурации от него не зависят
+func InitLoggerWithLevel(cfg *config.Config) (*zap.Logger, zap.AtomicLevel, error) {
+	level, err := zap.ParseAtomicLevel(cfg.Logger.Level)
+	if err != nil {
+		return nil, level, fmt.Errorf("failed to set log level: %w", err)
+	}
+
+	// базовое ядро пропускает самый подробный из уровней, остальное отсекает levelCore
+	minOverride := zapcore.InvalidLevel
+	overrides := make(map[string]zapcore.Level, len(cfg.Logger.Levels))
+	for subsystem, value := range cfg.Logger.Levels {
+		var l zapcore.Level
+		if err = l.Set(value); err != nil {
+			return nil, level, fmt.Errorf("failed to set log level for %s: %w", subsystem, err)
+		}
+
+		overrides[subsystem] = l
+		minOverride = min(minOverride, l)
+	}
+
+	enabler := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l >= min(level.Level(), minOverride)
+	})
+
+	outputs := cfg.Logger.Outputs
+	if len(outputs) == 0 {
+		outputs = []string{outputStdout}
+	}
+
+	cores := make([]zapcore.Core, 0, len(outputs))
+	for _, output := range outputs {
+		ws, terminal, err := openOutput(cfg, output)
+		if err != nil {
+			return nil, level, err
+		}
+
+		cores = append(cores, zapcore.NewCore(newEncoder(cfg, terminal), ws, enabler))
+	}
+
+	core := zapcore.NewTee(cores...)
+	if cfg.Logger.Sampling.Initial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, cfg.Logger.Sampling.Initial, cfg.Logger.Sampling.Thereafter)
 	}
 
-	stdout := zapcore.AddSync(os.Stdout)
-	var consoleEncoder zapcore.Encoder
+	return zap.New(newLevelCore(core, level, overrides)), level, nil
+}
+
+func openOutput(cfg *config.Config, output string) (zapcore.WriteSyncer, bool, error) {
+	switch output {
+	case outputStdout:
+		return zapcore.AddSync(os.Stdout), true, nil
+	case outputStderr:
+		return zapcore.AddSync(os.Stderr), true, nil
+	default:
+		rotation := cfg.Logger.Rotation
+		f, err := OpenRotatingFile(output, int64(rotation.MaxSize), rotation.MaxAge, rotation.MaxBackups)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to open log file %s: %w", output, err)
+		}
+
+		return f, false, nil
+	}
+}
 
+func newEncoder(cfg *config.Config, terminal bool) zapcore.Encoder {
+	var encoderCfg zapcore.EncoderConfig
 	if cfg.Logger.Mode == "devel" {
-		developmentCfg := zap.NewDevelopmentEncoderConfig()
-		developmentCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
-		consoleEncoder = zapcore.NewConsoleEncoder(developmentCfg)
+		encoderCfg = zap.NewDevelopmentEncoderConfig()
+		if terminal && cfg.Logger.Encoding != "json" {
+			encoderCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		}
 	} else {
-		productionCfg := zap.NewProductionEncoderConfig()
-		productionCfg.TimeKey = "timestamp"
-		productionCfg.EncodeTime = zapcore.ISO8601TimeEncoder
-		consoleEncoder = zapcore.NewConsoleEncoder(productionCfg)
+		encoderCfg = zap.NewProductionEncoderConfig()
+		encoderCfg.TimeKey = "timestamp"
+		encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	}
+
+	encoding := cfg.Logger.Encoding
+	if encoding == "" {
+		encoding = "console"
+		if cfg.Logger.Mode == "prod" {
+			encoding = "json"
+		}
+	}
+
+	if encoding == "json" {
+		return zapcore.NewJSONEncoder(encoderCfg)
 	}
 
-	return zap.New(
-		zapcore.NewCore(consoleEncoder, stdout, level),
-	), nil
+	return zapcore.NewConsoleEncoder(encoderCfg)
 }
@@ -1,18 +1,17 @@
 package logger
 
 import (
+	"encoding/json"
 	"github.com/patyukin/mdb/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"go.uber.org/zap/zapcore"
 )
 
-type MockConfig struct {
-	Logger struct {
-		Level string
-		Mode  string
-	}
-}
+type MockConfig = config.Config
 
 func newMockConfig(level, mode string) *MockConfig {
 	cfg := &MockConfig{}
@@ -90,3 +89,67 @@ func TestInitLogger(t *testing.T) {
 		})
 	}
 }
+
+func TestInitLogger_SubsystemLevels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mdb.log")
+
+	cfg := newMockConfig("info", "prod")
+	cfg.Logger.Encoding = "json"
+	cfg.Logger.Outputs = []string{path}
+	cfg.Logger.Levels = map[string]string{"storage": "warn", "compute": "debug"}
+
+	logger, err := InitLogger(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	logger.Debug("root debug")
+	logger.Info("root info")
+	logger.Named("storage").Info("storage info")
+	logger.Named("storage").Named("engine").Warn("engine warn")
+	logger.Named("compute").Debug("compute debug")
+	_ = logger.Sync()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry map[string]any
+		if err = json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("expected json line, got %q: %v", line, err)
+		}
+
+		messages = append(messages, entry["msg"].(string))
+	}
+
+	expected := []string{"root info", "engine warn", "compute debug"}
+	if strings.Join(messages, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected messages %v, got %v", expected, messages)
+	}
+}
+
+func TestInitLoggerWithLevel_ChangeLevel(t *testing.T) {
+	cfg := newMockConfig("info", "prod")
+	cfg.Logger.Levels = map[string]string{"storage": "error"}
+
+	logger, level, err := InitLoggerWithLevel(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if logger.Core().Enabled(zapcore.DebugLevel) {
+		t.Errorf("expected debug level to be disabled")
+	}
+
+	level.SetLevel(zapcore.DebugLevel)
+	if !logger.Core().Enabled(zapcore.DebugLevel) {
+		t.Errorf("expected debug level to be enabled after SetLevel")
+	}
+
+	if logger.Named("storage").Check(zapcore.WarnLevel, "storage warn") != nil {
+		t.Errorf("expected subsystem level to stay unchanged")
+	}
+}
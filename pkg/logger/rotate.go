@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "2006-01-02T15-04-05.000000000"
+
+// RotatingFile - файл журнала с ротацией по размеру и возрасту. Ротированные файлы
+// получают суффикс со временем ротации, лишние старые копии удаляются
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	now        func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// OpenRotatingFile открывает файл для дозаписи. Нулевые maxSize и maxAge отключают
+// соответствующий вид ротации, нулевой maxBackups сохраняет все копии
+func OpenRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+		now:        time.Now,
+	}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return fmt.Errorf("failed os.MkdirAll: %w", err)
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed os.OpenFile: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed file.Stat: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+
+	return nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	// при неудачной ротации запись продолжается в текущий файл, а ошибка возвращается
+	// вместе с записанными данными
+	var rotateErr error
+	if f.size > 0 && f.shouldRotate(int64(len(p))) {
+		rotateErr = f.rotate()
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+
+	return n, err
+}
+
+func (f *RotatingFile) shouldRotate(incoming int64) bool {
+	if f.maxSize > 0 && f.size+incoming > f.maxSize {
+		return true
+	}
+
+	return f.maxAge > 0 && f.now().Sub(f.openedAt) >= f.maxAge
+}
+
+// rotate переименовывает открытый файл и закрывает его только после открытия нового,
+// поэтому при любой ошибке запись продолжается в прежний файл
+func (f *RotatingFile) rotate() error {
+	backup := f.path + "." + f.now().UTC().Format(backupTimeFormat)
+	if err := os.Rename(f.path, backup); err != nil {
+		return fmt.Errorf("failed os.Rename: %w", err)
+	}
+
+	previous := f.file
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	if err := previous.Close(); err != nil {
+		return fmt.Errorf("failed previous.Close: %w", err)
+	}
+
+	return f.removeOldBackups()
+}
+
+func (f *RotatingFile) removeOldBackups() error {
+	if f.maxBackups <= 0 {
+		return nil
+	}
+
+	backups, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return fmt.Errorf("failed filepath.Glob: %w", err)
+	}
+
+	var valid []string
+	for _, backup := range backups {
+		if _, err = time.Parse(backupTimeFormat, backup[len(f.path)+1:]); err == nil {
+			valid = append(valid, backup)
+		}
+	}
+
+	sort.Strings(valid)
+	for len(valid) > f.maxBackups {
+		if err = os.Remove(valid[0]); err != nil {
+			return fmt.Errorf("failed os.Remove: %w", err)
+		}
+
+		valid = valid[1:]
+	}
+
+	return nil
+}
+
+func (f *RotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	return f.file.Sync()
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+	f.file = nil
+
+	return err
+}
@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile_RotateBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mdb.log")
+
+	f, err := OpenRotatingFile(path, 32, 0, 2)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	line := strings.Repeat("x", 20) + "\n"
+	for i := 0; i < 5; i++ {
+		_, err = f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	backups, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	assert.Len(t, backups, 2, "лишние копии должны удаляться")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, line, string(data))
+}
+
+func TestRotatingFile_RotateByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mdb.log")
+
+	f, err := OpenRotatingFile(path, 0, time.Hour, 0)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+	f.openedAt = now
+
+	_, err = f.Write([]byte("first\n"))
+	require.NoError(t, err)
+
+	now = now.Add(30 * time.Minute)
+	_, err = f.Write([]byte("second\n"))
+	require.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	_, err = f.Write([]byte("third\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	backups, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+
+	data, err := os.ReadFile(backups[0])
+	require.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", string(data))
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(data))
+}
+
+func TestRotatingFile_WriteAfterClose(t *testing.T) {
+	f, err := OpenRotatingFile(filepath.Join(t.TempDir(), "mdb.log"), 0, 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = f.Write([]byte("line\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestRotatingFile_RotateFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mdb.log")
+
+	f, err := OpenRotatingFile(path, 8, 0, 0)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+
+	// непустой каталог на месте копии не дает переименовать файл
+	backup := path + "." + now.Format(backupTimeFormat)
+	require.NoError(t, os.MkdirAll(filepath.Join(backup, "busy"), 0o755))
+
+	_, err = f.Write([]byte("first\n"))
+	require.NoError(t, err)
+
+	n, err := f.Write([]byte("second\n"))
+	assert.Error(t, err)
+	assert.Equal(t, len("second\n"), n)
+
+	// после устранения причины ротация проходит, запись не прерывается
+	require.NoError(t, os.RemoveAll(backup))
+	_, err = f.Write([]byte("third\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	data, err := os.ReadFile(backup)
+	require.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", string(data))
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(data))
+}